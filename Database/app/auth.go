@@ -0,0 +1,95 @@
+package main
+
+import (
+	"MiniRedisDb/command"
+	"crypto/subtle"
+	"net"
+	"strings"
+	"sync"
+)
+
+func init() {
+	command.Register(command.CommandInfo{Name: "AUTH", Usage: "AUTH <password>", Summary: "Authenticate the connection when MRD_PASSWORD is set"}, command.HandlerFunc(handleAuth))
+}
+
+// requiredPassword is the password set with MRD_PASSWORD; empty disables
+// authentication.
+var requiredPassword string
+
+// session is the authentication state of one client connection.
+type session struct {
+	password      string // The password the connection must present; empty if none
+	authenticated bool
+}
+
+var (
+	sessionsMu sync.Mutex
+	sessions   = make(map[net.Conn]*session)
+)
+
+// openSession starts tracking c, which must authenticate with password
+// before running most commands. An empty password needs no AUTH.
+func openSession(c net.Conn, password string) {
+	sessionsMu.Lock()
+	sessions[c] = &session{password: password, authenticated: password == ""}
+	sessionsMu.Unlock()
+}
+
+// closeSession forgets c's authentication state.
+func closeSession(c net.Conn) {
+	sessionsMu.Lock()
+	delete(sessions, c)
+	sessionsMu.Unlock()
+}
+
+// isAuthenticated reports whether c may run any command.
+func isAuthenticated(c net.Conn) bool {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	s, ok := sessions[c]
+	return ok && s.authenticated
+}
+
+// commandName returns the upper-cased name of a request's command: the first
+// element of a RESP array, or the first word of an inline command.
+func commandName(input string) string {
+	if strings.HasPrefix(input, "*") {
+		if parts := strings.Split(input, "\r\n"); len(parts) > 2 {
+			return strings.ToUpper(parts[2])
+		}
+	}
+	fields := strings.Fields(input)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToUpper(fields[0])
+}
+
+// allowedBeforeAuth reports whether a command may run on a connection that
+// hasn't authenticated yet. PING stays open for health checks.
+func allowedBeforeAuth(name string) bool {
+	return name == "AUTH" || name == "PING"
+}
+
+// handleAuth processes AUTH <password> against the password of the
+// connection's session, marking the session authenticated on a match.
+func handleAuth(argv []string, c net.Conn) {
+	if len(argv) != 2 {
+		c.Write([]byte("-ERR wrong number of arguments for 'auth' command\r\n"))
+		return
+	}
+
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	s, ok := sessions[c]
+	if !ok || s.password == "" {
+		c.Write([]byte("-ERR AUTH called without any password configured\r\n"))
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(argv[1]), []byte(s.password)) != 1 {
+		c.Write([]byte("-ERR invalid password\r\n"))
+		return
+	}
+	s.authenticated = true
+	c.Write([]byte("+OK\r\n"))
+}
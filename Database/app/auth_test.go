@@ -0,0 +1,96 @@
+package main
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestAuthGatesCommands(t *testing.T) {
+	requiredPassword = "s3cret"
+	t.Cleanup(func() { requiredPassword = "" })
+	addr := startServer(t)
+
+	c, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	for _, args := range [][]string{
+		{"SQL", "SELECT * FROM users"},
+		{"G.GETFRIENDS", "Alice"},
+		{"SET", "k", "v"},
+	} {
+		if got := send(t, c, args...); got != "-NOAUTH Authentication required\r\n" {
+			t.Errorf("%s before AUTH = %q, want -NOAUTH", args[0], got)
+		}
+	}
+	if got := send(t, c, "PING"); got != "+PONG\r\n" {
+		t.Errorf("PING before AUTH = %q, want +PONG", got)
+	}
+	if got := send(t, c, "AUTH", "wrong"); got != "-ERR invalid password\r\n" {
+		t.Errorf("AUTH with the wrong password = %q", got)
+	}
+	if got := send(t, c, "SQL", "SELECT * FROM users"); got != "-NOAUTH Authentication required\r\n" {
+		t.Errorf("SQL after a failed AUTH = %q, want -NOAUTH", got)
+	}
+
+	if got := send(t, c, "AUTH", "s3cret"); got != "+OK\r\n" {
+		t.Fatalf("AUTH with the right password = %q", got)
+	}
+	if got := send(t, c, "SQL", "SELECT name FROM users WHERE id = 1"); !strings.Contains(got, "Alice") {
+		t.Errorf("SQL after AUTH = %q, want Alice's row", got)
+	}
+
+	// Authentication belongs to the connection
+	other, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	if got := send(t, other, "SQL", "SELECT * FROM users"); got != "-NOAUTH Authentication required\r\n" {
+		t.Errorf("SQL on a second connection = %q, want -NOAUTH", got)
+	}
+}
+
+func TestAuthWithoutAPassword(t *testing.T) {
+	addr := startServer(t)
+	c, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if got := send(t, c, "AUTH", "anything"); !strings.HasPrefix(got, "-ERR") {
+		t.Errorf("AUTH with no password configured = %q, want an error", got)
+	}
+	if got := send(t, c, "SQL", "SELECT name FROM users WHERE id = 1"); !strings.Contains(got, "Alice") {
+		t.Errorf("SQL with no password configured = %q, want Alice's row", got)
+	}
+}
+
+func TestInlineAuth(t *testing.T) {
+	requiredPassword = "s3cret"
+	t.Cleanup(func() { requiredPassword = "" })
+	c, err := net.Dial("tcp", startServer(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	cases := []struct{ line, want string }{
+		{"AUTH", "-ERR wrong number of arguments for 'auth' command\r\n"},
+		{"AUTH wrong", "-ERR invalid password\r\n"},
+		{"AUTH s3cret extra", "-ERR wrong number of arguments for 'auth' command\r\n"},
+		{"SET k v", "-NOAUTH Authentication required\r\n"},
+		{"auth s3cret", "+OK\r\n"},
+	}
+	for _, tc := range cases {
+		if got := sendInline(t, c, tc.line); got != tc.want {
+			t.Errorf("%q = %q, want %q", tc.line, got, tc.want)
+		}
+	}
+	if got := send(t, c, "SQL", "SELECT name FROM users WHERE id = 1"); !strings.Contains(got, "Alice") {
+		t.Errorf("SQL after an inline AUTH = %q, want Alice's row", got)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"MiniRedisDb/command"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// commandSpec describes one of the original key-value commands: how it is
+// recognised, how it is documented in HELP and what runs it. A request
+// belongs to the command if it contains one of the keywords anywhere, as
+// these commands always matched (case-sensitively). Every other command
+// registers a command.CommandHandler instead and is looked up by name.
+type commandSpec struct {
+	usage    string
+	summary  string
+	keywords []string
+	handle   func(input string, c net.Conn)
+}
+
+// commands lists the commands that aren't in the command package's
+// registry. Order matters: requests go to the first entry that matches.
+var commands []commandSpec
+
+func init() {
+	command.Register(command.CommandInfo{Name: "HELP", Aliases: []string{"COMMAND"}, Usage: "HELP", Summary: "List the supported commands"}, command.HandlerFunc(handleHelp))
+
+	commands = []commandSpec{
+		{"AUTOSAVE-ON", "Save to the backup file every 60 seconds", []string{"AUTOSAVE-ON"}, func(input string, c net.Conn) { setAutoSave(true, c) }},
+		{"AUTOSAVE-OFF", "Stop the periodic save", []string{"AUTOSAVE-OFF"}, func(input string, c net.Conn) { setAutoSave(false, c) }},
+		{"CONFIG GET dir|dbfilename", "Report where the backup file is stored", []string{"CONFIG"}, command.HandleConfigGet},
+		{"SET <key> <value> [PX ms]", "Set a key, optionally with an expiry", []string{"SET"}, command.HandleSet},
+		{"GET <key>", "Get a key's value", []string{"GET"}, command.HandleGet},
+		{"SAVE", "Save all keys to the backup file", []string{"SAVE"}, func(input string, c net.Conn) { command.HandleSave(c) }},
+		{"KEYS <pattern>", "List the keys matching a wildcard pattern", []string{"KEYS"}, command.HandleKeys},
+		{"LIST", "List all key-value pairs", []string{"LIST"}, func(input string, c net.Conn) { command.HandleList(c) }},
+		{"LOAD", "Load all keys from the backup file", []string{"LOAD"}, func(input string, c net.Conn) { command.HandleLoad(c) }},
+		{"DELETE <key>", "Delete a key", []string{"DELETE"}, command.HandleDelete},
+		{"MULTI", "Start a transaction", []string{"MULTI"}, command.HandleMulti},
+		{"EXEC", "Run the queued transaction", []string{"EXEC"}, command.HandleExec},
+		{"DISCARD", "Drop the queued transaction", []string{"DISCARD"}, command.HandleDiscard},
+		{"INCR <key>", "Increment a key's integer value", []string{"INCR"}, command.HandleINCR},
+	}
+}
+
+// matches reports whether the request belongs to this command.
+func (spec *commandSpec) matches(input string) bool {
+	for _, keyword := range spec.keywords {
+		if strings.Contains(input, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupCommand returns the handler for a request: the one registered
+// under its name in the command package, else the first matching entry of
+// commands. ok is false if nothing handles it.
+func lookupCommand(input string) (handle func(input string, c net.Conn), ok bool) {
+	if handler, found := command.LookupHandler(commandName(input)); found {
+		return func(input string, c net.Conn) { handler.Handle(command.ParseArgs(input), c) }, true
+	}
+	for i := range commands {
+		spec := &commands[i]
+		if spec.matches(input) {
+			return spec.handle, true
+		}
+	}
+	return nil, false
+}
+
+// handleHelp replies with one "<usage> - <summary>" bulk string per
+// command: the key-value commands first, then the registered ones by name.
+func handleHelp(argv []string, c net.Conn) {
+	var lines []string
+	for _, spec := range commands {
+		lines = append(lines, spec.usage+" - "+spec.summary)
+	}
+	for _, info := range command.RegisteredCommands() {
+		lines = append(lines, info.Usage+" - "+info.Summary)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("*%d\r\n", len(lines)))
+	for _, line := range lines {
+		sb.WriteString(fmt.Sprintf("$%d\r\n%s\r\n", len(line), line))
+	}
+	c.Write([]byte(sb.String()))
+}
+
+// setAutoSave turns the periodic save on or off.
+func setAutoSave(enabled bool, c net.Conn) {
+	autoSaveMutex.Lock()
+	autoSave = enabled
+	autoSaveMutex.Unlock()
+	autoSaveSignal <- struct{}{} // Notify the autoSaveRoutine
+	c.Write([]byte("+OK\r\n"))
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"MiniRedisDb/command"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestPingAndEcho(t *testing.T) {
+	c, err := net.Dial("tcp", startServer(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	cases := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"PING"}, "+PONG\r\n"},
+		{[]string{"PING", "hello"}, "$5\r\nhello\r\n"},
+		{[]string{"ping"}, "+PONG\r\n"},
+		{[]string{"ECHO", "world"}, "$5\r\nworld\r\n"},
+		{[]string{"ECHO", "hello world"}, "$11\r\nhello world\r\n"},
+		{[]string{"ECHO"}, "-ERR wrong number of arguments for 'echo' command\r\n"},
+	}
+	for _, tc := range cases {
+		if got := send(t, c, tc.args...); got != tc.want {
+			t.Errorf("%q = %q, want %q", tc.args, got, tc.want)
+		}
+	}
+
+	// Inline commands are split on whitespace, as for every other command
+	inline := []struct{ line, want string }{
+		{"PING", "+PONG\r\n"},
+		{"PING hello", "$5\r\nhello\r\n"},
+		{"PING a b", "-ERR wrong number of arguments for 'ping' command\r\n"},
+		{"ECHO world", "$5\r\nworld\r\n"},
+		{"echo world", "$5\r\nworld\r\n"},
+		{"ECHO", "-ERR wrong number of arguments for 'echo' command\r\n"},
+	}
+	for _, tc := range inline {
+		if got := sendInline(t, c, tc.line); got != tc.want {
+			t.Errorf("%q = %q, want %q", tc.line, got, tc.want)
+		}
+	}
+}
+
+// recordConn stands in for a client connection, keeping every reply.
+type recordConn struct {
+	net.Conn
+	out strings.Builder
+}
+
+func (c *recordConn) Write(b []byte) (int, error) { return c.out.Write(b) }
+
+func TestHelpListsEveryCommand(t *testing.T) {
+	c := &recordConn{}
+	handleHelp([]string{"HELP"}, c)
+	lines := command.ParseArgs(c.out.String())
+	listed := func(name string) bool {
+		for _, line := range lines {
+			if strings.HasPrefix(strings.ToUpper(line), name+" ") {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Every command that dispatches is in HELP, whichever table it comes from
+	for _, name := range []string{"SQL", "SQLSTATS", "SQLCACHE", "G.ADDEDGE", "G.GETFRIENDS", "G.FOF", "PING", "ECHO", "HELP", "AUTH", "SET", "GET", "INCR", "MULTI"} {
+		if _, ok := lookupCommand(fmt.Sprintf("*1\r\n$%d\r\n%s\r\n", len(name), name)); !ok {
+			t.Errorf("%s doesn't dispatch", name)
+		}
+		if !listed(name) {
+			t.Errorf("%s is missing from HELP", name)
+		}
+	}
+	for _, info := range command.RegisteredCommands() {
+		if !listed(info.Name) {
+			t.Errorf("registered command %s is missing from HELP", info.Name)
+		}
+	}
+	for _, spec := range commands {
+		if !listed(spec.keywords[0]) {
+			t.Errorf("%s is missing from HELP", spec.keywords[0])
+		}
+	}
+	// Only the original key-value commands are matched by keyword; the
+	// server's own commands take parsed arguments from the registry
+	for _, name := range []string{"PING", "ECHO", "HELP", "COMMAND", "AUTH"} {
+		if _, ok := command.LookupHandler(name); !ok {
+			t.Errorf("%s isn't in the command registry", name)
+		}
+		for _, spec := range commands {
+			if spec.keywords[0] == name {
+				t.Errorf("%s is still in the keyword table", name)
+			}
+		}
+	}
+	if want := len(commands) + len(command.RegisteredCommands()); len(lines) != want {
+		t.Errorf("HELP has %d lines, want one per command (%d)", len(lines), want)
+	}
+}
+
+func init() {
+	command.Register(command.CommandInfo{Name: "APPTEST.ARGS", Usage: "APPTEST.ARGS [args...]", Summary: "Reply with the argument count"},
+		command.HandlerFunc(func(argv []string, c net.Conn) {
+			c.Write([]byte(fmt.Sprintf(":%d\r\n", len(argv)-1)))
+		}))
+}
+
+func TestDispatchReachesRegisteredHandlers(t *testing.T) {
+	c := &recordConn{}
+	dispatchCommand("*3\r\n$12\r\napptest.args\r\n$5\r\nhello\r\n$5\r\nworld\r\n", c)
+	if got := c.out.String(); got != ":2\r\n" {
+		t.Errorf("dispatch to a registered handler replied %q, want :2", got)
+	}
+	c.out.Reset()
+	dispatchCommand("*1\r\n$12\r\nAPPTEST.NONE\r\n", c)
+	if got := c.out.String(); got != "-ERR unknown command\r\n" {
+		t.Errorf("dispatch of an unknown command replied %q", got)
+	}
+}
@@ -2,11 +2,15 @@ package main
 
 import (
 	"MiniRedisDb/command"
+	"MiniRedisDb/config"
+	"context"
 	"fmt"
 	"net"
 	"os"
+	"os/signal"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 )
 
@@ -20,29 +24,72 @@ var autoSaveSignal = make(chan struct{})
 func main() {
 	fmt.Println("Logs from your program will appear here!")
 
+	if err := command.SetQueryLogLevel(config.LogLevel()); err != nil {
+		fmt.Printf("WARNING: %s, logging queries at %s\n", err, config.DefaultLogLevel)
+	}
+
+	requiredPassword = config.Password()
+
 	// Initialize the new SQL cache and backing DB
-	command.InitSQLCache()
+	command.InitSQLCache(command.DefaultSQLCacheConfig())
 	command.InitBackingDB()
 
+	// Initialize the graph database
+	command.InitGraphDB()
+
 	// Start a goroutine that listens for auto-save signals
 	go autoSaveRoutine()
 
 	go command.CheckForExpiry()
 
-	l, err := net.Listen("tcp", "0.0.0.0:6379")
+	addr := config.ListenAddress()
+	l, err := listen(addr)
 	if err != nil {
-		fmt.Println("Failed to bind to port 6379")
+		fmt.Println("Failed to bind to", addr)
+		os.Exit(1)
+	}
+	fmt.Println("Listening on", l.Addr())
+
+	// Block until asked to stop, then let in-flight commands finish
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	sig := <-signals
+	fmt.Println("Received", sig, "- shutting down")
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := Shutdown(ctx); err != nil {
+		fmt.Println("Shutdown timed out waiting for in-flight commands:", err.Error())
 		os.Exit(1)
 	}
+	fmt.Println("Shutdown complete")
+}
 
+// listen binds addr and serves the connections made to it in the background
+// until Shutdown closes the listener. Connections use the password that is
+// set when listen is called.
+func listen(addr string) (net.Listener, error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	listener = l
+	go acceptConnections(l, requiredPassword)
+	return l, nil
+}
+
+func acceptConnections(l net.Listener, password string) {
 	for {
 		c, err := l.Accept()
 		if err != nil {
+			if isShuttingDown() {
+				return // Listener was closed by Shutdown
+			}
 			fmt.Println("Error accepting connection: ", err.Error())
 			os.Exit(1)
 		}
 		fmt.Println("Accepted connection", c.RemoteAddr().String())
-		go handleConnection(c)
+		go handleConnection(c, password)
 	}
 }
 
@@ -66,8 +113,11 @@ func autoSaveRoutine() {
 	}
 }
 
-func handleConnection(c net.Conn) {
+func handleConnection(c net.Conn, password string) {
 	defer c.Close()
+	defer command.ForgetConnection(c)
+	openSession(c, password)
+	defer closeSession(c)
 	buf := make([]byte, 1024)
 
 	for {
@@ -87,68 +137,41 @@ func handleConnection(c net.Conn) {
 		}
 		fmt.Println("Received:", input)
 
-		// Transaction handling
-		if command.IsInTransaction {
-			if strings.Contains(input, "EXEC") {
-				command.HandleExec(input, c)
-			} else if strings.Contains(input, "DISCARD") {
-				command.HandleDiscard(input, c)
-			} else {
-				command.QueueCommand(input)
-			}
+		name := commandName(input)
+		if name == "AUTH" {
+			// Runs at once, even during a transaction: it only concerns
+			// this connection
+			handleAuth(command.ParseArgs(input), c)
+			continue
+		}
+		if !isAuthenticated(c) && !allowedBeforeAuth(name) {
+			c.Write([]byte("-NOAUTH Authentication required\r\n"))
+			continue
+		}
+
+		if !beginCommand() {
+			c.Write([]byte("-ERR server is shutting down\r\n"))
+			return
+		}
+		dispatchCommand(input, c)
+		endCommand()
+	}
+}
+
+// dispatchCommand routes one request to its handler (see lookupCommand).
+func dispatchCommand(input string, c net.Conn) {
+	// Transaction handling
+	if command.IsInTransaction {
+		if strings.Contains(input, "EXEC") {
+			command.HandleExec(input, c)
+		} else if strings.Contains(input, "DISCARD") {
+			command.HandleDiscard(input, c)
 		} else {
-			// --- MODIFIED SQL HANDLER ---
-			// We check for "SELECT" or the new "SQL" command
-			// --- NEW: Added SQLSTATS ---
-			upperInput := strings.ToUpper(input)
-			if strings.Contains(upperInput, "SQLSTATS") {
-				command.HandleSQLStats(c)
-			// --- End NEW ---
-			} else if strings.Contains(upperInput, "SELECT") || strings.Contains(upperInput, "SQL") {
-				command.HandleSQL(input, c)
-			} else if strings.Contains(input, "ECHO") {
-				command.HandleEcho(input, c)
-			} else if strings.Contains(input, "AUTOSAVE-ON") {
-				autoSaveMutex.Lock()
-				autoSave = true
-				autoSaveMutex.Unlock()
-				autoSaveSignal <- struct{}{} // Notify the autoSaveRoutine
-				c.Write([]byte("+OK\r\n"))
-			} else if strings.Contains(input, "AUTOSAVE-OFF") {
-				autoSaveMutex.Lock()
-				autoSave = false
-				autoSaveMutex.Unlock()
-				autoSaveSignal <- struct{}{} // Notify the autoSaveRoutine
-				c.Write([]byte("+OK\r\n"))
-			} else if strings.Contains(input, "CONFIG") {
-				command.HandleConfigGet(input, c)
-			} else if strings.Contains(input, "SET") {
-				command.HandleSet(input, c)
-			} else if strings.Contains(input, "GET") {
-				command.HandleGet(input, c)
-			} else if strings.Contains(input, "PING") {
-				c.Write([]byte("+PONG\r\n"))
-			} else if strings.Contains(input, "SAVE") {
-				command.HandleSave(c)
-			} else if strings.Contains(input, "KEYS") {
-				command.HandleKeys(input, c)
-			} else if strings.Contains(input, "LIST") {
-				command.HandleList(c)
-			} else if strings.Contains(input, "LOAD") {
-				command.HandleLoad(c)
-			} else if strings.Contains(input, "DELETE") {
-				command.HandleDelete(input, c)
-			} else if strings.Contains(input, "MULTI") {
-				command.HandleMulti(input, c)
-			} else if strings.Contains(input, "EXEC") {
-				command.HandleExec(input, c)
-			} else if strings.Contains(input, "DISCARD") {
-				command.HandleDiscard(input, c)
-			} else if strings.Contains(input, "INCR") {
-				command.HandleINCR(input, c)
-			} else {
-				c.Write([]byte("-ERR unknown command\r\n"))
-			}
+			command.QueueCommand(input)
 		}
+	} else if handle, ok := lookupCommand(input); ok {
+		handle(input, c)
+	} else {
+		c.Write([]byte("-ERR unknown command\r\n"))
 	}
-}
\ No newline at end of file
+}
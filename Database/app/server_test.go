@@ -0,0 +1,91 @@
+package main
+
+import (
+	"MiniRedisDb/command"
+	"MiniRedisDb/config"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startServer runs the server on an ephemeral port with a fresh SQL cache
+// and shuts it down when the test ends. It returns the address to dial.
+func startServer(t *testing.T) string {
+	t.Helper()
+	cfg := command.DefaultSQLCacheConfig()
+	cfg.RefreshInterval = 0
+	command.InitSQLCache(cfg)
+	command.InitBackingDB()
+
+	shutdownMutex.Lock()
+	shuttingDown = false
+	shutdownMutex.Unlock()
+
+	t.Setenv("MRD_HOST", "127.0.0.1")
+	t.Setenv("MRD_PORT", "0")
+	l, err := listen(config.ListenAddress())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		Shutdown(ctx)
+	})
+	return l.Addr().String()
+}
+
+// send writes args to c as a RESP array, as a client library would, and
+// returns the reply.
+func send(t *testing.T, c net.Conn, args ...string) string {
+	t.Helper()
+	req := fmt.Sprintf("*%d\r\n", len(args))
+	for _, arg := range args {
+		req += fmt.Sprintf("$%d\r\n%s\r\n", len(arg), arg)
+	}
+	return roundTrip(t, c, req)
+}
+
+// sendInline sends line as an inline command, as telnet or nc would, and
+// returns the reply.
+func sendInline(t *testing.T, c net.Conn, line string) string {
+	t.Helper()
+	return roundTrip(t, c, line+"\r\n")
+}
+
+// roundTrip writes a raw request and returns the reply, read in one go.
+func roundTrip(t *testing.T, c net.Conn, req string) string {
+	t.Helper()
+	if _, err := c.Write([]byte(req)); err != nil {
+		t.Fatal(err)
+	}
+	c.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 4096)
+	n, err := c.Read(buf)
+	if err != nil {
+		t.Fatalf("reading the reply to %q: %v", req, err)
+	}
+	return string(buf[:n])
+}
+
+func TestServerListensOnConfiguredAddress(t *testing.T) {
+	addr := startServer(t)
+	if !strings.HasPrefix(addr, "127.0.0.1:") || strings.HasSuffix(addr, ":0") {
+		t.Fatalf("server bound to %s, want an ephemeral port on 127.0.0.1", addr)
+	}
+
+	c, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if got := send(t, c, "PING"); got != "+PONG\r\n" {
+		t.Errorf("PING = %q, want +PONG", got)
+	}
+	if got := send(t, c, "SQL", "SELECT name FROM users WHERE id = 1"); !strings.Contains(got, "Alice") {
+		t.Errorf("SQL over the connection = %q, want Alice's row", got)
+	}
+}
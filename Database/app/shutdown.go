@@ -0,0 +1,83 @@
+package main
+
+import (
+	"MiniRedisDb/command"
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// shutdownTimeout bounds how long a SIGINT/SIGTERM waits for in-flight
+// commands (e.g. a cache miss sleeping out its penalty) before giving up.
+const shutdownTimeout = 10 * time.Second
+
+var listener net.Listener
+
+var shutdownMutex sync.Mutex // Guards shuttingDown, drained and every inFlight.Add
+var shuttingDown bool
+var inFlight sync.WaitGroup
+var drained chan struct{} // Closed once the commands in flight at shutdown finish
+
+// beginCommand registers a command as in flight. It returns false once
+// shutdown has started, in which case the command must not run.
+// Every successful call must be paired with endCommand.
+func beginCommand() bool {
+	shutdownMutex.Lock()
+	defer shutdownMutex.Unlock()
+	if shuttingDown {
+		return false
+	}
+	inFlight.Add(1)
+	return true
+}
+
+func endCommand() {
+	inFlight.Done()
+}
+
+func isShuttingDown() bool {
+	shutdownMutex.Lock()
+	defer shutdownMutex.Unlock()
+	return shuttingDown
+}
+
+// Shutdown stops accepting connections, waits for in-flight commands to
+// finish, stops the SQL cache's background refresh and, if autosave is on,
+// writes a final backup. It returns ctx.Err() if the commands don't finish
+// before ctx is done; the backup is skipped in that case since the store
+// may still be changing. Calling it again waits for the same commands.
+func Shutdown(ctx context.Context) error {
+	shutdownMutex.Lock()
+	if !shuttingDown {
+		shuttingDown = true
+		drained = make(chan struct{})
+		go func(done chan struct{}) {
+			inFlight.Wait()
+			close(done)
+		}(drained)
+	}
+	done := drained
+	shutdownMutex.Unlock()
+
+	if listener != nil {
+		listener.Close()
+	}
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	command.SQLCache.Close()
+
+	autoSaveMutex.Lock()
+	defer autoSaveMutex.Unlock()
+	if autoSave {
+		fmt.Println("Saving before shutdown...")
+		dummyConn := &net.TCPConn{}
+		command.HandleSave(dummyConn)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"MiniRedisDb/command"
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// slowQuery starts a cache miss that sleeps out a penalty on its own
+// connection, and returns a channel that receives its reply.
+func slowQuery(t *testing.T, addr string, penalty time.Duration) <-chan string {
+	t.Helper()
+	command.SQLCache.SetMissPenalty(penalty)
+	c, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+
+	reply := make(chan string, 1)
+	go func() {
+		buf := make([]byte, 4096)
+		c.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, _ := c.Read(buf)
+		reply <- string(buf[:n])
+	}()
+	c.Write([]byte("*2\r\n$3\r\nSQL\r\n$36\r\nSELECT name FROM users WHERE id = 1\r\n"))
+	time.Sleep(penalty / 3) // Let the miss start sleeping
+	return reply
+}
+
+func TestShutdownDrainsInFlightQueries(t *testing.T) {
+	addr := startServer(t)
+	reply := slowQuery(t, addr, 300*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	start := time.Now()
+	if err := Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown = %v, want the slow query drained", err)
+	}
+	if waited := time.Since(start); waited < 150*time.Millisecond {
+		t.Errorf("Shutdown returned after %v, before the slow query could finish", waited)
+	}
+	select {
+	case got := <-reply:
+		if !strings.Contains(got, "Alice") {
+			t.Errorf("slow query replied %q, want Alice's row", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the slow query never replied")
+	}
+
+	if c, err := net.DialTimeout("tcp", addr, 200*time.Millisecond); err == nil {
+		c.Close()
+		t.Error("the server still accepts connections after Shutdown")
+	}
+}
+
+func TestShutdownTimesOut(t *testing.T) {
+	addr := startServer(t)
+	reply := slowQuery(t, addr, 500*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Shutdown with a short timeout = %v, want DeadlineExceeded", err)
+	}
+
+	// The query still completes, and a patient Shutdown then succeeds
+	if got := <-reply; !strings.Contains(got, "Alice") {
+		t.Errorf("slow query replied %q, want Alice's row", got)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := Shutdown(ctx); err != nil {
+		t.Errorf("second Shutdown = %v, want nil once the query finished", err)
+	}
+}
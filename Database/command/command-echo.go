@@ -3,16 +3,36 @@ package command
 import (
 	"fmt"
 	"net"
-	"strings"
 )
 
-func HandleEcho(input string,c net.Conn) {
-	parts := strings.Split(input,"\r\n")
-	fmt.Println("Echoing",parts[4])
-	// c.Write([]byte(parts[4]+"\r\n"))
-	message := parts[4] // parts[4] should contain the message (e.g., "hey")
+func init() {
+	Register(CommandInfo{Name: "PING", Usage: "PING [message]", Summary: "Return PONG, or the message if one is given"}, HandlerFunc(HandlePing))
+	Register(CommandInfo{Name: "ECHO", Usage: "ECHO <message>", Summary: "Return the message"}, HandlerFunc(HandleEcho))
+}
 
-    // RESP bulk string response format: $<number of bytes>\r\n<string>\r\n
-    resp := fmt.Sprintf("$%d\r\n%s\r\n", len(message), message)
-    c.Write([]byte(resp))
-}
\ No newline at end of file
+// HandleEcho processes ECHO <message>, replying with the message as a bulk
+// string.
+func HandleEcho(argv []string, c net.Conn) {
+	if len(argv) != 2 {
+		c.Write([]byte("-ERR wrong number of arguments for 'echo' command\r\n"))
+		return
+	}
+	message := argv[1]
+
+	// RESP bulk string response format: $<number of bytes>\r\n<string>\r\n
+	resp := fmt.Sprintf("$%d\r\n%s\r\n", len(message), message)
+	c.Write([]byte(resp))
+}
+
+// HandlePing replies +PONG, or echoes its argument back as a bulk string
+// for PING <message>, like Redis.
+func HandlePing(argv []string, c net.Conn) {
+	switch len(argv) {
+	case 1:
+		c.Write([]byte("+PONG\r\n"))
+	case 2:
+		c.Write([]byte(fmt.Sprintf("$%d\r\n%s\r\n", len(argv[1]), argv[1])))
+	default:
+		c.Write([]byte("-ERR wrong number of arguments for 'ping' command\r\n"))
+	}
+}
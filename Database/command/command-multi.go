@@ -36,7 +36,7 @@ func HandleExec(input string, c net.Conn) {
 	for _, cmd := range transactionQueue {
 		// Parse and execute the queued commands.
 		if strings.Contains(cmd,"ECHO"){
-			HandleEcho(input,c)
+			HandleEcho(ParseArgs(cmd),c)
 		}else if strings.Contains(cmd,"CONFIG"){
 			HandleConfigGet(input,c)
 		}else if strings.Contains(cmd,"SET"){
@@ -0,0 +1,549 @@
+package command
+
+import (
+	"container/heap"
+	"sort"
+)
+
+// shortestPath runs a breadth-first search from start to goal and returns
+// the node sequence including both ends, or nil if goal is unreachable.
+// NOTE: This function is not thread-safe, callers must hold graphMutex!
+func shortestPath(start, goal string) []string {
+	if _, ok := GraphStore[start]; !ok {
+		return nil
+	}
+	if start == goal {
+		return []string{start}
+	}
+
+	visited := map[string]bool{start: true}
+	parent := make(map[string]string)
+	queue := []string{start}
+
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+
+		for _, next := range sortedNeighbours(node) {
+			if visited[next] {
+				continue
+			}
+			visited[next] = true
+			parent[next] = node
+
+			if next == goal {
+				// Walk the parent links back to the start, then reverse
+				path := []string{goal}
+				for path[len(path)-1] != start {
+					path = append(path, parent[path[len(path)-1]])
+				}
+				for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+					path[i], path[j] = path[j], path[i]
+				}
+				return path
+			}
+			queue = append(queue, next)
+		}
+	}
+	return nil
+}
+
+// pathExists runs a breadth-first search from start and reports whether goal
+// is reachable, stopping as soon as it is found. A node always reaches itself.
+// NOTE: This function is not thread-safe, callers must hold graphMutex!
+func pathExists(start, goal string) bool {
+	if _, ok := GraphStore[start]; !ok {
+		return false
+	}
+	if start == goal {
+		return true
+	}
+
+	visited := map[string]bool{start: true}
+	queue := []string{start}
+
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+
+		for next := range GraphStore[node] {
+			if next == goal {
+				return true
+			}
+			if !visited[next] {
+				visited[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+	return false
+}
+
+// bfsOrder returns the nodes reachable from start (start first) in
+// breadth-first visitation order. Each node's neighbours are expanded in
+// sorted order so the result is deterministic. Unknown nodes give nil.
+// NOTE: This function is not thread-safe, callers must hold graphMutex!
+func bfsOrder(start string) []string {
+	if _, ok := GraphStore[start]; !ok {
+		return nil
+	}
+
+	visited := map[string]bool{start: true}
+	order := []string{start}
+
+	// order doubles as the queue: everything after head is still to expand
+	for head := 0; head < len(order); head++ {
+		for _, next := range sortedNeighbours(order[head]) {
+			if !visited[next] {
+				visited[next] = true
+				order = append(order, next)
+			}
+		}
+	}
+	return order
+}
+
+// dfsOrder returns the nodes reachable from start (start first) in
+// depth-first preorder, descending into neighbours in sorted order. It uses
+// an explicit stack so deep graphs can't overflow the call stack.
+// NOTE: This function is not thread-safe, callers must hold graphMutex!
+func dfsOrder(start string) []string {
+	if _, ok := GraphStore[start]; !ok {
+		return nil
+	}
+
+	visited := make(map[string]bool)
+	var order []string
+	stack := []string{start}
+
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if visited[node] {
+			continue // Pushed more than once before its first visit
+		}
+		visited[node] = true
+		order = append(order, node)
+
+		// Push in reverse so the smallest neighbour is popped first
+		neighbours := sortedNeighbours(node)
+		for i := len(neighbours) - 1; i >= 0; i-- {
+			if !visited[neighbours[i]] {
+				stack = append(stack, neighbours[i])
+			}
+		}
+	}
+	return order
+}
+
+// reachableWithin runs a breadth-first search from start and returns every
+// node whose hop distance is between 1 and depth, mapped to that distance.
+// The start node itself is never included.
+// NOTE: This function is not thread-safe, callers must hold graphMutex!
+func reachableWithin(start string, depth int) map[string]int {
+	dist := map[string]int{start: 0}
+	queue := []string{start}
+
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		if dist[node] == depth {
+			continue // Don't expand past the requested depth
+		}
+		for next := range GraphStore[node] {
+			if _, seen := dist[next]; seen {
+				continue
+			}
+			dist[next] = dist[node] + 1
+			queue = append(queue, next)
+		}
+	}
+
+	delete(dist, start)
+	return dist
+}
+
+// countComponents returns the number of connected components in the graph
+// using union-find. Edge direction is ignored, so a directed edge still joins
+// its two endpoints into the same component.
+// NOTE: This function is not thread-safe, callers must hold graphMutex!
+func countComponents() int {
+	parent := make(map[string]string, len(GraphStore))
+	for node := range GraphStore {
+		parent[node] = node
+	}
+
+	var find func(string) string
+	find = func(n string) string {
+		if parent[n] != n {
+			parent[n] = find(parent[n]) // Path compression
+		}
+		return parent[n]
+	}
+
+	components := len(parent)
+	for node, neighbours := range GraphStore {
+		for next := range neighbours {
+			rootA, rootB := find(node), find(next)
+			if rootA != rootB {
+				parent[rootA] = rootB
+				components--
+			}
+		}
+	}
+	return components
+}
+
+// undirectedView returns the graph's adjacency with edge direction dropped:
+// a directed edge a -> b still links a and b, so both directions are kept.
+// Nodes without any edge are left out.
+// NOTE: This function is not thread-safe, callers must hold graphMutex!
+func undirectedView() map[string]map[string]bool {
+	undirected := make(map[string]map[string]bool, len(GraphStore))
+	for node, neighbours := range GraphStore {
+		for next := range neighbours {
+			if undirected[node] == nil {
+				undirected[node] = make(map[string]bool)
+			}
+			if undirected[next] == nil {
+				undirected[next] = make(map[string]bool)
+			}
+			undirected[node][next] = true
+			undirected[next][node] = true
+		}
+	}
+	return undirected
+}
+
+// findBridges returns the bridges of the graph viewed as undirected: edges
+// whose removal disconnects their endpoints. Each is written "a-b" with
+// a < b, and the list is sorted.
+//
+// It is Tarjan's low-link DFS: disc[n] is the order in which n was first
+// visited, low[n] the smallest disc reachable from n's DFS subtree using at
+// most one non-tree edge. A tree edge parent -> child is a bridge exactly
+// when low[child] > disc[parent], i.e. the subtree has no other way back.
+// NOTE: This function is not thread-safe, callers must hold graphMutex!
+func findBridges() []string {
+	undirected := undirectedView()
+	disc := make(map[string]int, len(undirected))
+	low := make(map[string]int, len(undirected))
+	var bridges []string
+
+	var visit func(node, parent string)
+	visit = func(node, parent string) {
+		disc[node] = len(disc) + 1
+		low[node] = disc[node]
+		for _, next := range sortedKeys(undirected[node]) {
+			if next == parent || next == node {
+				continue // The edge we arrived by, or a self-loop
+			}
+			if _, seen := disc[next]; seen {
+				low[node] = min(low[node], disc[next])
+				continue
+			}
+			visit(next, node)
+			low[node] = min(low[node], low[next])
+			if low[next] > disc[node] {
+				a, b := node, next
+				if a > b {
+					a, b = b, a
+				}
+				bridges = append(bridges, a+"-"+b)
+			}
+		}
+	}
+
+	// Start a DFS in every component, in sorted order for stable output
+	for _, node := range sortedKeys(undirected) {
+		if _, seen := disc[node]; !seen {
+			visit(node, "")
+		}
+	}
+	sort.Strings(bridges)
+	return bridges
+}
+
+// findArticulationPoints returns the cut vertices of the graph viewed as
+// undirected: nodes whose removal leaves more connected components, sorted.
+//
+// It uses the same low-link DFS as findBridges. A non-root node is a cut
+// vertex when some child's subtree can't reach above it without it
+// (low[child] >= disc[node]); the DFS root is one when it has two or more
+// children, since they can only be connected through it.
+// NOTE: This function is not thread-safe, callers must hold graphMutex!
+func findArticulationPoints() []string {
+	undirected := undirectedView()
+	disc := make(map[string]int, len(undirected))
+	low := make(map[string]int, len(undirected))
+	cut := make(map[string]bool)
+
+	var visit func(node, parent string)
+	visit = func(node, parent string) {
+		disc[node] = len(disc) + 1
+		low[node] = disc[node]
+		children := 0
+		for _, next := range sortedKeys(undirected[node]) {
+			if next == parent || next == node {
+				continue // The edge we arrived by, or a self-loop
+			}
+			if _, seen := disc[next]; seen {
+				low[node] = min(low[node], disc[next])
+				continue
+			}
+			children++
+			visit(next, node)
+			low[node] = min(low[node], low[next])
+			if parent != "" && low[next] >= disc[node] {
+				cut[node] = true
+			}
+		}
+		if parent == "" && children > 1 {
+			cut[node] = true
+		}
+	}
+
+	for _, node := range sortedKeys(undirected) {
+		if _, seen := disc[node]; !seen {
+			visit(node, "")
+		}
+	}
+	return sortedKeys(cut)
+}
+
+// hasCycle reports whether the graph, viewed as undirected, contains a cycle.
+// It runs a depth-first search from every unvisited node, so cycles in any
+// component are found: reaching an already visited node other than the one
+// we came from means there are two distinct routes to it. A self-loop is a
+// cycle too.
+// NOTE: This function is not thread-safe, callers must hold graphMutex!
+func hasCycle() bool {
+	undirected := undirectedView()
+
+	type frame struct{ node, parent string }
+	visited := make(map[string]bool, len(undirected))
+
+	for root := range undirected {
+		if visited[root] {
+			continue
+		}
+		visited[root] = true
+		stack := []frame{{node: root}}
+
+		for len(stack) > 0 {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+
+			for next := range undirected[top.node] {
+				if next == top.parent && next != top.node {
+					continue // The edge we arrived by
+				}
+				if visited[next] {
+					return true
+				}
+				visited[next] = true
+				stack = append(stack, frame{node: next, parent: top.node})
+			}
+		}
+	}
+	return false
+}
+
+// weightedPath runs Dijkstra's algorithm from start to goal over the edge
+// weights and returns the cheapest path plus its total cost. ok is false if
+// goal is unreachable. With every weight at 1 this finds a fewest-hops path,
+// the same length as shortestPath.
+// NOTE: This function is not thread-safe, callers must hold graphMutex!
+func weightedPath(start, goal string) ([]string, int, bool) {
+	if _, ok := GraphStore[start]; !ok {
+		return nil, 0, false
+	}
+
+	dist := map[string]int{start: 0}
+	parent := make(map[string]string)
+	done := make(map[string]bool)
+	pq := &pathQueue{{node: start, dist: 0}}
+
+	for pq.Len() > 0 {
+		item := heap.Pop(pq).(pathItem)
+		if done[item.node] {
+			continue // Stale queue entry, a cheaper route was already settled
+		}
+		done[item.node] = true
+
+		if item.node == goal {
+			path := []string{goal}
+			for path[len(path)-1] != start {
+				path = append(path, parent[path[len(path)-1]])
+			}
+			for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+				path[i], path[j] = path[j], path[i]
+			}
+			return path, item.dist, true
+		}
+
+		for _, next := range sortedNeighbours(item.node) {
+			if done[next] {
+				continue
+			}
+			cost := item.dist + GraphStore[item.node][next]
+			if best, seen := dist[next]; !seen || cost < best {
+				dist[next] = cost
+				parent[next] = item.node
+				heap.Push(pq, pathItem{node: next, dist: cost})
+			}
+		}
+	}
+	return nil, 0, false
+}
+
+// pathItem is a node waiting in Dijkstra's priority queue.
+type pathItem struct {
+	node string
+	dist int
+}
+
+// pathQueue is a min-heap of pathItems ordered by distance (then name, so
+// ties are broken the same way every run). It implements heap.Interface.
+type pathQueue []pathItem
+
+func (pq pathQueue) Len() int { return len(pq) }
+
+func (pq pathQueue) Less(i, j int) bool {
+	if pq[i].dist != pq[j].dist {
+		return pq[i].dist < pq[j].dist
+	}
+	return pq[i].node < pq[j].node
+}
+
+func (pq pathQueue) Swap(i, j int) { pq[i], pq[j] = pq[j], pq[i] }
+
+func (pq *pathQueue) Push(x interface{}) { *pq = append(*pq, x.(pathItem)) }
+
+func (pq *pathQueue) Pop() interface{} {
+	old := *pq
+	item := old[len(old)-1]
+	*pq = old[:len(old)-1]
+	return item
+}
+
+// pageRank runs the iterative PageRank algorithm over the graph and returns
+// each node's score; the scores sum to 1. Each round a node keeps
+// (1-damping)/N and passes damping times its score to its outgoing
+// neighbours in equal shares (edge weights are ignored). Nodes without
+// outgoing edges share theirs with every node, so no rank leaks away.
+// NOTE: This function is not thread-safe, callers must hold graphMutex!
+func pageRank(iterations int, damping float64) map[string]float64 {
+	nodes := sortedKeys(GraphStore)
+	n := float64(len(nodes))
+	ranks := make(map[string]float64, len(nodes))
+	for _, node := range nodes {
+		ranks[node] = 1 / n
+	}
+
+	for i := 0; i < iterations; i++ {
+		dangling := 0.0
+		for _, node := range nodes {
+			if len(GraphStore[node]) == 0 {
+				dangling += ranks[node]
+			}
+		}
+
+		next := make(map[string]float64, len(nodes))
+		for _, node := range nodes {
+			next[node] = (1-damping)/n + damping*dangling/n
+		}
+		for _, node := range nodes {
+			neighbours := GraphStore[node]
+			if len(neighbours) == 0 {
+				continue
+			}
+			share := damping * ranks[node] / float64(len(neighbours))
+			for neighbour := range neighbours {
+				next[neighbour] += share
+			}
+		}
+		ranks = next
+	}
+	return ranks
+}
+
+// clusteringCoefficient returns the local clustering coefficient of node:
+// the fraction of pairs of its neighbours that are connected to each other
+// (in either direction). Nodes with fewer than two neighbours score 0.
+// NOTE: This function is not thread-safe, callers must hold graphMutex!
+func clusteringCoefficient(node string) float64 {
+	var neighbours []string
+	for _, neighbour := range sortedNeighbours(node) {
+		if neighbour != node { // A self-loop doesn't make a node its own neighbour
+			neighbours = append(neighbours, neighbour)
+		}
+	}
+	k := len(neighbours)
+	if k < 2 {
+		return 0
+	}
+
+	links := 0
+	for i, a := range neighbours {
+		for _, b := range neighbours[i+1:] {
+			_, ab := GraphStore[a][b]
+			_, ba := GraphStore[b][a]
+			if ab || ba {
+				links++
+			}
+		}
+	}
+	return float64(links) / float64(k*(k-1)/2)
+}
+
+// commonNeighbourCounts scores every friend-of-friend of node by how many of
+// node's friends lead to it, i.e. how many neighbours the two have in
+// common. Like G.FOF, node itself and its direct friends are left out.
+// NOTE: This function is not thread-safe, callers must hold graphMutex!
+func commonNeighbourCounts(node string) map[string]int {
+	friends := GraphStore[node]
+	counts := make(map[string]int)
+	for friend := range friends {
+		if friend == node {
+			continue
+		}
+		for candidate := range GraphStore[friend] {
+			if candidate == node {
+				continue
+			}
+			if _, isFriend := friends[candidate]; isFriend {
+				continue
+			}
+			counts[candidate]++
+		}
+	}
+	return counts
+}
+
+// inducedEdges returns the edges whose endpoints are both in nodes, i.e. the
+// subgraph the nodes induce. Edge direction is ignored, so each connected
+// pair appears once, written "a-b" with a < b; the list is sorted. A
+// self-loop on one of the nodes is "a-a". Unknown nodes simply add no edges.
+// NOTE: This function is not thread-safe, callers must hold graphMutex!
+func inducedEdges(nodes []string) []string {
+	inSet := make(map[string]bool, len(nodes))
+	for _, node := range nodes {
+		inSet[node] = true
+	}
+
+	pairs := make(map[string]bool)
+	for node := range inSet {
+		for next := range GraphStore[node] {
+			if !inSet[next] {
+				continue
+			}
+			a, b := node, next
+			if a > b {
+				a, b = b, a
+			}
+			pairs[a+"-"+b] = true
+		}
+	}
+	return sortedKeys(pairs)
+}
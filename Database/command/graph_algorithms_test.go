@@ -0,0 +1,347 @@
+package command
+
+import (
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestShortestPath(t *testing.T) {
+	resetGraph(t)
+
+	if got, want := runCommand(t, "G.SHORTESTPATH", "Alice", "Grace"), respArray("Alice", "Charlie", "Eve", "Grace"); got != want {
+		t.Errorf("G.SHORTESTPATH Alice Grace = %q, want %q", got, want)
+	}
+	if got, want := runCommand(t, "G.SHORTESTPATH", "Alice", "Alice"), respArray("Alice"); got != want {
+		t.Errorf("G.SHORTESTPATH Alice Alice = %q, want %q", got, want)
+	}
+
+	runCommand(t, "G.ADDEDGE", "Xavier", "Yolanda")
+	if got := runCommand(t, "G.SHORTESTPATH", "Alice", "Xavier"); got != "*-1\r\n" {
+		t.Errorf("G.SHORTESTPATH between components = %q, want a null array", got)
+	}
+	if got := runCommand(t, "G.SHORTESTPATH", "Alice", "Nobody"); got != "*-1\r\n" {
+		t.Errorf("G.SHORTESTPATH to a missing node = %q, want a null array", got)
+	}
+}
+
+func TestWeightedShortestPathPrefersCheapEdges(t *testing.T) {
+	resetGraph(t)
+	runCommand(t, "G.ADDEDGE", "S", "T", "10")
+	runCommand(t, "G.ADDEDGE", "S", "A", "1")
+	runCommand(t, "G.ADDEDGE", "A", "B", "2")
+	runCommand(t, "G.ADDEDGE", "B", "T", "3")
+
+	if got, want := runCommand(t, "G.SHORTESTPATH", "S", "T"), respArray("S", "T"); got != want {
+		t.Errorf("G.SHORTESTPATH S T = %q, want the one-hop %q", got, want)
+	}
+	if got, want := runCommand(t, "G.WSHORTESTPATH", "S", "T"), "*2\r\n"+respArray("S", "A", "B", "T")+":6\r\n"; got != want {
+		t.Errorf("G.WSHORTESTPATH S T = %q, want %q", got, want)
+	}
+
+	// With every weight 1 it finds the BFS path
+	if got, want := runCommand(t, "G.WSHORTESTPATH", "Alice", "Grace"), "*2\r\n"+respArray("Alice", "Charlie", "Eve", "Grace")+":3\r\n"; got != want {
+		t.Errorf("G.WSHORTESTPATH Alice Grace = %q, want %q", got, want)
+	}
+	if got := runCommand(t, "G.WSHORTESTPATH", "Alice", "S"); got[0] != '-' {
+		t.Errorf("G.WSHORTESTPATH between components = %q, want an error", got)
+	}
+}
+
+func TestReachMatchesFriendsAndFOF(t *testing.T) {
+	resetGraph(t)
+	runCommand(t, "G.ADDEDGE", "Bob", "Eve") // close a loop so hops overlap
+
+	graphMutex.RLock()
+	nodes := sortedKeys(GraphStore)
+	graphMutex.RUnlock()
+	for _, node := range nodes {
+		within2 := make(map[string]bool)
+		for _, n := range ParseArgs(runCommand(t, "G.GETFRIENDS", node)) {
+			within2[n] = true
+		}
+		if got, want := runCommand(t, "G.REACH", node, "1"), formatSetAsRespArray(within2); got != want {
+			t.Errorf("G.REACH %s 1 = %q, want its friends %q", node, got, want)
+		}
+		for _, n := range ParseArgs(runCommand(t, "G.FOF", node)) {
+			within2[n] = true
+		}
+		if got, want := runCommand(t, "G.REACH", node, "2"), formatSetAsRespArray(within2); got != want {
+			t.Errorf("G.REACH %s 2 = %q, want friends plus FOF %q", node, got, want)
+		}
+	}
+
+	resetGraph(t)
+	if got, want := runCommand(t, "G.REACH", "Alice", "3"), respArray("Bob", "Charlie", "David", "Eve", "Frank", "Grace"); got != want {
+		t.Errorf("G.REACH Alice 3 = %q, want %q", got, want)
+	}
+	if got, want := runCommand(t, "G.REACH", "Frank", "3"), respArray("Alice", "Bob", "David"); got != want {
+		t.Errorf("G.REACH Frank 3 = %q, want %q", got, want)
+	}
+	if got := runCommand(t, "G.REACH", "Alice", "0"); got[0] != '-' {
+		t.Errorf("G.REACH with depth 0 = %q, want an error", got)
+	}
+}
+
+func TestComponents(t *testing.T) {
+	resetGraph(t)
+	if got := runCommand(t, "G.COMPONENTS"); got != ":1\r\n" {
+		t.Errorf("seeded graph has %q components, want :1", got)
+	}
+	runCommand(t, "G.ADDEDGE", "Xavier", "Yolanda")
+	if got := runCommand(t, "G.COMPONENTS"); got != ":2\r\n" {
+		t.Errorf("with an isolated edge: %q components, want :2", got)
+	}
+	runCommand(t, "G.ADDEDGE", "Yolanda", "Grace")
+	if got := runCommand(t, "G.COMPONENTS"); got != ":1\r\n" {
+		t.Errorf("after joining the edge to the graph: %q components, want :1", got)
+	}
+}
+
+func TestPathExists(t *testing.T) {
+	resetGraph(t)
+	runCommand(t, "G.ADDEDGE", "Xavier", "Yolanda")
+
+	cases := []struct {
+		from, to, want string
+	}{
+		{"Frank", "Grace", ":1\r\n"},
+		{"Grace", "Frank", ":1\r\n"},
+		{"Alice", "Xavier", ":0\r\n"},
+		{"Alice", "Alice", ":1\r\n"},
+		{"Alice", "Nobody", ":0\r\n"},
+	}
+	for _, c := range cases {
+		if got := runCommand(t, "G.PATHEXISTS", c.from, c.to); got != c.want {
+			t.Errorf("G.PATHEXISTS %s %s = %q, want %q", c.from, c.to, got, c.want)
+		}
+	}
+}
+
+func TestBFSOrder(t *testing.T) {
+	resetGraph(t)
+	if got, want := runCommand(t, "G.BFS", "Alice"), respArray("Alice", "Bob", "Charlie", "David", "Eve", "Frank", "Grace"); got != want {
+		t.Errorf("G.BFS Alice = %q, want %q", got, want)
+	}
+
+	// A new neighbour of Alice joins the first level in sorted place, ahead
+	// of anything two hops away
+	runCommand(t, "G.ADDEDGE", "Alice", "Aaron")
+	if got, want := runCommand(t, "G.BFS", "Alice"), respArray("Alice", "Aaron", "Bob", "Charlie", "David", "Eve", "Frank", "Grace"); got != want {
+		t.Errorf("G.BFS Alice with Aaron = %q, want %q", got, want)
+	}
+	if got := runCommand(t, "G.BFS", "Nobody"); got != "*0\r\n" {
+		t.Errorf("G.BFS from a missing node = %q, want an empty array", got)
+	}
+}
+
+func TestDFSOrder(t *testing.T) {
+	resetGraph(t)
+	if got, want := runCommand(t, "G.DFS", "Alice"), respArray("Alice", "Bob", "David", "Frank", "Charlie", "Eve", "Grace"); got != want {
+		t.Errorf("G.DFS Alice = %q, want %q", got, want)
+	}
+
+	// Loops must not make a node appear twice
+	runCommand(t, "G.ADDEDGE", "Frank", "Grace")
+	runCommand(t, "G.ADDEDGE", "Bob", "Eve")
+	for _, node := range []string{"Alice", "Eve", "Grace"} {
+		order := ParseArgs(runCommand(t, "G.DFS", node))
+		seen := make(map[string]bool)
+		for _, n := range order {
+			if seen[n] {
+				t.Errorf("G.DFS %s visits %s twice: %v", node, n, order)
+			}
+			seen[n] = true
+		}
+		if len(order) != 7 || order[0] != node {
+			t.Errorf("G.DFS %s = %v, want all 7 nodes starting at %s", node, order, node)
+		}
+	}
+}
+
+func TestHasCycle(t *testing.T) {
+	resetGraph(t)
+	if got := runCommand(t, "G.HASCYCLE"); got != ":0\r\n" {
+		t.Errorf("seeded tree: G.HASCYCLE = %q, want :0", got)
+	}
+	runCommand(t, "G.ADDEDGE", "Frank", "Grace")
+	if got := runCommand(t, "G.HASCYCLE"); got != ":1\r\n" {
+		t.Errorf("after closing the path into a loop: G.HASCYCLE = %q, want :1", got)
+	}
+
+	// A cycle in a component of its own is found too
+	resetGraph(t)
+	runCommand(t, "G.ADDEDGE", "X", "Y")
+	runCommand(t, "G.ADDEDGE", "Y", "Z")
+	if got := runCommand(t, "G.HASCYCLE"); got != ":0\r\n" {
+		t.Errorf("two trees: G.HASCYCLE = %q, want :0", got)
+	}
+	runCommand(t, "G.ADDEDGE", "Z", "X")
+	if got := runCommand(t, "G.HASCYCLE"); got != ":1\r\n" {
+		t.Errorf("isolated triangle: G.HASCYCLE = %q, want :1", got)
+	}
+}
+
+func TestBridges(t *testing.T) {
+	resetGraph(t)
+	// On the seeded path every edge is a bridge
+	want := respArray("Alice-Bob", "Alice-Charlie", "Bob-David", "Charlie-Eve", "David-Frank", "Eve-Grace")
+	if got := runCommand(t, "G.BRIDGES"); got != want {
+		t.Errorf("G.BRIDGES on the path = %q, want %q", got, want)
+	}
+
+	// Closing the path into a cycle leaves none
+	runCommand(t, "G.ADDEDGE", "Frank", "Grace")
+	if got := runCommand(t, "G.BRIDGES"); got != "*0\r\n" {
+		t.Errorf("G.BRIDGES on a cycle = %q, want an empty array", got)
+	}
+
+	// A triangle hanging off the cycle by one edge: that edge is the bridge
+	runCommand(t, "G.ADDEDGE", "X", "Y")
+	runCommand(t, "G.ADDEDGE", "Y", "Z")
+	runCommand(t, "G.ADDEDGE", "Z", "X")
+	runCommand(t, "G.ADDEDGE", "Grace", "X")
+	if got, want := runCommand(t, "G.BRIDGES"), respArray("Grace-X"); got != want {
+		t.Errorf("G.BRIDGES with a hanging triangle = %q, want %q", got, want)
+	}
+}
+
+func TestArticulationPoints(t *testing.T) {
+	resetGraph(t)
+	// Every node inside the path is a cut vertex; the two ends aren't
+	want := respArray("Alice", "Bob", "Charlie", "David", "Eve")
+	if got := runCommand(t, "G.ARTICULATION"); got != want {
+		t.Errorf("G.ARTICULATION on the path = %q, want %q", got, want)
+	}
+
+	runCommand(t, "G.ADDEDGE", "Frank", "Grace")
+	if got := runCommand(t, "G.ARTICULATION"); got != "*0\r\n" {
+		t.Errorf("G.ARTICULATION on a cycle = %q, want an empty array", got)
+	}
+
+	// Both ends of the edge joining a triangle to the cycle are cut vertices
+	runCommand(t, "G.ADDEDGE", "X", "Y")
+	runCommand(t, "G.ADDEDGE", "Y", "Z")
+	runCommand(t, "G.ADDEDGE", "Z", "X")
+	runCommand(t, "G.ADDEDGE", "Grace", "X")
+	if got, want := runCommand(t, "G.ARTICULATION"), respArray("Grace", "X"); got != want {
+		t.Errorf("G.ARTICULATION with a hanging triangle = %q, want %q", got, want)
+	}
+}
+
+// pageRanks runs G.PAGERANK and returns the nodes in reply order with their
+// scores.
+func pageRanks(t *testing.T, args ...string) ([]string, map[string]float64) {
+	t.Helper()
+	items := ParseArgs(runCommand(t, append([]string{"G.PAGERANK"}, args...)...))
+	var order []string
+	scores := make(map[string]float64)
+	for i := 0; i+1 < len(items); i += 2 {
+		score, err := strconv.ParseFloat(items[i+1], 64)
+		if err != nil {
+			t.Fatalf("score %q of %s: %v", items[i+1], items[i], err)
+		}
+		order = append(order, items[i])
+		scores[items[i]] = score
+	}
+	return order, scores
+}
+
+func TestPageRank(t *testing.T) {
+	resetGraph(t)
+	order, scores := pageRanks(t)
+	if len(order) != 7 {
+		t.Fatalf("G.PAGERANK ranked %d nodes, want 7: %v", len(order), order)
+	}
+	sum := 0.0
+	for _, s := range scores {
+		sum += s
+	}
+	if math.Abs(sum-1) > 1e-4 {
+		t.Errorf("scores sum to %f, want 1", sum)
+	}
+	// On the path, both ends rank below every inner node
+	for _, inner := range []string{"Alice", "Bob", "Charlie", "David", "Eve"} {
+		for _, end := range []string{"Frank", "Grace"} {
+			if scores[inner] <= scores[end] {
+				t.Errorf("%s (%f) doesn't outrank the path end %s (%f)", inner, scores[inner], end, scores[end])
+			}
+		}
+	}
+	if order[5] != "Frank" || order[6] != "Grace" {
+		t.Errorf("G.PAGERANK order = %v, want the ends last", order)
+	}
+
+	// A hub connected to most of the graph ranks first
+	for _, n := range []string{"David", "Eve", "Frank", "Grace"} {
+		runCommand(t, "G.ADDEDGE", "Alice", n)
+	}
+	if order, _ := pageRanks(t, "50", "0.9"); order[0] != "Alice" {
+		t.Errorf("with Alice as a hub the order is %v, want Alice first", order)
+	}
+	if got := runCommand(t, "G.PAGERANK", "20", "1.5"); got[0] != '-' {
+		t.Errorf("damping 1.5 = %q, want an error", got)
+	}
+}
+
+func TestClustering(t *testing.T) {
+	resetGraph(t)
+	// Alice's friends Bob and Charlie aren't connected
+	if got := runCommand(t, "G.CLUSTERING", "Alice"); got != "$8\r\n0.000000\r\n" {
+		t.Errorf("G.CLUSTERING Alice on the path = %q, want 0", got)
+	}
+
+	// Triangle
+	runCommand(t, "G.ADDEDGE", "T1", "T2")
+	runCommand(t, "G.ADDEDGE", "T2", "T3")
+	runCommand(t, "G.ADDEDGE", "T3", "T1")
+	// Star
+	for _, leaf := range []string{"S1", "S2", "S3", "S4"} {
+		runCommand(t, "G.ADDEDGE", "Hub", leaf)
+	}
+
+	cases := []struct {
+		node, want string
+	}{
+		{"T1", "1.000000"},
+		{"Hub", "0.000000"},
+		{"S1", "0.000000"}, // Fewer than two friends
+		{"Nobody", "0.000000"},
+	}
+	for _, c := range cases {
+		if got, want := runCommand(t, "G.CLUSTERING", c.node), "$8\r\n"+c.want+"\r\n"; got != want {
+			t.Errorf("G.CLUSTERING %s = %q, want %q", c.node, got, want)
+		}
+	}
+
+	// One link between two of the four leaves: 1 of 6 possible
+	runCommand(t, "G.ADDEDGE", "S1", "S2")
+	if got := runCommand(t, "G.CLUSTERING", "Hub"); got != "$8\r\n0.166667\r\n" {
+		t.Errorf("G.CLUSTERING Hub with one leaf link = %q, want 0.166667", got)
+	}
+}
+
+func TestRecommendRanksByCommonFriends(t *testing.T) {
+	resetGraph(t)
+	// Eve becomes reachable through both of Alice's friends, David through one.
+	// Bob and Charlie become each other's friends, but stay out as Alice's.
+	runCommand(t, "G.ADDEDGE", "Bob", "Eve")
+	runCommand(t, "G.ADDEDGE", "Bob", "Charlie")
+
+	if got, want := runCommand(t, "G.RECOMMEND", "Alice"), respArray("Eve:2", "David:1"); got != want {
+		t.Errorf("G.RECOMMEND Alice = %q, want %q", got, want)
+	}
+	if got, want := runCommand(t, "G.RECOMMEND", "Alice", "1"), respArray("Eve:2"); got != want {
+		t.Errorf("G.RECOMMEND Alice 1 = %q, want %q", got, want)
+	}
+
+	// Equal counts are ordered by name
+	runCommand(t, "G.ADDEDGE", "Charlie", "Dan")
+	runCommand(t, "G.ADDEDGE", "Bob", "Dan")
+	if got, want := runCommand(t, "G.RECOMMEND", "Alice"), respArray("Dan:2", "Eve:2", "David:1"); got != want {
+		t.Errorf("G.RECOMMEND Alice with a tie = %q, want %q", got, want)
+	}
+	if got := runCommand(t, "G.RECOMMEND", "Nobody"); got != "*0\r\n" {
+		t.Errorf("G.RECOMMEND for a missing node = %q, want an empty array", got)
+	}
+}
@@ -3,46 +3,155 @@ package command
 import (
 	"fmt"
 	"net"
+	"sort"
+	"strconv"
 	"strings"
 )
 
-// HandleGraphAddEdge processes G.ADDEDGE <node1> <node2>
-func HandleGraphAddEdge(input string, c net.Conn) {
-	parts := strings.Split(input, "\r\n")
-	if len(parts) < 7 {
+// defaultRecommendLimit is how many candidates G.RECOMMEND returns when it
+// isn't given a count.
+const defaultRecommendLimit = 10
+
+// PageRank defaults, used when G.PAGERANK isn't given them.
+const (
+	defaultPageRankIterations = 20
+	defaultPageRankDamping    = 0.85
+)
+
+func init() {
+	Register(CommandInfo{Name: "G.ADDEDGE", Usage: "G.ADDEDGE <a> <b> [weight] [DIRECTED]", Summary: "Connect two nodes"}, HandlerFunc(HandleGraphAddEdge))
+	Register(CommandInfo{Name: "G.SETMAXDEGREE", Usage: "G.SETMAXDEGREE <node> <n>", Summary: "Cap how many connections a node may have"}, HandlerFunc(HandleGraphSetMaxDegree))
+	Register(CommandInfo{Name: "G.DELEDGE", Usage: "G.DELEDGE <a> <b>", Summary: "Remove the edge between two nodes"}, HandlerFunc(HandleGraphDelEdge))
+	Register(CommandInfo{Name: "G.GETFRIENDS", Usage: "G.GETFRIENDS <node> [pattern]", Summary: "List a node's neighbours, optionally only those matching a glob pattern"}, HandlerFunc(HandleGraphGetFriends))
+	Register(CommandInfo{Name: "G.DEGREE", Usage: "G.DEGREE <node>", Summary: "Count a node's connections"}, HandlerFunc(HandleGraphDegree))
+	Register(CommandInfo{Name: "G.FOF", Usage: "G.FOF <node>", Summary: "List friends-of-friends"}, HandlerFunc(HandleGraphFOF))
+	Register(CommandInfo{Name: "G.MUTUAL", Usage: "G.MUTUAL <a> <b>", Summary: "List the friends two nodes have in common"}, HandlerFunc(HandleGraphMutual))
+	Register(CommandInfo{Name: "G.REACH", Usage: "G.REACH <node> <depth>", Summary: "List the nodes within depth hops"}, HandlerFunc(HandleGraphReach))
+	Register(CommandInfo{Name: "G.COMPONENTS", Usage: "G.COMPONENTS", Summary: "Count the connected components"}, HandlerFunc(HandleGraphComponents))
+	Register(CommandInfo{Name: "G.HASCYCLE", Usage: "G.HASCYCLE", Summary: "Report whether the graph has a cycle"}, HandlerFunc(HandleGraphHasCycle))
+	Register(CommandInfo{Name: "G.BRIDGES", Usage: "G.BRIDGES", Summary: "List the edges whose removal splits the graph"}, HandlerFunc(HandleGraphBridges))
+	Register(CommandInfo{Name: "G.ARTICULATION", Usage: "G.ARTICULATION", Summary: "List the nodes whose removal splits the graph"}, HandlerFunc(HandleGraphArticulation))
+	Register(CommandInfo{Name: "G.SHORTESTPATH", Usage: "G.SHORTESTPATH <a> <b>", Summary: "Find the fewest-hops path"}, HandlerFunc(HandleGraphShortestPath))
+	Register(CommandInfo{Name: "G.PATHEXISTS", Usage: "G.PATHEXISTS <a> <b>", Summary: "Report whether b can be reached from a"}, HandlerFunc(HandleGraphPathExists))
+	Register(CommandInfo{Name: "G.BFS", Usage: "G.BFS <node>", Summary: "List the reachable nodes in breadth-first order"}, HandlerFunc(HandleGraphBFS))
+	Register(CommandInfo{Name: "G.DFS", Usage: "G.DFS <node>", Summary: "List the reachable nodes in depth-first order"}, HandlerFunc(HandleGraphDFS))
+	Register(CommandInfo{Name: "G.VERIFY", Usage: "G.VERIFY [REPAIR]", Summary: "List the edges missing their reverse, or add the missing reverses"}, HandlerFunc(HandleGraphVerify))
+	Register(CommandInfo{Name: "G.PAGERANK", Usage: "G.PAGERANK [iterations] [damping]", Summary: "Rank the nodes by PageRank score"}, HandlerFunc(HandleGraphPageRank))
+	Register(CommandInfo{Name: "G.CLUSTERING", Usage: "G.CLUSTERING <node>", Summary: "Report how connected a node's neighbours are to each other"}, HandlerFunc(HandleGraphClustering))
+	Register(CommandInfo{Name: "G.RECOMMEND", Usage: "G.RECOMMEND <node> [count]", Summary: "Suggest new friends ranked by common neighbours"}, HandlerFunc(HandleGraphRecommend))
+	Register(CommandInfo{Name: "G.WSHORTESTPATH", Usage: "G.WSHORTESTPATH <a> <b>", Summary: "Find the lowest-weight path and its cost"}, HandlerFunc(HandleGraphWeightedShortestPath))
+	Register(CommandInfo{Name: "G.SUBGRAPH", Usage: "G.SUBGRAPH <node> [node ...]", Summary: "List the edges among the given nodes"}, HandlerFunc(HandleGraphSubgraph))
+}
+
+// HandleGraphAddEdge processes G.ADDEDGE <node1> <node2> [weight] [DIRECTED]
+// Without the DIRECTED flag the edge goes both ways. The weight is a positive
+// integer (connection strength) and defaults to 1.
+func HandleGraphAddEdge(argv []string, c net.Conn) {
+	if len(argv) < 3 {
 		c.Write([]byte("-ERR wrong number of arguments for G.ADDEDGE\r\n"))
 		return
 	}
-	node1 := parts[4]
-	node2 := parts[6]
+	node1 := argv[1]
+	node2 := argv[2]
+
+	// Optional trailing arguments: a weight and/or DIRECTED
+	directed := false
+	weight := defaultEdgeWeight
+	for _, arg := range argv[3:] {
+		if arg == "" {
+			continue
+		}
+		if strings.EqualFold(arg, "DIRECTED") {
+			directed = true
+			continue
+		}
+		w, err := strconv.Atoi(arg)
+		if err != nil {
+			c.Write([]byte("-ERR unknown G.ADDEDGE option '" + arg + "'\r\n"))
+			return
+		}
+		if w <= 0 {
+			c.Write([]byte("-ERR edge weight must be a positive integer\r\n"))
+			return
+		}
+		weight = w
+	}
 
 	graphMutex.Lock()
 	defer graphMutex.Unlock()
 
-	// Add edge from node1 to node2
-	if _, ok := GraphStore[node1]; !ok {
-		GraphStore[node1] = make(map[string]bool)
+	if exceedsDegreeLimit(node1, node2) || (!directed && exceedsDegreeLimit(node2, node1)) {
+		c.Write([]byte("-ERR degree limit exceeded\r\n"))
+		return
 	}
-	GraphStore[node1][node2] = true
 
-	// Add edge from node2 to node1 (undirected graph)
-	if _, ok := GraphStore[node2]; !ok {
-		GraphStore[node2] = make(map[string]bool)
+	if directed {
+		addDirectedEdge(node1, node2, weight)
+		fmt.Printf("Graph edge added: %s -> %s (weight %d)\n", node1, node2, weight)
+	} else {
+		addEdge(node1, node2, weight)
+		fmt.Printf("Graph edge added: %s <-> %s (weight %d)\n", node1, node2, weight)
+	}
+	c.Write([]byte("+OK\r\n"))
+}
+
+// HandleGraphSetMaxDegree processes G.SETMAXDEGREE <node> <n>
+// Caps the node's number of connections at n. Existing edges are kept even if
+// they exceed the new limit; only new edges are rejected by G.ADDEDGE.
+func HandleGraphSetMaxDegree(argv []string, c net.Conn) {
+	if len(argv) < 3 {
+		c.Write([]byte("-ERR wrong number of arguments for G.SETMAXDEGREE\r\n"))
+		return
+	}
+	node := argv[1]
+	limit, err := strconv.Atoi(argv[2])
+	if err != nil || limit < 0 {
+		c.Write([]byte("-ERR max degree must be a non-negative integer\r\n"))
+		return
 	}
-	GraphStore[node2][node1] = true
 
-	fmt.Printf("Graph edge added: %s <-> %s\n", node1, node2)
+	graphMutex.Lock()
+	defer graphMutex.Unlock()
+
+	degreeLimits[node] = limit
+	fmt.Printf("Graph degree limit set: %s <= %d\n", node, limit)
 	c.Write([]byte("+OK\r\n"))
 }
 
-// HandleGraphGetFriends processes G.GETFRIENDS <node>
-func HandleGraphGetFriends(input string, c net.Conn) {
-	parts := strings.Split(input, "\r\n")
-	if len(parts) < 5 {
+// HandleGraphDelEdge processes G.DELEDGE <node1> <node2>
+// Both directions are removed. Replies :1 if an edge was removed, :0 otherwise.
+func HandleGraphDelEdge(argv []string, c net.Conn) {
+	if len(argv) < 3 {
+		c.Write([]byte("-ERR wrong number of arguments for G.DELEDGE\r\n"))
+		return
+	}
+	node1 := argv[1]
+	node2 := argv[2]
+
+	graphMutex.Lock()
+	defer graphMutex.Unlock()
+
+	removedForward := removeDirectedEdge(node1, node2)
+	removedBackward := removeDirectedEdge(node2, node1)
+
+	if removedForward || removedBackward {
+		fmt.Printf("Graph edge removed: %s <-> %s\n", node1, node2)
+		c.Write([]byte(":1\r\n"))
+		return
+	}
+	c.Write([]byte(":0\r\n"))
+}
+
+// HandleGraphGetFriends processes G.GETFRIENDS <node> [pattern]
+// For directed edges only the outgoing neighbours are returned. With a
+// pattern only the friends matching it are, using the same '*' and '?'
+// wildcards as KEYS.
+func HandleGraphGetFriends(argv []string, c net.Conn) {
+	if len(argv) < 2 {
 		c.Write([]byte("-ERR wrong number of arguments for G.GETFRIENDS\r\n"))
 		return
 	}
-	node := parts[4]
+	node := argv[1]
 
 	graphMutex.RLock()
 	defer graphMutex.RUnlock()
@@ -53,19 +162,44 @@ func HandleGraphGetFriends(input string, c net.Conn) {
 		return
 	}
 
+	if len(argv) > 2 && argv[2] != "" {
+		pattern := compileGlobPattern(argv[2])
+		matching := make(map[string]int)
+		for friend, weight := range friends {
+			if pattern.MatchString(friend) {
+				matching[friend] = weight
+			}
+		}
+		friends = matching
+	}
+
 	// Convert the set of friends to a RESP array
 	resp := formatSetAsRespArray(friends)
 	c.Write([]byte(resp))
 }
 
+// HandleGraphDegree processes G.DEGREE <node>
+// Replies with the number of direct (outgoing) connections, :0 if the node doesn't exist.
+func HandleGraphDegree(argv []string, c net.Conn) {
+	if len(argv) < 2 {
+		c.Write([]byte("-ERR wrong number of arguments for G.DEGREE\r\n"))
+		return
+	}
+	node := argv[1]
+
+	graphMutex.RLock()
+	defer graphMutex.RUnlock()
+
+	c.Write([]byte(fmt.Sprintf(":%d\r\n", len(GraphStore[node]))))
+}
+
 // HandleGraphFOF processes G.FOF <node> (Friends of Friends)
-func HandleGraphFOF(input string, c net.Conn) {
-	parts := strings.Split(input, "\r\n")
-	if len(parts) < 5 {
+func HandleGraphFOF(argv []string, c net.Conn) {
+	if len(argv) < 2 {
 		c.Write([]byte("-ERR wrong number of arguments for G.FOF\r\n"))
 		return
 	}
-	startNode := parts[4]
+	startNode := argv[1]
 
 	graphMutex.RLock()
 	defer graphMutex.RUnlock()
@@ -109,4 +243,347 @@ func HandleGraphFOF(input string, c net.Conn) {
 	// 8. Format and return the result
 	resp := formatSetAsRespArray(fofSet)
 	c.Write([]byte(resp))
-}
\ No newline at end of file
+}
+
+// HandleGraphMutual processes G.MUTUAL <node1> <node2>
+// Returns the friends both nodes have in common.
+func HandleGraphMutual(argv []string, c net.Conn) {
+	if len(argv) < 3 {
+		c.Write([]byte("-ERR wrong number of arguments for G.MUTUAL\r\n"))
+		return
+	}
+	node1 := argv[1]
+	node2 := argv[2]
+
+	graphMutex.RLock()
+	defer graphMutex.RUnlock()
+
+	// Walk the smaller neighbour set and probe the larger one
+	small, large := GraphStore[node1], GraphStore[node2]
+	if len(small) > len(large) {
+		small, large = large, small
+	}
+
+	mutual := make(map[string]bool)
+	for friend := range small {
+		if friend == node1 || friend == node2 {
+			continue // The two nodes being friends doesn't make them mutual friends
+		}
+		if _, ok := large[friend]; ok {
+			mutual[friend] = true
+		}
+	}
+
+	resp := formatSetAsRespArray(mutual)
+	c.Write([]byte(resp))
+}
+
+// HandleGraphReach processes G.REACH <node> <depth>
+// Returns every node within depth hops of the start (the start itself is
+// excluded). G.REACH <node> 2 is the direct friends plus the G.FOF result.
+func HandleGraphReach(argv []string, c net.Conn) {
+	if len(argv) < 3 {
+		c.Write([]byte("-ERR wrong number of arguments for G.REACH\r\n"))
+		return
+	}
+	startNode := argv[1]
+	depth, err := strconv.Atoi(argv[2])
+	if err != nil || depth <= 0 {
+		c.Write([]byte("-ERR depth must be a positive integer\r\n"))
+		return
+	}
+
+	graphMutex.RLock()
+	defer graphMutex.RUnlock()
+
+	resp := formatSetAsRespArray(reachableWithin(startNode, depth))
+	c.Write([]byte(resp))
+}
+
+// HandleGraphComponents processes G.COMPONENTS
+// Replies with the number of disconnected clusters in the graph.
+func HandleGraphComponents(argv []string, c net.Conn) {
+	graphMutex.RLock()
+	defer graphMutex.RUnlock()
+
+	c.Write([]byte(fmt.Sprintf(":%d\r\n", countComponents())))
+}
+
+// HandleGraphHasCycle processes G.HASCYCLE
+// Replies :1 if the graph (ignoring edge direction) contains a cycle, :0 otherwise.
+func HandleGraphHasCycle(argv []string, c net.Conn) {
+	graphMutex.RLock()
+	defer graphMutex.RUnlock()
+
+	if hasCycle() {
+		c.Write([]byte(":1\r\n"))
+	} else {
+		c.Write([]byte(":0\r\n"))
+	}
+}
+
+// HandleGraphBridges processes G.BRIDGES
+// Replies with the edges whose removal would disconnect the graph (ignoring
+// edge direction) as a sorted array of "a-b" strings.
+func HandleGraphBridges(argv []string, c net.Conn) {
+	graphMutex.RLock()
+	defer graphMutex.RUnlock()
+
+	c.Write([]byte(formatListAsRespArray(findBridges())))
+}
+
+// HandleGraphArticulation processes G.ARTICULATION
+// Replies with the nodes whose removal would split the graph (ignoring edge
+// direction) into more components, as a sorted array.
+func HandleGraphArticulation(argv []string, c net.Conn) {
+	graphMutex.RLock()
+	defer graphMutex.RUnlock()
+
+	c.Write([]byte(formatListAsRespArray(findArticulationPoints())))
+}
+
+// HandleGraphShortestPath processes G.SHORTESTPATH <from> <to>
+// Replies with the hop-by-hop path as an ordered array, or a null array if
+// the nodes aren't connected.
+func HandleGraphShortestPath(argv []string, c net.Conn) {
+	if len(argv) < 3 {
+		c.Write([]byte("-ERR wrong number of arguments for G.SHORTESTPATH\r\n"))
+		return
+	}
+	from := argv[1]
+	to := argv[2]
+
+	graphMutex.RLock()
+	defer graphMutex.RUnlock()
+
+	path := shortestPath(from, to)
+	if path == nil {
+		c.Write([]byte("*-1\r\n")) // No path
+		return
+	}
+	c.Write([]byte(formatListAsRespArray(path)))
+}
+
+// HandleGraphPathExists processes G.PATHEXISTS <from> <to>
+// Replies :1 if to is reachable from from, :0 otherwise. Cheaper than
+// G.SHORTESTPATH since no path is built.
+func HandleGraphPathExists(argv []string, c net.Conn) {
+	if len(argv) < 3 {
+		c.Write([]byte("-ERR wrong number of arguments for G.PATHEXISTS\r\n"))
+		return
+	}
+	from := argv[1]
+	to := argv[2]
+
+	graphMutex.RLock()
+	defer graphMutex.RUnlock()
+
+	if pathExists(from, to) {
+		c.Write([]byte(":1\r\n"))
+	} else {
+		c.Write([]byte(":0\r\n"))
+	}
+}
+
+// HandleGraphBFS processes G.BFS <start>
+// Replies with every node reachable from start in breadth-first order, as an
+// ordered array (empty if the node doesn't exist).
+func HandleGraphBFS(argv []string, c net.Conn) {
+	if len(argv) < 2 {
+		c.Write([]byte("-ERR wrong number of arguments for G.BFS\r\n"))
+		return
+	}
+	start := argv[1]
+
+	graphMutex.RLock()
+	defer graphMutex.RUnlock()
+
+	c.Write([]byte(formatListAsRespArray(bfsOrder(start))))
+}
+
+// HandleGraphDFS processes G.DFS <start>
+// Replies with every node reachable from start in depth-first order, as an
+// ordered array (empty if the node doesn't exist).
+func HandleGraphDFS(argv []string, c net.Conn) {
+	if len(argv) < 2 {
+		c.Write([]byte("-ERR wrong number of arguments for G.DFS\r\n"))
+		return
+	}
+	start := argv[1]
+
+	graphMutex.RLock()
+	defer graphMutex.RUnlock()
+
+	c.Write([]byte(formatListAsRespArray(dfsOrder(start))))
+}
+
+// HandleGraphWeightedShortestPath processes G.WSHORTESTPATH <from> <to>
+// Replies with a two-element array: the cheapest path (ordered array) and its
+// total weight (integer). Disconnected nodes produce an error reply.
+func HandleGraphWeightedShortestPath(argv []string, c net.Conn) {
+	if len(argv) < 3 {
+		c.Write([]byte("-ERR wrong number of arguments for G.WSHORTESTPATH\r\n"))
+		return
+	}
+	from := argv[1]
+	to := argv[2]
+
+	graphMutex.RLock()
+	defer graphMutex.RUnlock()
+
+	path, cost, ok := weightedPath(from, to)
+	if !ok {
+		c.Write([]byte(fmt.Sprintf("-ERR no path from '%s' to '%s'\r\n", from, to)))
+		return
+	}
+	resp := "*2\r\n" + formatListAsRespArray(path) + fmt.Sprintf(":%d\r\n", cost)
+	c.Write([]byte(resp))
+}
+
+// HandleGraphVerify processes G.VERIFY [REPAIR]
+// Checks the undirected invariant: every edge a -> b should have a matching
+// b -> a. Without REPAIR it replies with the offending edges as a sorted
+// array of "a->b" strings; with REPAIR it adds each missing reverse edge
+// (same weight, ignoring degree limits) and replies with how many it added.
+// Edges added with DIRECTED are one-way on purpose and are reported too.
+func HandleGraphVerify(argv []string, c net.Conn) {
+	repair := false
+	if len(argv) > 1 {
+		if !strings.EqualFold(argv[1], "REPAIR") {
+			c.Write([]byte("-ERR unknown G.VERIFY option '" + argv[1] + "'\r\n"))
+			return
+		}
+		repair = true
+	}
+
+	if !repair {
+		graphMutex.RLock()
+		defer graphMutex.RUnlock()
+
+		edges := asymmetricEdges()
+		items := make([]string, len(edges))
+		for i, edge := range edges {
+			items[i] = edge[0] + "->" + edge[1]
+		}
+		c.Write([]byte(formatListAsRespArray(items)))
+		return
+	}
+
+	graphMutex.Lock()
+	defer graphMutex.Unlock()
+
+	edges := asymmetricEdges()
+	for _, edge := range edges {
+		addDirectedEdge(edge[1], edge[0], GraphStore[edge[0]][edge[1]])
+		fmt.Printf("Graph edge repaired: added %s -> %s\n", edge[1], edge[0])
+	}
+	c.Write([]byte(fmt.Sprintf(":%d\r\n", len(edges))))
+}
+
+// HandleGraphPageRank processes G.PAGERANK [iterations] [damping]
+// Replies with a flat array of node, score pairs, highest score first (ties
+// by name), with scores as bulk strings to 6 decimal places. Iterations
+// defaults to 20 and damping, between 0 and 1, to 0.85.
+func HandleGraphPageRank(argv []string, c net.Conn) {
+	iterations := defaultPageRankIterations
+	damping := defaultPageRankDamping
+	if len(argv) > 1 {
+		n, err := strconv.Atoi(argv[1])
+		if err != nil || n <= 0 {
+			c.Write([]byte("-ERR iterations must be a positive integer\r\n"))
+			return
+		}
+		iterations = n
+	}
+	if len(argv) > 2 {
+		d, err := strconv.ParseFloat(argv[2], 64)
+		if err != nil || d < 0 || d > 1 {
+			c.Write([]byte("-ERR damping must be a number between 0 and 1\r\n"))
+			return
+		}
+		damping = d
+	}
+
+	graphMutex.RLock()
+	defer graphMutex.RUnlock()
+
+	ranks := pageRank(iterations, damping)
+	nodes := sortedKeys(ranks)
+	sort.SliceStable(nodes, func(i, j int) bool { return ranks[nodes[i]] > ranks[nodes[j]] })
+
+	items := make([]string, 0, 2*len(nodes))
+	for _, node := range nodes {
+		items = append(items, node, strconv.FormatFloat(ranks[node], 'f', 6, 64))
+	}
+	c.Write([]byte(formatListAsRespArray(items)))
+}
+
+// HandleGraphClustering processes G.CLUSTERING <node>
+// Replies with the node's local clustering coefficient, between 0 and 1, as
+// a bulk string to 6 decimal places: 1 when all its neighbours know each
+// other, 0 when none do or it has fewer than two.
+func HandleGraphClustering(argv []string, c net.Conn) {
+	if len(argv) < 2 {
+		c.Write([]byte("-ERR wrong number of arguments for G.CLUSTERING\r\n"))
+		return
+	}
+	node := argv[1]
+
+	graphMutex.RLock()
+	defer graphMutex.RUnlock()
+
+	score := strconv.FormatFloat(clusteringCoefficient(node), 'f', 6, 64)
+	c.Write([]byte(fmt.Sprintf("$%d\r\n%s\r\n", len(score), score)))
+}
+
+// HandleGraphRecommend processes G.RECOMMEND <node> [count]
+// Ranks the node's friends-of-friends by how many friends they share with
+// it (most first, ties by name) and replies with the top count (default 10)
+// as "name:common" strings.
+func HandleGraphRecommend(argv []string, c net.Conn) {
+	if len(argv) < 2 {
+		c.Write([]byte("-ERR wrong number of arguments for G.RECOMMEND\r\n"))
+		return
+	}
+	node := argv[1]
+	limit := defaultRecommendLimit
+	if len(argv) > 2 {
+		n, err := strconv.Atoi(argv[2])
+		if err != nil || n <= 0 {
+			c.Write([]byte("-ERR count must be a positive integer\r\n"))
+			return
+		}
+		limit = n
+	}
+
+	graphMutex.RLock()
+	defer graphMutex.RUnlock()
+
+	counts := commonNeighbourCounts(node)
+	candidates := sortedKeys(counts)
+	sort.SliceStable(candidates, func(i, j int) bool { return counts[candidates[i]] > counts[candidates[j]] })
+	if len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+
+	items := make([]string, len(candidates))
+	for i, candidate := range candidates {
+		items[i] = fmt.Sprintf("%s:%d", candidate, counts[candidate])
+	}
+	c.Write([]byte(formatListAsRespArray(items)))
+}
+
+// HandleGraphSubgraph processes G.SUBGRAPH <node1> <node2> ...
+// Replies with the edges among the given nodes (the induced subgraph),
+// ignoring edge direction, as a sorted array of "a-b" strings.
+func HandleGraphSubgraph(argv []string, c net.Conn) {
+	if len(argv) < 2 {
+		c.Write([]byte("-ERR wrong number of arguments for G.SUBGRAPH\r\n"))
+		return
+	}
+
+	graphMutex.RLock()
+	defer graphMutex.RUnlock()
+
+	c.Write([]byte(formatListAsRespArray(inducedEdges(argv[1:]))))
+}
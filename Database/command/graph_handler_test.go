@@ -0,0 +1,307 @@
+package command
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDirectedEdgesAreOneWay(t *testing.T) {
+	resetGraph(t)
+	for _, edge := range [][2]string{{"X", "Y"}, {"Y", "Z"}} {
+		if reply := runCommand(t, "G.ADDEDGE", edge[0], edge[1], "DIRECTED"); reply != "+OK\r\n" {
+			t.Fatalf("G.ADDEDGE %s %s DIRECTED replied %q", edge[0], edge[1], reply)
+		}
+	}
+
+	cases := []struct {
+		node string
+		want string
+	}{
+		{"X", respArray("Y")},
+		{"Y", respArray("Z")},
+		{"Z", respArray()},
+	}
+	for _, c := range cases {
+		if got := runCommand(t, "G.GETFRIENDS", c.node); got != c.want {
+			t.Errorf("G.GETFRIENDS %s = %q, want %q", c.node, got, c.want)
+		}
+	}
+
+	// Without DIRECTED the edge still goes both ways
+	runCommand(t, "G.ADDEDGE", "P", "Q")
+	if got := runCommand(t, "G.GETFRIENDS", "Q"); got != respArray("P") {
+		t.Errorf("G.GETFRIENDS Q after an undirected edge = %q", got)
+	}
+}
+
+func TestDelEdge(t *testing.T) {
+	resetGraph(t)
+
+	if got := runCommand(t, "G.DELEDGE", "Bob", "Alice"); got != ":1\r\n" {
+		t.Errorf("G.DELEDGE of an existing edge = %q, want :1", got)
+	}
+	if got := runCommand(t, "G.GETFRIENDS", "Alice"); got != respArray("Charlie") {
+		t.Errorf("Alice's friends after the delete = %q", got)
+	}
+	if got := runCommand(t, "G.GETFRIENDS", "Bob"); got != respArray("David") {
+		t.Errorf("Bob's friends after the delete = %q", got)
+	}
+	if got := runCommand(t, "G.DELEDGE", "Alice", "Grace"); got != ":0\r\n" {
+		t.Errorf("G.DELEDGE of a missing edge = %q, want :0", got)
+	}
+	if got := runCommand(t, "G.DELEDGE", "Nobody", "Alice"); got != ":0\r\n" {
+		t.Errorf("G.DELEDGE from a missing node = %q, want :0", got)
+	}
+
+	// Frank's only edge goes, and Frank with it
+	runCommand(t, "G.DELEDGE", "Frank", "David")
+	graphMutex.RLock()
+	_, frank := GraphStore["Frank"]
+	graphMutex.RUnlock()
+	if frank {
+		t.Error("Frank kept an empty neighbour map")
+	}
+}
+
+func TestEdgeWeightsAreSymmetric(t *testing.T) {
+	resetGraph(t)
+
+	if reply := runCommand(t, "G.ADDEDGE", "Alice", "Zoe", "5"); reply != "+OK\r\n" {
+		t.Fatalf("G.ADDEDGE with a weight replied %q", reply)
+	}
+	runCommand(t, "G.ADDEDGE", "Zoe", "Yuri")
+	runCommand(t, "G.ADDEDGE", "Zoe", "Walt", "3", "DIRECTED")
+
+	graphMutex.RLock()
+	defer graphMutex.RUnlock()
+	cases := []struct {
+		from, to string
+		want     int
+		exists   bool
+	}{
+		{"Alice", "Zoe", 5, true},
+		{"Zoe", "Alice", 5, true},
+		{"Zoe", "Yuri", defaultEdgeWeight, true},
+		{"Yuri", "Zoe", defaultEdgeWeight, true},
+		{"Zoe", "Walt", 3, true},
+		{"Walt", "Zoe", 0, false},
+	}
+	for _, c := range cases {
+		got, exists := GraphStore[c.from][c.to]
+		if got != c.want || exists != c.exists {
+			t.Errorf("%s -> %s: weight %d (exists %v), want %d (exists %v)", c.from, c.to, got, exists, c.want, c.exists)
+		}
+	}
+}
+
+func TestAddEdgeRejectsBadWeights(t *testing.T) {
+	resetGraph(t)
+	for _, weight := range []string{"0", "-2", "heavy"} {
+		if reply := runCommand(t, "G.ADDEDGE", "Alice", "Zoe", weight); reply[0] != '-' {
+			t.Errorf("weight %q: replied %q, want an error", weight, reply)
+		}
+	}
+}
+
+func TestDegree(t *testing.T) {
+	resetGraph(t)
+	cases := map[string]string{
+		"Alice":  ":2\r\n",
+		"Grace":  ":1\r\n",
+		"Nobody": ":0\r\n",
+	}
+	for node, want := range cases {
+		if got := runCommand(t, "G.DEGREE", node); got != want {
+			t.Errorf("G.DEGREE %s = %q, want %q", node, got, want)
+		}
+	}
+}
+
+func TestMutual(t *testing.T) {
+	resetGraph(t)
+	cases := []struct {
+		a, b string
+		want string
+	}{
+		{"Bob", "Charlie", respArray("Alice")},
+		{"Alice", "Eve", respArray("Charlie")},
+		{"Alice", "Grace", respArray()},
+		{"Alice", "Bob", respArray()}, // friends of each other, but nobody in common
+		{"Alice", "Nobody", respArray()},
+	}
+	for _, c := range cases {
+		if got := runCommand(t, "G.MUTUAL", c.a, c.b); got != c.want {
+			t.Errorf("G.MUTUAL %s %s = %q, want %q", c.a, c.b, got, c.want)
+		}
+	}
+
+	runCommand(t, "G.ADDEDGE", "Alice", "David")
+	if got, want := runCommand(t, "G.MUTUAL", "Alice", "Bob"), respArray("David"); got != want {
+		t.Errorf("G.MUTUAL Alice Bob in a triangle = %q, want %q", got, want)
+	}
+}
+
+func TestMaxDegree(t *testing.T) {
+	resetGraph(t)
+	if got := runCommand(t, "G.SETMAXDEGREE", "Hub", "2"); got != "+OK\r\n" {
+		t.Fatalf("G.SETMAXDEGREE Hub 2 = %q", got)
+	}
+	runCommand(t, "G.ADDEDGE", "Hub", "A")
+	runCommand(t, "G.ADDEDGE", "B", "Hub")
+	if got := runCommand(t, "G.ADDEDGE", "Hub", "C"); got != "-ERR degree limit exceeded\r\n" {
+		t.Errorf("third edge from Hub = %q, want the degree limit error", got)
+	}
+	if got := runCommand(t, "G.ADDEDGE", "D", "Hub"); got != "-ERR degree limit exceeded\r\n" {
+		t.Errorf("third edge into Hub = %q, want the degree limit error", got)
+	}
+	if got := runCommand(t, "G.DEGREE", "Hub"); got != ":2\r\n" {
+		t.Errorf("G.DEGREE Hub = %q after rejected edges, want :2", got)
+	}
+	if got := runCommand(t, "G.GETFRIENDS", "C"); got != "*0\r\n" {
+		t.Errorf("a rejected edge left C with friends %q", got)
+	}
+
+	// Changing the weight of an existing edge isn't a new connection
+	if got := runCommand(t, "G.ADDEDGE", "Hub", "A", "5"); got != "+OK\r\n" {
+		t.Errorf("reweighting an existing edge = %q, want +OK", got)
+	}
+	// Nodes without a limit stay unbounded
+	for _, n := range []string{"P", "Q", "R", "S"} {
+		if got := runCommand(t, "G.ADDEDGE", "Free", n); got != "+OK\r\n" {
+			t.Errorf("G.ADDEDGE Free %s = %q, want +OK", n, got)
+		}
+	}
+	if got := runCommand(t, "G.SETMAXDEGREE", "Hub", "-1"); got[0] != '-' {
+		t.Errorf("negative limit = %q, want an error", got)
+	}
+}
+
+func TestFriendsAreSorted(t *testing.T) {
+	resetGraph(t)
+	for _, n := range []string{"Zoe", "Mike", "Aaron"} {
+		runCommand(t, "G.ADDEDGE", "Alice", n)
+	}
+	want := respArray("Aaron", "Bob", "Charlie", "Mike", "Zoe")
+	for i := 0; i < 20; i++ {
+		if got := runCommand(t, "G.GETFRIENDS", "Alice"); got != want {
+			t.Fatalf("G.GETFRIENDS Alice call %d = %q, want %q", i, got, want)
+		}
+	}
+	if got, want := runCommand(t, "G.FOF", "Alice"), respArray("David", "Eve"); got != want {
+		t.Errorf("G.FOF Alice = %q, want %q", got, want)
+	}
+}
+
+func TestGetFriendsPattern(t *testing.T) {
+	resetGraph(t)
+	runCommand(t, "G.ADDEDGE", "Alice", "Ivan")
+	runCommand(t, "G.ADDEDGE", "Alice", "Steve")
+
+	cases := []struct {
+		pattern, want string
+	}{
+		{"*e*", respArray("Charlie", "Steve")},
+		{"?ob", respArray("Bob")},
+		{"*", respArray("Bob", "Charlie", "Ivan", "Steve")},
+		{"", respArray("Bob", "Charlie", "Ivan", "Steve")},
+		{"C*", respArray("Charlie")},
+		{"*x*", "*0\r\n"},
+		{"Bo", "*0\r\n"}, // Patterns match the whole name
+	}
+	for _, c := range cases {
+		if got := runCommand(t, "G.GETFRIENDS", "Alice", c.pattern); got != c.want {
+			t.Errorf("G.GETFRIENDS Alice %q = %q, want %q", c.pattern, got, c.want)
+		}
+	}
+}
+
+func TestVerifyAndRepair(t *testing.T) {
+	resetGraph(t)
+	if got := runCommand(t, "G.VERIFY"); got != "*0\r\n" {
+		t.Fatalf("seeded graph: G.VERIFY = %q, want no asymmetric edges", got)
+	}
+
+	// Corrupt the store: drop two reverse edges and leave a dangling one
+	graphMutex.Lock()
+	delete(GraphStore["Bob"], "Alice")
+	delete(GraphStore["Grace"], "Eve")
+	GraphStore["Eve"]["Zed"] = 4
+	graphMutex.Unlock()
+
+	want := respArray("Alice->Bob", "Eve->Grace", "Eve->Zed")
+	if got := runCommand(t, "G.VERIFY"); got != want {
+		t.Errorf("corrupted graph: G.VERIFY = %q, want %q", got, want)
+	}
+	if got := runCommand(t, "G.VERIFY", "REPAIR"); got != ":3\r\n" {
+		t.Errorf("G.VERIFY REPAIR = %q, want :3", got)
+	}
+	if got := runCommand(t, "G.VERIFY"); got != "*0\r\n" {
+		t.Errorf("after REPAIR: G.VERIFY = %q, want no asymmetric edges", got)
+	}
+	graphMutex.RLock()
+	weight, ok := GraphStore["Zed"]["Eve"]
+	graphMutex.RUnlock()
+	if !ok || weight != 4 {
+		t.Errorf("repaired Zed -> Eve has weight %d (present %v), want 4", weight, ok)
+	}
+	if got, want := runCommand(t, "G.GETFRIENDS", "Bob"), respArray("Alice", "David"); got != want {
+		t.Errorf("Bob's friends after REPAIR = %q, want %q", got, want)
+	}
+	if got := runCommand(t, "G.VERIFY", "FIX"); got[0] != '-' {
+		t.Errorf("G.VERIFY FIX = %q, want an error", got)
+	}
+}
+
+func TestSubgraphListsInternalEdges(t *testing.T) {
+	resetGraph(t)
+	runCommand(t, "G.ADDEDGE", "Alice", "Eve")
+	runCommand(t, "G.ADDEDGE", "Grace", "Bob", "DIRECTED")
+	cases := []struct {
+		nodes []string
+		want  string
+	}{
+		// Charlie-Eve and Eve-Grace leave the set, so they aren't listed
+		{[]string{"Alice", "Bob", "Charlie"}, respArray("Alice-Bob", "Alice-Charlie")},
+		{[]string{"Eve", "Charlie", "Alice"}, respArray("Alice-Charlie", "Alice-Eve", "Charlie-Eve")},
+		// A one-way edge is listed once, whichever way it points
+		{[]string{"Bob", "Grace"}, respArray("Bob-Grace")},
+		{[]string{"Alice", "Alice", "Bob"}, respArray("Alice-Bob")},
+		{[]string{"Frank", "Grace", "Nobody"}, respArray()},
+		{[]string{"Alice"}, respArray()},
+	}
+	for _, c := range cases {
+		if got := runCommand(t, append([]string{"G.SUBGRAPH"}, c.nodes...)...); got != c.want {
+			t.Errorf("G.SUBGRAPH %v = %q, want %q", c.nodes, got, c.want)
+		}
+	}
+	if got := runCommand(t, "G.SUBGRAPH"); got[0] != '-' {
+		t.Errorf("G.SUBGRAPH with no nodes = %q, want an error", got)
+	}
+}
+
+func TestDirectedDeleteKeepsTargetNodes(t *testing.T) {
+	resetGraph(t)
+	graphMutex.Lock()
+	GraphStore = make(map[string]map[string]int)
+	graphMutex.Unlock()
+	runCommand(t, "G.ADDEDGE", "c", "a", "DIRECTED")
+	runCommand(t, "G.ADDEDGE", "a", "b", "DIRECTED")
+	if got := runCommand(t, "G.DELEDGE", "a", "b"); got != ":1\r\n" {
+		t.Fatalf("G.DELEDGE a b = %q, want :1", got)
+	}
+
+	// c -> a is still there, so a is still a node; b has no edges left
+	if got, want := runCommand(t, "G.BFS", "a"), respArray("a"); got != want {
+		t.Errorf("G.BFS a = %q, want %q", got, want)
+	}
+	if got, want := runCommand(t, "G.BFS", "c"), respArray("c", "a"); got != want {
+		t.Errorf("G.BFS c = %q, want %q", got, want)
+	}
+	order, scores := pageRanks(t)
+	if len(order) != 2 {
+		t.Errorf("G.PAGERANK ranked %v, want a and c", order)
+	}
+	if sum := scores["a"] + scores["c"]; math.Abs(sum-1) > 1e-4 {
+		t.Errorf("scores %v sum to %f, want 1", scores, sum)
+	}
+}
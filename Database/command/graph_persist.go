@@ -0,0 +1,99 @@
+package command
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+)
+
+// graphFilePath is where G.SAVE writes the graph and where startup looks for it.
+const graphFilePath = "./graph.json"
+
+// SaveGraph writes the adjacency list (including edge weights and nodes with
+// no outgoing edges) to path as JSON.
+func SaveGraph(path string) error {
+	graphMutex.RLock()
+	data, err := json.Marshal(GraphStore)
+	graphMutex.RUnlock()
+	if err != nil {
+		return fmt.Errorf("marshalling graph: %w", err)
+	}
+
+	// Write to a temp file first so a failed save never truncates the old one
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("writing graph file: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("replacing graph file: %w", err)
+	}
+	return nil
+}
+
+// LoadGraph replaces GraphStore with the graph saved at path. The current
+// graph is left untouched if the file is missing or malformed.
+func LoadGraph(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var loaded map[string]map[string]int
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		return fmt.Errorf("decoding graph file: %w", err)
+	}
+	if loaded == nil {
+		loaded = make(map[string]map[string]int)
+	}
+
+	// Make sure every node has a neighbour map, including edge targets
+	for node, neighbours := range loaded {
+		if neighbours == nil {
+			loaded[node] = make(map[string]int)
+		}
+		for next := range neighbours {
+			if _, ok := loaded[next]; !ok {
+				loaded[next] = make(map[string]int)
+			}
+		}
+	}
+
+	graphMutex.Lock()
+	GraphStore = loaded
+	graphMutex.Unlock()
+	return nil
+}
+
+func init() {
+	Register(CommandInfo{Name: "G.SAVE", Usage: "G.SAVE", Summary: "Write the graph to " + graphFilePath}, HandlerFunc(HandleGraphSave))
+	Register(CommandInfo{Name: "G.LOAD", Usage: "G.LOAD", Summary: "Replace the graph with the contents of " + graphFilePath}, HandlerFunc(HandleGraphLoad))
+}
+
+// HandleGraphSave processes G.SAVE
+func HandleGraphSave(argv []string, c net.Conn) {
+	if err := SaveGraph(graphFilePath); err != nil {
+		fmt.Println("Error saving graph:", err)
+		c.Write([]byte("-ERR " + err.Error() + "\r\n"))
+		return
+	}
+	fmt.Println("Graph saved to", graphFilePath)
+	c.Write([]byte("+OK\r\n"))
+}
+
+// HandleGraphLoad processes G.LOAD
+func HandleGraphLoad(argv []string, c net.Conn) {
+	err := LoadGraph(graphFilePath)
+	if errors.Is(err, os.ErrNotExist) {
+		c.Write([]byte("-ERR graph file not found\r\n"))
+		return
+	}
+	if err != nil {
+		fmt.Println("Error loading graph:", err)
+		c.Write([]byte("-ERR " + err.Error() + "\r\n"))
+		return
+	}
+	fmt.Println("Graph loaded from", graphFilePath)
+	c.Write([]byte("+OK\r\n"))
+}
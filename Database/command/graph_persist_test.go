@@ -0,0 +1,65 @@
+package command
+
+import (
+	"reflect"
+	"testing"
+)
+
+// snapshotGraph copies GraphStore, so it can be compared after the graph changes.
+func snapshotGraph() map[string]map[string]int {
+	graphMutex.RLock()
+	defer graphMutex.RUnlock()
+	copied := make(map[string]map[string]int, len(GraphStore))
+	for node, neighbours := range GraphStore {
+		copied[node] = make(map[string]int, len(neighbours))
+		for next, weight := range neighbours {
+			copied[node][next] = weight
+		}
+	}
+	return copied
+}
+
+func TestSaveAndLoadGraph(t *testing.T) {
+	resetGraph(t)
+	runCommand(t, "G.ADDEDGE", "Grace", "Heidi", "4")
+	runCommand(t, "G.ADDEDGE", "Heidi", "Ivan", "DIRECTED")
+	want := snapshotGraph()
+
+	if reply := runCommand(t, "G.SAVE"); reply != "+OK\r\n" {
+		t.Fatalf("G.SAVE replied %q", reply)
+	}
+	graphMutex.Lock()
+	GraphStore = make(map[string]map[string]int)
+	graphMutex.Unlock()
+
+	if reply := runCommand(t, "G.LOAD"); reply != "+OK\r\n" {
+		t.Fatalf("G.LOAD replied %q", reply)
+	}
+	if got := snapshotGraph(); !reflect.DeepEqual(got, want) {
+		t.Errorf("reloaded graph %v, want %v", got, want)
+	}
+
+	// Startup prefers the saved graph over the seed data
+	graphMutex.Lock()
+	GraphStore = make(map[string]map[string]int)
+	graphMutex.Unlock()
+	InitGraphDB()
+	if got := snapshotGraph(); !reflect.DeepEqual(got, want) {
+		t.Errorf("graph after startup %v, want the saved %v", got, want)
+	}
+}
+
+func TestLoadGraphWithoutAFile(t *testing.T) {
+	resetGraph(t) // in an empty directory, so this is the seed data
+	want := snapshotGraph()
+
+	if reply := runCommand(t, "G.LOAD"); reply != "-ERR graph file not found\r\n" {
+		t.Errorf("G.LOAD without a file replied %q", reply)
+	}
+	if got := snapshotGraph(); !reflect.DeepEqual(got, want) {
+		t.Error("a failed G.LOAD changed the graph")
+	}
+	if _, ok := want["Alice"]["Bob"]; !ok {
+		t.Error("startup without a saved graph didn't seed it")
+	}
+}
@@ -1,59 +1,205 @@
 package command
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
 	"sync"
 )
 
 // GraphStore will represent our graph as an adjacency list.
 // The key is the node (e.g., "Alice")
-// The value is a "set" (map[string]bool) of connected nodes (e.g., {"Bob": true, "Charlie": true})
-var GraphStore map[string]map[string]bool
+// The value maps each connected node to the edge weight (e.g., {"Bob": 1, "Charlie": 5})
+// Edges are stored as outgoing links: an undirected edge is simply stored in both directions.
+var GraphStore map[string]map[string]int
 var graphMutex sync.RWMutex
 
-// InitGraphDB initializes the graph database with hardcoded data.
+// degreeLimits holds the optional maximum degree (outgoing connections) per
+// node, set with G.SETMAXDEGREE. Nodes without an entry are unbounded.
+// Guarded by graphMutex, like GraphStore.
+var degreeLimits = make(map[string]int)
+
+// defaultEdgeWeight is used when G.ADDEDGE is given no explicit weight.
+const defaultEdgeWeight = 1
+
+// InitGraphDB loads the graph saved by G.SAVE if there is one, otherwise it
+// initializes the graph database with hardcoded data.
 func InitGraphDB() {
 	fmt.Println("Initializing Graph Database...")
+	err := LoadGraph(graphFilePath)
+	if err == nil {
+		fmt.Println("Graph loaded from", graphFilePath)
+		return
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		fmt.Println("WARNING: could not load saved graph, using seed data:", err)
+	}
+
 	graphMutex.Lock()
 	defer graphMutex.Unlock()
 
-	GraphStore = make(map[string]map[string]bool)
+	GraphStore = make(map[string]map[string]int)
 
 	// Hardcode some data
 	// We'll use a helper to make it undirected (A -> B and B -> A)
-	addEdge("Alice", "Bob")
-	addEdge("Alice", "Charlie")
-	addEdge("Bob", "David")
-	addEdge("Charlie", "Eve")
-	addEdge("David", "Frank")
-	addEdge("Eve", "Grace")
+	addEdge("Alice", "Bob", defaultEdgeWeight)
+	addEdge("Alice", "Charlie", defaultEdgeWeight)
+	addEdge("Bob", "David", defaultEdgeWeight)
+	addEdge("Charlie", "Eve", defaultEdgeWeight)
+	addEdge("David", "Frank", defaultEdgeWeight)
+	addEdge("Eve", "Grace", defaultEdgeWeight)
 }
 
 // addEdge is an internal helper to create an undirected edge
-// NOTE: This function is not thread-safe, it's only for use in InitGraphDB!
-func addEdge(node1, node2 string) {
-	// Add edge from node1 to node2
-	if _, ok := GraphStore[node1]; !ok {
-		GraphStore[node1] = make(map[string]bool)
+// NOTE: This function is not thread-safe, callers must hold graphMutex!
+func addEdge(node1, node2 string, weight int) {
+	addDirectedEdge(node1, node2, weight)
+	addDirectedEdge(node2, node1, weight)
+}
+
+// addDirectedEdge records a single edge from -> to. The target is registered
+// as a node too, so it shows up in the graph even without outgoing edges.
+// NOTE: This function is not thread-safe, callers must hold graphMutex!
+func addDirectedEdge(from, to string, weight int) {
+	if _, ok := GraphStore[from]; !ok {
+		GraphStore[from] = make(map[string]int)
+	}
+	GraphStore[from][to] = weight
+
+	if _, ok := GraphStore[to]; !ok {
+		GraphStore[to] = make(map[string]int)
 	}
-	GraphStore[node1][node2] = true
+}
 
-	// Add edge from node2 to node1
-	if _, ok := GraphStore[node2]; !ok {
-		GraphStore[node2] = make(map[string]bool)
+// exceedsDegreeLimit reports whether adding the edge from -> to would give
+// from more connections than its limit allows. Re-adding an existing edge
+// (e.g. to change its weight) doesn't add a connection.
+// NOTE: This function is not thread-safe, callers must hold graphMutex!
+func exceedsDegreeLimit(from, to string) bool {
+	limit, limited := degreeLimits[from]
+	if !limited {
+		return false
+	}
+	if _, exists := GraphStore[from][to]; exists {
+		return false
 	}
-	GraphStore[node2][node1] = true
+	return len(GraphStore[from])+1 > limit
 }
 
-// Helper to convert a set (map[string]bool) to a RESP Array string
-func formatSetAsRespArray(set map[string]bool) string {
-	if len(set) == 0 {
-		return "*0\r\n" // Empty array
+// removeDirectedEdge deletes the edge from -> to and reports whether it existed.
+// Either end left with no edges at all is dropped so empty maps don't pile
+// up; a node that other nodes still point to stays, as addDirectedEdge
+// registers it.
+// NOTE: This function is not thread-safe, callers must hold graphMutex!
+func removeDirectedEdge(from, to string) bool {
+	neighbours, ok := GraphStore[from]
+	if !ok {
+		return false
+	}
+	if _, hasEdge := neighbours[to]; !hasEdge {
+		return false
 	}
+	delete(neighbours, to)
+	dropIfIsolated(from)
+	dropIfIsolated(to)
+	return true
+}
 
-	resp := fmt.Sprintf("*%d\r\n", len(set))
-	for key := range set {
-		resp += fmt.Sprintf("$%d\r\n%s\r\n", len(key), key)
+// dropIfIsolated removes node from the graph if it has no outgoing edges
+// and no other node has an edge to it.
+// NOTE: This function is not thread-safe, callers must hold graphMutex!
+func dropIfIsolated(node string) {
+	if len(GraphStore[node]) > 0 {
+		return
+	}
+	for _, neighbours := range GraphStore {
+		if _, ok := neighbours[node]; ok {
+			return
+		}
+	}
+	delete(GraphStore, node)
+}
+
+// asymmetricEdges lists every edge from -> to whose reverse to -> from is
+// missing, as [from, to] pairs sorted by from then to. For a graph built
+// only from undirected edges it is always empty.
+// NOTE: This function is not thread-safe, callers must hold graphMutex!
+func asymmetricEdges() [][2]string {
+	var edges [][2]string
+	for _, from := range sortedKeys(GraphStore) {
+		for _, to := range sortedNeighbours(from) {
+			if _, ok := GraphStore[to][from]; !ok {
+				edges = append(edges, [2]string{from, to})
+			}
+		}
+	}
+	return edges
+}
+
+// Helper to convert a set (map keys, e.g. map[string]bool or an adjacency
+// map of weights) to a RESP Array string. Keys are sorted so replies are
+// stable across calls despite Go's random map order.
+func formatSetAsRespArray[V any](set map[string]V) string {
+	return formatListAsRespArray(sortedKeys(set))
+}
+
+// formatListAsRespArray converts an ordered list to a RESP Array string,
+// preserving its order.
+func formatListAsRespArray(items []string) string {
+	resp := fmt.Sprintf("*%d\r\n", len(items))
+	for _, item := range items {
+		resp += fmt.Sprintf("$%d\r\n%s\r\n", len(item), item)
 	}
 	return resp
-}
\ No newline at end of file
+}
+
+// sortedKeys returns a map's keys in sorted order.
+func sortedKeys[V any](set map[string]V) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// compileGlobPattern translates a glob pattern into an anchored regex: '*'
+// matches any run of characters, '?' exactly one, and a backslash makes the
+// next character literal. Matching is case-sensitive, like node names.
+func compileGlobPattern(pattern string) *regexp.Regexp {
+	var sb strings.Builder
+	sb.WriteString("(?s)^")
+
+	escaped := false
+	for _, ch := range pattern {
+		switch {
+		case escaped:
+			sb.WriteString(regexp.QuoteMeta(string(ch)))
+			escaped = false
+		case ch == '\\':
+			escaped = true
+		case ch == '*':
+			sb.WriteString(".*")
+		case ch == '?':
+			sb.WriteString(".")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+	if escaped {
+		sb.WriteString(regexp.QuoteMeta("\\")) // A trailing backslash is literal
+	}
+
+	sb.WriteString("$")
+	return regexp.MustCompile(sb.String())
+}
+
+// sortedNeighbours returns a node's outgoing neighbours in a stable order, so
+// traversals give the same answer every time despite Go's random map order.
+// NOTE: This function is not thread-safe, callers must hold graphMutex!
+func sortedNeighbours(node string) []string {
+	return sortedKeys(GraphStore[node])
+}
@@ -0,0 +1,164 @@
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"testing"
+)
+
+// recordConn stands in for a client connection in tests, keeping every
+// reply written to it.
+type recordConn struct {
+	net.Conn // nil; only Write and RemoteAddr are ever called
+	out      bytes.Buffer
+}
+
+func (c *recordConn) Write(b []byte) (int, error) { return c.out.Write(b) }
+
+func (c *recordConn) RemoteAddr() net.Addr { return nil }
+
+// resetSQL gives a test a fresh cache and the seeded tables. The miss
+// penalty and the background refresh are off so tests run quickly.
+func resetSQL(t testing.TB) {
+	t.Helper()
+	cfg := DefaultSQLCacheConfig()
+	cfg.MissPenalty = 0
+	cfg.RefreshInterval = 0
+	InitSQLCache(cfg)
+	InitBackingDB()
+}
+
+// runSQL sends one SQL command through HandleSQL, as a client would, and
+// returns the raw reply.
+func runSQL(t *testing.T, c *recordConn, query string) string {
+	t.Helper()
+	c.out.Reset()
+	HandleSQL([]string{"SQL", query}, c)
+	return c.out.String()
+}
+
+// mustSQL is runSQL on a fresh connection that fails the test on an error
+// reply.
+func mustSQL(t *testing.T, query string) string {
+	t.Helper()
+	reply := runSQL(t, &recordConn{}, query)
+	if strings.HasPrefix(reply, "-") {
+		t.Fatalf("%s: %s", query, strings.TrimSpace(reply))
+	}
+	return reply
+}
+
+// parseQuery parses a SELECT and resolves its names against the tables,
+// failing the test on any error.
+func parseQuery(t *testing.T, query string) *QueryAST {
+	t.Helper()
+	ast, err := ParseSQL(query)
+	if err != nil {
+		t.Fatalf("parse %q: %v", query, err)
+	}
+	if err := resolveQueryNames(ast); err != nil {
+		t.Fatalf("resolve %q: %v", query, err)
+	}
+	return ast
+}
+
+// queryRows parses and runs a SELECT straight against the tables,
+// bypassing the cache, and fails the test on any error.
+func queryRows(t *testing.T, query string) *Table {
+	t.Helper()
+	ast := parseQuery(t, query)
+	results, err := executeOnBackingStore(ast)
+	if err != nil {
+		t.Fatalf("execute %q: %v", query, err)
+	}
+	return results
+}
+
+// column returns one column of a result, row by row.
+func column(results *Table, col string) []interface{} {
+	vals := make([]interface{}, len(results.Rows))
+	for i, row := range results.Rows {
+		vals[i] = row[col]
+	}
+	return vals
+}
+
+// cacheOutcome runs a SELECT through HandleSQL and reports how the cache
+// answered it: "direct", "semantic" or "miss". It also checks the reply
+// against the same query run with NOCACHE, so a hit can't serve wrong rows.
+func cacheOutcome(t *testing.T, query string) string {
+	t.Helper()
+	direct, semantic := SQLCache.directHits.Load(), SQLCache.semanticHits.Load()
+	reply := mustSQL(t, query)
+	if want := mustSQL(t, "NOCACHE "+query); reply != want {
+		t.Errorf("%s: cached reply\n%s\ndiffers from the tables'\n%s", query, reply, want)
+	}
+	switch {
+	case SQLCache.directHits.Load() > direct:
+		return "direct"
+	case SQLCache.semanticHits.Load() > semantic:
+		return "semantic"
+	}
+	return "miss"
+}
+
+// resetGraph gives a test the seeded graph and no degree limits. The test
+// runs in a temporary directory, so G.SAVE and G.LOAD never touch a real
+// graph.json.
+func resetGraph(t *testing.T) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	InitGraphDB()
+	graphMutex.Lock()
+	degreeLimits = make(map[string]int)
+	graphMutex.Unlock()
+}
+
+// runCommand dispatches argv through the command registry, as the server
+// does, and returns the raw reply.
+func runCommand(t *testing.T, argv ...string) string {
+	t.Helper()
+	handler, ok := LookupHandler(argv[0])
+	if !ok {
+		t.Fatalf("no handler registered for %s", argv[0])
+	}
+	c := &recordConn{}
+	handler.Handle(argv, c)
+	return c.out.String()
+}
+
+// respArray is the RESP array reply listing items in order.
+func respArray(items ...string) string {
+	return formatListAsRespArray(items)
+}
+
+// bulkString is the RESP bulk string reply carrying s.
+func bulkString(s string) string {
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(s), s)
+}
+
+// bulkBody returns the payload of a RESP bulk string reply, failing the test
+// if the reply isn't one or its length prefix is wrong.
+func bulkBody(t *testing.T, reply string) string {
+	t.Helper()
+	var n int
+	if _, err := fmt.Sscanf(reply, "$%d\r\n", &n); err != nil {
+		t.Fatalf("reply %q isn't a bulk string", reply)
+	}
+	start := strings.Index(reply, "\r\n") + 2
+	if len(reply) != start+n+2 || !strings.HasSuffix(reply, "\r\n") {
+		t.Fatalf("bulk string length %d doesn't match its payload in %q", n, reply)
+	}
+	return reply[start : start+n]
+}
@@ -0,0 +1,121 @@
+package command
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// CommandHandler runs one command. argv is the parsed request: the command
+// name followed by its arguments.
+type CommandHandler interface {
+	Handle(argv []string, c net.Conn)
+}
+
+// HandlerFunc lets an ordinary function be used as a CommandHandler.
+type HandlerFunc func(argv []string, c net.Conn)
+
+// Handle calls f(argv, c).
+func (f HandlerFunc) Handle(argv []string, c net.Conn) {
+	f(argv, c)
+}
+
+// CommandInfo describes a registered command for HELP.
+type CommandInfo struct {
+	Name    string   // Upper-case command name, e.g. "G.ADDEDGE"
+	Aliases []string // Other names that run the same handler
+	Usage   string   // e.g. "G.ADDEDGE <a> <b> [weight] [DIRECTED]"
+	Summary string   // One-line description
+}
+
+type registeredCommand struct {
+	info    CommandInfo
+	handler CommandHandler
+}
+
+var registryMutex sync.RWMutex
+var registry = make(map[string]*registeredCommand) // Keyed by name and by each alias
+
+// Register adds a command under its name and aliases. Handlers register
+// themselves from init functions next to their implementation, so a new
+// command doesn't need an entry in the server's dispatch code. Registering
+// a name twice panics, since it is always a programming error.
+func Register(info CommandInfo, handler CommandHandler) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+
+	entry := &registeredCommand{info: info, handler: handler}
+	for _, name := range append([]string{info.Name}, info.Aliases...) {
+		name = strings.ToUpper(name)
+		if _, exists := registry[name]; exists {
+			panic(fmt.Sprintf("command: multiple registrations for %s", name))
+		}
+		registry[name] = entry
+	}
+}
+
+// LookupHandler returns the handler registered under name, in any case.
+func LookupHandler(name string) (CommandHandler, bool) {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	entry, ok := registry[strings.ToUpper(name)]
+	if !ok {
+		return nil, false
+	}
+	return entry.handler, true
+}
+
+// RegisteredCommands lists every registered command once, sorted by name.
+func RegisteredCommands() []CommandInfo {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	var infos []CommandInfo
+	for name, entry := range registry {
+		if name == entry.info.Name {
+			infos = append(infos, entry.info)
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// ParseArgs splits a request into argv. A RESP array is read using its
+// length prefixes, so arguments may contain spaces or line breaks; anything
+// else is treated as an inline command and split on whitespace.
+func ParseArgs(input string) []string {
+	if !strings.HasPrefix(input, "*") {
+		return strings.Fields(input)
+	}
+
+	header, rest, _ := strings.Cut(input, "\r\n")
+	count, err := strconv.Atoi(header[1:])
+	if err != nil || count < 0 {
+		return strings.Fields(input)
+	}
+
+	argv := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		prefix, body, found := strings.Cut(rest, "\r\n")
+		if !found && prefix == "" {
+			break // Fewer elements than announced
+		}
+		if !strings.HasPrefix(prefix, "$") {
+			break
+		}
+		n, err := strconv.Atoi(prefix[1:])
+		if err != nil || n < 0 {
+			break
+		}
+		if n > len(body) {
+			n = len(body) // The server trims trailing whitespace off the last argument
+		}
+		argv = append(argv, body[:n])
+		rest = strings.TrimPrefix(body[n:], "\r\n")
+	}
+	return argv
+}
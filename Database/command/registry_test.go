@@ -0,0 +1,70 @@
+package command
+
+import (
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeCalls records the argv of every TEST.FAKE call.
+var fakeCalls [][]string
+
+func init() {
+	Register(CommandInfo{Name: "TEST.FAKE", Aliases: []string{"TFAKE"}, Usage: "TEST.FAKE [args...]", Summary: "Record the call"},
+		HandlerFunc(func(argv []string, c net.Conn) {
+			fakeCalls = append(fakeCalls, argv)
+			c.Write([]byte("+FAKE\r\n"))
+		}))
+}
+
+func TestRegistryDispatchesToRegisteredHandlers(t *testing.T) {
+	fakeCalls = nil
+	if got := runCommand(t, "test.fake", "a", "b c"); got != "+FAKE\r\n" {
+		t.Errorf("TEST.FAKE replied %q", got)
+	}
+	if got := runCommand(t, "TFAKE"); got != "+FAKE\r\n" {
+		t.Errorf("the TFAKE alias replied %q", got)
+	}
+	if want := [][]string{{"test.fake", "a", "b c"}, {"TFAKE"}}; !reflect.DeepEqual(fakeCalls, want) {
+		t.Errorf("handler saw %q, want %q", fakeCalls, want)
+	}
+	if _, ok := LookupHandler("TEST.MISSING"); ok {
+		t.Error("LookupHandler found an unregistered command")
+	}
+
+	listed := 0
+	for _, info := range RegisteredCommands() {
+		if info.Name == "TEST.FAKE" {
+			listed++
+		}
+	}
+	if listed != 1 {
+		t.Errorf("TEST.FAKE is listed %d times, want once despite its alias", listed)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("registering TFAKE twice didn't panic")
+		}
+	}()
+	Register(CommandInfo{Name: "tfake"}, HandlerFunc(func([]string, net.Conn) {}))
+}
+
+func TestParseArgs(t *testing.T) {
+	cases := []struct {
+		input string
+		want  []string
+	}{
+		{"*3\r\n$3\r\nSQL\r\n$5\r\na b c\r\n$0\r\n\r\n", []string{"SQL", "a b c", ""}},
+		{"*2\r\n$4\r\nECHO\r\n$7\r\nline\r\n2\r\n", []string{"ECHO", "line\r\n2"}},
+		{"G.FOF  Alice", []string{"G.FOF", "Alice"}},
+		// The server trims trailing whitespace, shortening the last argument
+		{strings.TrimSpace("*2\r\n$4\r\nECHO\r\n$6\r\nhello \r\n"), []string{"ECHO", "hello"}},
+	}
+	for _, c := range cases {
+		if got := ParseArgs(c.input); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("ParseArgs(%q) = %q, want %q", c.input, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AggregateExpr is an aggregate call from the select list, e.g. COUNT(*) or SUM(cpu_load).
+type AggregateExpr struct {
+	Func     string // COUNT, SUM, AVG, MIN or MAX
+	Column   string // "*" only for COUNT(*)
+	Distinct bool   // COUNT(DISTINCT col): count each value once
+}
+
+// String returns the name the aggregate is reported under, e.g. "COUNT(*)"
+// or "COUNT(DISTINCT server_name)".
+func (ae *AggregateExpr) String() string {
+	if ae.Distinct {
+		return fmt.Sprintf("%s(DISTINCT %s)", ae.Func, ae.Column)
+	}
+	return fmt.Sprintf("%s(%s)", ae.Func, ae.Column)
+}
+
+func isAggregateFunc(name string) bool {
+	switch name {
+	case "COUNT", "SUM", "AVG", "MIN", "MAX":
+		return true
+	}
+	return false
+}
+
+// groupRows buckets rows by the query's GROUP BY columns and computes every
+// aggregate per bucket, including those only HAVING uses. Without GROUP BY
+// all rows form a single group. Rows missing a grouping column are bucketed
+// together under a nil value. Groups are returned in the order their first
+// row was seen.
+func groupRows(rows []Row, query *QueryAST) []Row {
+	var order []string
+	groups := make(map[string][]Row)
+
+	for _, row := range rows {
+		key := groupKey(row, query.GroupBy)
+		if _, seen := groups[key]; !seen {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], row)
+	}
+
+	// An aggregate over zero rows still produces one row (e.g. COUNT(*) = 0)
+	if len(query.GroupBy) == 0 && len(order) == 0 {
+		order = append(order, "")
+		groups[""] = nil
+	}
+
+	result := make([]Row, 0, len(order))
+	for _, key := range order {
+		members := groups[key]
+		out := make(Row)
+		if len(members) > 0 {
+			for _, col := range query.GroupBy {
+				out[col] = members[0][col]
+			}
+		}
+		for _, agg := range query.Aggregates {
+			out[agg.String()] = computeAggregate(agg, members)
+		}
+		for _, agg := range query.HavingAggs {
+			if _, done := out[agg.String()]; !done {
+				out[agg.String()] = computeAggregate(agg, members)
+			}
+		}
+		result = append(result, out)
+	}
+	return result
+}
+
+// groupKey builds a map key from the grouping column values of a row.
+func groupKey(row Row, cols []string) string {
+	parts := make([]string, len(cols))
+	for i, col := range cols {
+		val, ok := row[col]
+		if !ok || val == nil {
+			parts[i] = "\x00NULL" // can't collide with a real "<nil>" string value
+			continue
+		}
+		parts[i] = fmt.Sprintf("%T:%v", val, val)
+	}
+	return strings.Join(parts, "\x1f")
+}
+
+// computeAggregate evaluates one aggregate over the rows of a group.
+// COUNT ignores NULLs and, with DISTINCT, repeated values; 42 and 42.0 are
+// the same value, as they are for "=".
+// SUM and AVG add up the numeric values and are NULL without any; SUM is an
+// integer unless a value has a fractional part. MIN and MAX use
+// compareValues.
+func computeAggregate(agg *AggregateExpr, rows []Row) interface{} {
+	switch agg.Func {
+	case "COUNT":
+		if agg.Column == "*" {
+			return len(rows)
+		}
+		count := 0
+		seen := make(map[string]bool)
+		for _, row := range rows {
+			val, ok := row[agg.Column]
+			if !ok || val == nil {
+				continue
+			}
+			if agg.Distinct {
+				key := literalKey(fmt.Sprintf("%v", val))
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+			}
+			count++
+		}
+		return count
+
+	case "SUM", "AVG":
+		intSum, floatSum, n := 0, 0.0, 0
+		sawFloat := false
+		for _, row := range rows {
+			switch val := row[agg.Column].(type) {
+			case int:
+				intSum += val
+			case float64:
+				floatSum += val
+				sawFloat = true
+			default:
+				continue
+			}
+			n++
+		}
+		if n == 0 {
+			return nil // No values to add up: NULL, as in SQL
+		}
+		sum := float64(intSum) + floatSum
+		if agg.Func == "AVG" {
+			return sum / float64(n)
+		}
+		if !sawFloat {
+			return intSum // Whole numbers stay exact
+		}
+		return sum
+
+	case "MIN", "MAX":
+		var best interface{}
+		for _, row := range rows {
+			val, ok := row[agg.Column]
+			if !ok || val == nil {
+				continue
+			}
+			if best == nil {
+				best = val
+				continue
+			}
+			cmp := compareValues(val, best)
+			if (agg.Func == "MIN" && cmp < 0) || (agg.Func == "MAX" && cmp > 0) {
+				best = val
+			}
+		}
+		return best
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package command
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGroupByStatus(t *testing.T) {
+	resetSQL(t)
+
+	results := queryRows(t, "SELECT status, COUNT(*), MAX(cpu_load) FROM server_logs GROUP BY status")
+	if got, want := results.Columns, []string{"status", "COUNT(*)", "MAX(cpu_load)"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("columns = %v, want %v", got, want)
+	}
+	// Groups come out in the order their first row was seen
+	want := []Row{
+		{"status": "OK", "COUNT(*)": 5, "MAX(cpu_load)": 75},
+		{"status": "WARNING", "COUNT(*)": 7, "MAX(cpu_load)": 92},
+		{"status": "ERROR", "COUNT(*)": 2, "MAX(cpu_load)": 99},
+	}
+	if !reflect.DeepEqual(results.Rows, want) {
+		t.Errorf("rows = %v, want %v", results.Rows, want)
+	}
+}
+
+func TestGroupByPutsNullsInOneGroup(t *testing.T) {
+	resetSQL(t)
+	mustSQL(t, "INSERT INTO server_logs (id, server_name, cpu_load) VALUES (2001, 'web-04', 10)")
+	mustSQL(t, "INSERT INTO server_logs (id, server_name, cpu_load) VALUES (2002, 'web-05', 20)")
+
+	results := queryRows(t, "SELECT status, COUNT(*) FROM server_logs GROUP BY status")
+	if len(results.Rows) != 4 {
+		t.Fatalf("got %d groups, want OK, WARNING, ERROR and NULL", len(results.Rows))
+	}
+	last := results.Rows[3]
+	if last["status"] != nil || last["COUNT(*)"] != 2 {
+		t.Errorf("NULL group = %v, want status NULL with COUNT(*) 2", last)
+	}
+}
+
+func TestGroupByRejectsColumnsOutsideTheSelectList(t *testing.T) {
+	for _, q := range []string{
+		"SELECT COUNT(*) FROM server_logs GROUP BY status",
+		"SELECT server_name, COUNT(*) FROM server_logs GROUP BY status",
+		"SELECT * FROM server_logs GROUP BY status",
+	} {
+		if _, err := ParseSQL(q); err == nil {
+			t.Errorf("%s: parsed without error", q)
+		}
+	}
+}
+
+func TestSumAndAvgOfNoValuesAreNull(t *testing.T) {
+	resetSQL(t)
+
+	results := queryRows(t, "SELECT SUM(cpu_load), AVG(cpu_load), COUNT(*) FROM server_logs WHERE id > 100000")
+	row := results.Rows[0]
+	if row["SUM(cpu_load)"] != nil {
+		t.Errorf("SUM over no rows = %v, want NULL", row["SUM(cpu_load)"])
+	}
+	if row["AVG(cpu_load)"] != nil {
+		t.Errorf("AVG over no rows = %v, want NULL", row["AVG(cpu_load)"])
+	}
+	if row["COUNT(*)"] != 0 {
+		t.Errorf("COUNT(*) over no rows = %v, want 0", row["COUNT(*)"])
+	}
+}
+
+func TestSumAndAvgMixIntsAndFloats(t *testing.T) {
+	resetSQL(t)
+	mustSQL(t, "INSERT INTO server_logs (id, server_name, cpu_load, status) VALUES (2001, 'web-04', 80.5, 'OK')")
+
+	results := queryRows(t, "SELECT SUM(cpu_load), AVG(cpu_load), COUNT(cpu_load) FROM server_logs WHERE status = 'OK'")
+	row := results.Rows[0]
+	if row["SUM(cpu_load)"] != 265.5 {
+		t.Errorf("SUM = %v, want 265.5", row["SUM(cpu_load)"])
+	}
+	if row["AVG(cpu_load)"] != 44.25 {
+		t.Errorf("AVG = %v, want 44.25", row["AVG(cpu_load)"])
+	}
+	if row["COUNT(cpu_load)"] != 6 {
+		t.Errorf("COUNT = %v, want 6", row["COUNT(cpu_load)"])
+	}
+}
+
+func TestSumOfIntsStaysInteger(t *testing.T) {
+	resetSQL(t)
+
+	results := queryRows(t, "SELECT SUM(cpu_load) FROM server_logs WHERE status = 'OK'")
+	if got := results.Rows[0]["SUM(cpu_load)"]; got != 185 {
+		t.Errorf("SUM = %v (%T), want int 185", got, got)
+	}
+}
+
+func TestCountDistinctTreatsEqualNumbersAsOne(t *testing.T) {
+	resetSQL(t)
+	mustSQL(t, "INSERT INTO server_logs (id, server_name, cpu_load, status) VALUES (2001, 'web-04', 25.0, 'OK')")
+	mustSQL(t, "INSERT INTO server_logs (id, server_name, cpu_load, status) VALUES (2002, 'web-05', 25.5, 'OK')")
+
+	results := queryRows(t, "SELECT COUNT(DISTINCT cpu_load) FROM server_logs WHERE status = 'OK'")
+	// 25, 75, 30, 15 and 40, plus 25.5; 25.0 is the same value as 25
+	if got := results.Rows[0]["COUNT(DISTINCT cpu_load)"]; got != 6 {
+		t.Errorf("COUNT(DISTINCT cpu_load) = %v, want 6", got)
+	}
+}
+
+func TestHavingRejectsSubqueries(t *testing.T) {
+	resetSQL(t)
+
+	_, err := ParseSQL("SELECT status, COUNT(*) FROM server_logs GROUP BY status HAVING status IN (SELECT status FROM server_logs WHERE cpu_load > 95)")
+	if err == nil || !strings.Contains(err.Error(), "HAVING") {
+		t.Fatalf("ParseSQL error = %v, want subqueries in HAVING rejected", err)
+	}
+	reply := runSQL(t, &recordConn{}, "SELECT status, COUNT(*) FROM server_logs GROUP BY status HAVING status IN (SELECT status FROM server_logs)")
+	if !strings.HasPrefix(reply, "-ERR") {
+		t.Errorf("reply = %q, want an error", reply)
+	}
+}
+
+func TestHavingFiltersGroups(t *testing.T) {
+	resetSQL(t)
+	cases := []struct {
+		query string
+		want  []interface{} // Statuses of the groups kept
+	}{
+		{"SELECT status, COUNT(*) FROM server_logs GROUP BY status HAVING COUNT(*) > 3", []interface{}{"OK", "WARNING"}},
+		{"SELECT status, COUNT(*) FROM server_logs GROUP BY status HAVING COUNT(*) > 6", []interface{}{"WARNING"}},
+		{"SELECT status, COUNT(*) FROM server_logs GROUP BY status HAVING COUNT(*) > 7", nil},
+		// HAVING may use an aggregate the select list doesn't
+		{"SELECT status FROM server_logs GROUP BY status HAVING MAX(cpu_load) >= 92", []interface{}{"WARNING", "ERROR"}},
+		// WHERE filters rows before grouping, HAVING the groups after
+		{"SELECT status, COUNT(*) FROM server_logs WHERE cpu_load > 80 GROUP BY status HAVING COUNT(*) > 3", []interface{}{"WARNING"}},
+		{"SELECT status, COUNT(*) FROM server_logs GROUP BY status HAVING status = 'OK' OR COUNT(*) < 3", []interface{}{"OK", "ERROR"}},
+	}
+	for _, c := range cases {
+		got := column(queryRows(t, c.query), "status")
+		if !reflect.DeepEqual(got, c.want) && !(len(got) == 0 && len(c.want) == 0) {
+			t.Errorf("%s: got groups %v, want %v", c.query, got, c.want)
+		}
+	}
+}
+
+func TestCountDistinctServers(t *testing.T) {
+	resetSQL(t)
+	results := queryRows(t, "SELECT COUNT(DISTINCT server_name), COUNT(server_name) FROM server_logs")
+	// web-01, web-02, web-03, db-01, db-02, api-01, api-02 and cache-01
+	want := Row{"COUNT(DISTINCT server_name)": 8, "COUNT(server_name)": 14}
+	if !reflect.DeepEqual(results.Rows, []Row{want}) {
+		t.Errorf("rows = %v, want %v", results.Rows, want)
+	}
+
+	results = queryRows(t, "SELECT status, COUNT(DISTINCT server_name) FROM server_logs GROUP BY status")
+	if got, want := column(results, "COUNT(DISTINCT server_name)"), []interface{}{3, 4, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("distinct servers per status = %v, want %v", got, want)
+	}
+
+	// NULL isn't a value, so it isn't counted
+	mustSQL(t, "INSERT INTO server_logs (id, cpu_load) VALUES (2001, 10)")
+	results = queryRows(t, "SELECT COUNT(DISTINCT server_name) FROM server_logs")
+	if got := results.Rows[0]["COUNT(DISTINCT server_name)"]; got != 8 {
+		t.Errorf("COUNT(DISTINCT server_name) with a NULL = %v, want 8", got)
+	}
+}
@@ -0,0 +1,144 @@
+package command
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+func init() {
+	Register(CommandInfo{Name: "SQLBENCH", Usage: "SQLBENCH <path>", Summary: "Run a file of SQL queries and report timing and cache hit ratios"}, HandlerFunc(HandleSQLBench))
+}
+
+// benchConn stands in for the client while SQLBENCH runs its queries: the
+// replies are thrown away, only errors are counted.
+type benchConn struct {
+	net.Conn // nil; only Write and RemoteAddr are ever called
+	errors   int
+}
+
+func (c *benchConn) Write(b []byte) (int, error) {
+	if len(b) > 0 && b[0] == '-' {
+		c.errors++
+	}
+	return len(b), nil
+}
+
+func (c *benchConn) RemoteAddr() net.Addr { return nil }
+
+// benchSummary is the outcome of one SQLBENCH run. The cache counters are
+// the change in the global stats over the run, so they also include any
+// queries other clients sent meanwhile.
+type benchSummary struct {
+	statements   int
+	errors       int
+	elapsed      time.Duration
+	totalQueries uint64
+	directHits   uint64
+	semanticHits uint64
+	cacheMisses  uint64
+}
+
+// readBenchQueries reads a query file: one or more statements per line,
+// with blank lines and lines starting with "--" or "#" skipped.
+func readBenchQueries(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open query file: %w", err)
+	}
+	defer file.Close()
+
+	var statements []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "--") || strings.HasPrefix(line, "#") {
+			continue
+		}
+		statements = append(statements, splitSQLStatements(line)...)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cannot read query file: %w", err)
+	}
+	return statements, nil
+}
+
+// runBench runs each statement as HandleSQL would, through the cache, and
+// measures the run.
+func runBench(statements []string) benchSummary {
+	conn := &benchConn{}
+	defer ForgetConnection(conn) // A USE TABLE in the file only lasts the run
+	total, direct := SQLCache.totalQueries.Load(), SQLCache.directHits.Load()
+	semantic, misses := SQLCache.semanticHits.Load(), SQLCache.cacheMisses.Load()
+
+	start := time.Now()
+	for _, stmt := range statements {
+		handleSQLStatement(stmt, conn)
+	}
+
+	return benchSummary{
+		statements:   len(statements),
+		errors:       conn.errors,
+		elapsed:      time.Since(start),
+		totalQueries: SQLCache.totalQueries.Load() - total,
+		directHits:   SQLCache.directHits.Load() - direct,
+		semanticHits: SQLCache.semanticHits.Load() - semantic,
+		cacheMisses:  SQLCache.cacheMisses.Load() - misses,
+	}
+}
+
+// String renders the summary in the style of the SQLSTATS report.
+func (s benchSummary) String() string {
+	ratio := func(n uint64) float64 {
+		if s.totalQueries == 0 {
+			return 0
+		}
+		return float64(n) / float64(s.totalQueries) * 100
+	}
+	var avgMs float64
+	if s.statements > 0 {
+		avgMs = float64(s.elapsed.Microseconds()) / 1000 / float64(s.statements)
+	}
+
+	return fmt.Sprintf(
+		"--- SQLBENCH Summary ---\n"+
+			"Statements: %d (%d errors)\n"+
+			"Total Time: %.3fms | Avg: %.3fms per statement\n"+
+			"Cached Queries: %d\n"+
+			"Total Cache Hits: %d (%.2f%%)\n"+
+			"  - Direct Hits:   %d (%.2f%%)\n"+
+			"  - Semantic Hits: %d (%.2f%%)\n"+
+			"Cache Misses: %d (%.2f%%)",
+		s.statements, s.errors,
+		float64(s.elapsed.Microseconds())/1000, avgMs,
+		s.totalQueries,
+		s.directHits+s.semanticHits, ratio(s.directHits+s.semanticHits),
+		s.directHits, ratio(s.directHits),
+		s.semanticHits, ratio(s.semanticHits),
+		s.cacheMisses, ratio(s.cacheMisses),
+	)
+}
+
+// HandleSQLBench processes SQLBENCH <path>: it runs every query in the file
+// through the cache, discarding the results, and replies with the timing
+// and hit ratios of the run. This warms the cache or measures it without a
+// separate client. The cache's own stats are updated as usual.
+func HandleSQLBench(argv []string, c net.Conn) {
+	if len(argv) < 2 || argv[1] == "" {
+		c.Write([]byte("-ERR wrong number of arguments for 'sqlbench' command\r\n"))
+		return
+	}
+
+	statements, err := readBenchQueries(argv[1])
+	if err != nil {
+		c.Write([]byte(fmt.Sprintf("-ERR %s\r\n", err.Error())))
+		return
+	}
+
+	summary := runBench(statements).String()
+	queryLog.Info("sqlbench", "path", argv[1], "statements", len(statements))
+	c.Write([]byte(fmt.Sprintf("$%d\r\n%s\r\n", len(summary), summary)))
+}
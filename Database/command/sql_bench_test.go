@@ -0,0 +1,54 @@
+package command
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBenchSummaryMatchesCacheStats(t *testing.T) {
+	resetSQL(t)
+	path := filepath.Join(t.TempDir(), "queries.sql")
+	queries := "-- warm the cache\n" +
+		"SELECT * FROM users WHERE age > 40\n" +
+		"\n" +
+		"# repeats and subsets\n" +
+		"SELECT * FROM users WHERE age > 40; select * from USERS where age > 40\n" +
+		"SELECT * FROM users WHERE age > 60\n" +
+		"SELECT * FROM products\n" +
+		"SELECT * FROM nope\n"
+	if err := os.WriteFile(path, []byte(queries), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := &recordConn{}
+	HandleSQLBench([]string{"SQLBENCH", path}, c)
+	summary := strings.Split(bulkBody(t, c.out.String()), "\n")
+	stats := strings.Split(SQLCache.GetCacheStats(), "\n")
+
+	if summary[1] != "Statements: 6 (1 errors)" {
+		t.Errorf("summary has %q, want 6 statements and 1 error", summary[1])
+	}
+	if want := strings.Replace(stats[1], "Total Queries", "Cached Queries", 1); summary[3] != want {
+		t.Errorf("summary has %q, stats have %q", summary[3], stats[1])
+	}
+	// Hits, direct hits, semantic hits and misses, with their ratios
+	for i := 0; i < 4; i++ {
+		if summary[4+i] != stats[2+i] {
+			t.Errorf("summary has %q, stats have %q", summary[4+i], stats[2+i])
+		}
+	}
+	if got := SQLCache.directHits.Load(); got != 2 {
+		t.Errorf("%d direct hits, want 2", got)
+	}
+	if got := SQLCache.semanticHits.Load(); got != 1 {
+		t.Errorf("%d semantic hits, want 1", got)
+	}
+
+	c.out.Reset()
+	HandleSQLBench([]string{"SQLBENCH", filepath.Join(t.TempDir(), "missing.sql")}, c)
+	if !strings.HasPrefix(c.out.String(), "-ERR ") {
+		t.Errorf("SQLBENCH of a missing file = %q, want an error", c.out.String())
+	}
+}
@@ -0,0 +1,73 @@
+package command
+
+import (
+	"hash/fnv"
+)
+
+// Bloom filter sizing: the cache only ever holds a handful of entries, so a
+// small filter keeps the false positive rate negligible.
+const (
+	bloomBits   = 1024
+	bloomHashes = 3
+)
+
+// bloomFilter is a fixed-size Bloom filter over strings. It can say a string
+// was definitely never added; a positive answer may be a false positive.
+// Strings can't be removed, only the whole filter reset.
+type bloomFilter struct {
+	bits [bloomBits / 64]uint64
+}
+
+// positions returns the bloomHashes bit positions for s, derived from two
+// FNV hashes (Kirsch-Mitzenmacher double hashing).
+func (bf *bloomFilter) positions(s string) [bloomHashes]uint {
+	h1 := fnv.New64a()
+	h1.Write([]byte(s))
+	h2 := fnv.New64()
+	h2.Write([]byte(s))
+	a, b := h1.Sum64(), h2.Sum64()|1 // Odd step so the positions differ
+
+	var pos [bloomHashes]uint
+	for i := range pos {
+		pos[i] = uint((a + uint64(i)*b) % bloomBits)
+	}
+	return pos
+}
+
+func (bf *bloomFilter) add(s string) {
+	for _, p := range bf.positions(s) {
+		bf.bits[p/64] |= 1 << (p % 64)
+	}
+}
+
+// mayContain reports false only if s was never added since the last reset.
+func (bf *bloomFilter) mayContain(s string) bool {
+	for _, p := range bf.positions(s) {
+		if bf.bits[p/64]&(1<<(p%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (bf *bloomFilter) reset() {
+	bf.bits = [bloomBits / 64]uint64{}
+}
+
+// semanticSignature identifies the cached queries that could possibly answer
+// query semantically: isQuerySubset only matches queries over the same
+// table(s), so that is what the signature is made of.
+func semanticSignature(query *QueryAST) string {
+	if query.Join != nil {
+		return query.FromTable + " JOIN " + query.Join.Table
+	}
+	return query.FromTable
+}
+
+// canServeSemanticHits reports whether a cached query could ever be the
+// superset isQuerySubset is looking for. Other entries only serve direct
+// hits, so they are kept out of the Bloom filter.
+func canServeSemanticHits(query *QueryAST) bool {
+	return !query.HasLimit && query.Offset == 0 && !isGroupedQuery(query) && !query.Distinct &&
+		!hasColumnComparison(query.Where)
+}
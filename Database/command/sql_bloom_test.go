@@ -0,0 +1,104 @@
+package command
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestBloomFilterHasNoFalseNegatives(t *testing.T) {
+	var bf bloomFilter
+	for i := 0; i < 500; i++ {
+		bf.add(fmt.Sprintf("table_%d", i))
+	}
+	for i := 0; i < 500; i++ {
+		if s := fmt.Sprintf("table_%d", i); !bf.mayContain(s) {
+			t.Fatalf("mayContain(%q) = false after adding it", s)
+		}
+	}
+	bf.reset()
+	if bf.mayContain("table_0") {
+		t.Error("mayContain is still true after a reset")
+	}
+}
+
+// TestBloomFilterNeverHidesAHit runs the same queries with and without the
+// filter, including after invalidations rebuild it, and expects every query
+// to be answered the same way both times.
+func TestBloomFilterNeverHidesAHit(t *testing.T) {
+	steps := []string{
+		"SELECT * FROM users WHERE age > 50",
+		"SELECT * FROM server_logs WHERE cpu_load > 80",
+		"SELECT * FROM products WHERE stock > 100",
+		"SELECT name FROM users WHERE age > 60",
+		"SELECT * FROM server_logs WHERE cpu_load > 90",
+		"SELECT * FROM products WHERE stock > 300",
+		"UPDATE products SET stock = 400 WHERE id = 103",
+		"SELECT * FROM products WHERE stock > 300",
+		"SELECT * FROM users WHERE age > 70",
+		"SELECT * FROM server_logs WHERE cpu_load > 95 AND status = 'ERROR'",
+		"SELECT * FROM users WHERE age > 50",
+	}
+	run := func(bloom bool) []string {
+		cfg := DefaultSQLCacheConfig()
+		cfg.MaxSize, cfg.MissPenalty, cfg.RefreshInterval, cfg.BloomFilter = 16, 0, 0, bloom
+		InitSQLCache(cfg)
+		InitBackingDB()
+		var outcomes []string
+		for _, q := range steps {
+			if sqlStatementKind(q) != "SELECT" {
+				mustSQL(t, q)
+				continue
+			}
+			outcomes = append(outcomes, cacheOutcome(t, q))
+		}
+		return outcomes
+	}
+
+	without, with := run(false), run(true)
+	for i := range without {
+		if with[i] != without[i] {
+			t.Errorf("query %d: %s with the Bloom filter, %s without", i, with[i], without[i])
+		}
+	}
+	if with[3] != "semantic" || with[len(with)-1] != "direct" {
+		t.Errorf("outcomes %v: expected semantic and direct hits to still happen", with)
+	}
+}
+
+// BenchmarkSemanticLookupOfUncachedTable looks up queries on a table none of
+// the cached entries read, the case the Bloom filter lets the lookup skip.
+func BenchmarkSemanticLookupOfUncachedTable(b *testing.B) {
+	for _, bloom := range []bool{false, true} {
+		b.Run(fmt.Sprintf("bloom=%v", bloom), func(b *testing.B) {
+			cfg := DefaultSQLCacheConfig()
+			cfg.MaxSize, cfg.Shards, cfg.MissPenalty, cfg.RefreshInterval, cfg.BloomFilter = 64, 1, 0, 0, bloom
+			InitSQLCache(cfg)
+			InitBackingDB()
+			for i := 0; i < 64; i++ {
+				ast, err := ParseSQL(fmt.Sprintf("SELECT * FROM users WHERE age > %d", i))
+				if err != nil {
+					b.Fatal(err)
+				}
+				results, err := executeOnBackingStore(ast)
+				if err != nil {
+					b.Fatal(err)
+				}
+				SQLCache.AddToCache(ast.OriginalString, ast, results)
+			}
+			if n := SQLCache.Len(); n != 64 {
+				b.Fatalf("cache holds %d entries, want 64", n)
+			}
+			query, err := ParseSQL("SELECT * FROM products WHERE stock > 100")
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, _, hit := SQLCache.PeekSemanticHit(query); hit {
+					b.Fatal("unexpected semantic hit")
+				}
+			}
+		})
+	}
+}
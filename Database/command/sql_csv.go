@@ -0,0 +1,101 @@
+package command
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// LoadTableFromCSV reads a CSV file with a header row into a new Table and
+// registers it under tableName ("<db>.<table>" for a named database),
+// replacing any table with that name. Cells that parse as integers are stored as int, everything else
+// as string. Returns the number of rows loaded.
+func LoadTableFromCSV(tableName, path string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("cannot open CSV file: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return 0, fmt.Errorf("cannot read CSV header: %w", err)
+	}
+	columns := make([]string, len(header))
+	for i, name := range header {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return 0, fmt.Errorf("malformed CSV header: column %d has no name", i+1)
+		}
+		if containsColumnFold(columns[:i], name) {
+			return 0, fmt.Errorf("malformed CSV header: duplicate column '%s'", name)
+		}
+		columns[i] = name
+	}
+
+	// The reader enforces that every record has as many fields as the header
+	records, err := reader.ReadAll()
+	if err != nil {
+		return 0, fmt.Errorf("malformed CSV data: %w", err)
+	}
+
+	rows := make([]Row, 0, len(records))
+	for _, record := range records {
+		row := make(Row, len(columns))
+		for i, cell := range record {
+			if n, err := strconv.Atoi(cell); err == nil {
+				row[columns[i]] = n
+			} else {
+				row[columns[i]] = cell
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	dbMutex.Lock()
+	defer dbMutex.Unlock()
+	tables, key, fullName, err := tableSlot(tableName)
+	if err != nil {
+		return 0, err
+	}
+	if existing, ok := lookupTable(tableName); ok {
+		_, existingKey := splitTableName(existing.Name)
+		delete(tables, existingKey) // Replaced, even if spelled differently
+	}
+	tables[key] = &Table{Name: fullName, Columns: columns, Rows: rows}
+	bumpGeneration(fullName)
+
+	return len(rows), nil
+}
+
+func init() {
+	Register(CommandInfo{Name: "DBLOADCSV", Usage: "DBLOADCSV <table> <path>", Summary: "Load a CSV file into a table"}, HandlerFunc(HandleDBLoadCSV))
+}
+
+// HandleDBLoadCSV processes DBLOADCSV <table> <path>
+// Replies with the number of rows loaded. Cached queries for the table are
+// dropped since they may describe the data it replaced.
+func HandleDBLoadCSV(argv []string, c net.Conn) {
+	if len(argv) < 3 || argv[1] == "" || argv[2] == "" {
+		c.Write([]byte("-ERR wrong number of arguments for 'dbloadcsv' command\r\n"))
+		return
+	}
+	table := argv[1]
+	path := argv[2]
+
+	loaded, err := LoadTableFromCSV(table, path)
+	if err != nil {
+		c.Write([]byte(fmt.Sprintf("-ERR %s\r\n", err.Error())))
+		return
+	}
+
+	removed := SQLCache.InvalidateTable(table)
+	queryLog.Info("load csv", "table", table, "path", path, "rows", loaded, "invalidated", removed)
+	c.Write([]byte(fmt.Sprintf(":%d\r\n", loaded)))
+}
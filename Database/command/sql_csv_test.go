@@ -0,0 +1,69 @@
+package command
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeCSV writes content to a file in the test's temporary directory and
+// returns its path.
+func writeCSV(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "data.csv")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadTableFromCSV(t *testing.T) {
+	resetSQL(t)
+	path := writeCSV(t, "id,host,load\n1,web-01,42\n2,web-02,n/a\n3,007,-5\n")
+
+	reply := runCommand(t, "DBLOADCSV", "hosts", path)
+	if reply != ":3\r\n" {
+		t.Fatalf("DBLOADCSV replied %q, want :3", reply)
+	}
+	results := queryRows(t, "SELECT * FROM hosts")
+	if len(results.Rows) != 3 {
+		t.Fatalf("loaded %d rows, want 3", len(results.Rows))
+	}
+	want := []Row{
+		{"id": 1, "host": "web-01", "load": 42},
+		{"id": 2, "host": "web-02", "load": "n/a"},
+		{"id": 3, "host": 7, "load": -5}, // each cell is typed on its own
+	}
+	for i, row := range results.Rows {
+		for col, val := range want[i] {
+			if row[col] != val {
+				t.Errorf("row %d %s = %#v, want %#v", i, col, row[col], val)
+			}
+		}
+	}
+	if got := strings.Join(results.Columns, ","); got != "id,host,load" {
+		t.Errorf("columns = %s", got)
+	}
+}
+
+func TestLoadTableFromCSVErrors(t *testing.T) {
+	resetSQL(t)
+	cases := map[string]string{
+		"empty column name": "id,,load\n1,a,2\n",
+		"duplicate column":  "id,host,ID\n1,a,2\n",
+		"ragged row":        "id,host\n1,a,2\n",
+		"no header":         "",
+	}
+	for name, content := range cases {
+		if _, err := LoadTableFromCSV("hosts", writeCSV(t, content)); err == nil {
+			t.Errorf("%s: loaded without error", name)
+		}
+	}
+	if _, err := LoadTableFromCSV("hosts", filepath.Join(t.TempDir(), "missing.csv")); err == nil {
+		t.Error("a missing file loaded without error")
+	}
+	if reply := runCommand(t, "DBLOADCSV", "hosts", writeCSV(t, "id,,load\n")); !strings.HasPrefix(reply, "-ERR malformed CSV header") {
+		t.Errorf("DBLOADCSV with a bad header replied %q", reply)
+	}
+}
@@ -0,0 +1,141 @@
+package command
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+)
+
+// DEFAULT_DATABASE holds the tables named without a database, e.g. "users"
+// rather than "analytics.events". It always exists and can't be dropped.
+const DEFAULT_DATABASE = "default"
+
+// Databases maps each database name to its tables, keyed by bare table name.
+// Tables outside the default database are named "<db>.<table>" everywhere
+// else (Table.Name, the cache, the stats), so queries and cache entries for
+// same-named tables in different databases never mix.
+// BackingDatabase is Databases[DEFAULT_DATABASE].
+var Databases map[string]map[string]*Table
+
+// splitTableName splits "db.table" into its database and table names.
+// A bare name is in the default database.
+func splitTableName(name string) (string, string) {
+	if i := strings.Index(name, "."); i >= 0 {
+		return name[:i], name[i+1:]
+	}
+	return DEFAULT_DATABASE, name
+}
+
+// lookupDatabase finds a database by name, preferring an exact match and
+// falling back to a case-insensitive one. It returns the declared name.
+// NOTE: This function is not thread-safe, callers must hold dbMutex!
+func lookupDatabase(name string) (string, map[string]*Table, bool) {
+	if tables, ok := Databases[name]; ok {
+		return name, tables, true
+	}
+	for key, tables := range Databases {
+		if strings.EqualFold(key, name) {
+			return key, tables, true
+		}
+	}
+	return "", nil, false
+}
+
+// tableSlot resolves where the table called name lives, or would be
+// created: its database's table map, its key in that map and its full
+// name ("<db>.<table>", or just "<table>" in the default database).
+// NOTE: This function is not thread-safe, callers must hold dbMutex!
+func tableSlot(name string) (map[string]*Table, string, string, error) {
+	dbName, table := splitTableName(name)
+	if table == "" || strings.Contains(table, ".") {
+		return nil, "", "", fmt.Errorf("invalid table name '%s'", name)
+	}
+	canonical, tables, ok := lookupDatabase(dbName)
+	if !ok {
+		return nil, "", "", fmt.Errorf("database '%s' not found", dbName)
+	}
+	if canonical == DEFAULT_DATABASE {
+		return tables, table, table, nil
+	}
+	return tables, table, canonical + "." + table, nil
+}
+
+// handleCreateDatabase parses and runs a CREATE DATABASE statement.
+func handleCreateDatabase(query string, c net.Conn) {
+	stmt, err := ParseCreateDatabase(query)
+	if err != nil {
+		c.Write([]byte(fmt.Sprintf("-ERR %s\r\n", err.Error())))
+		return
+	}
+
+	if err := executeCreateDatabase(stmt); err != nil {
+		c.Write([]byte(fmt.Sprintf("-ERR %s\r\n", err.Error())))
+		return
+	}
+
+	queryLog.Info("create database", "query", query, "database", stmt.Name)
+	c.Write([]byte("+OK\r\n"))
+}
+
+// executeCreateDatabase registers a new, empty database.
+func executeCreateDatabase(stmt *CreateDatabaseAST) error {
+	dbMutex.Lock()
+	defer dbMutex.Unlock()
+
+	if existing, _, exists := lookupDatabase(stmt.Name); exists {
+		return fmt.Errorf("database '%s' already exists", existing)
+	}
+	Databases[stmt.Name] = make(map[string]*Table)
+	return nil
+}
+
+// handleDropDatabase parses and runs a DROP DATABASE, then drops the cached
+// queries of every table it held.
+func handleDropDatabase(query string, c net.Conn) {
+	stmt, err := ParseDropDatabase(query)
+	if err != nil {
+		c.Write([]byte(fmt.Sprintf("-ERR %s\r\n", err.Error())))
+		return
+	}
+
+	tables, err := executeDropDatabase(stmt)
+	if err != nil {
+		c.Write([]byte(fmt.Sprintf("-ERR %s\r\n", err.Error())))
+		return
+	}
+
+	removed := 0
+	for _, table := range tables {
+		removed += SQLCache.InvalidateTable(table)
+	}
+	queryLog.Info("drop database", "query", query, "tables", len(tables), "invalidated", removed)
+	c.Write([]byte("+OK\r\n"))
+}
+
+// executeDropDatabase removes a database and returns the full names of the
+// tables it held. A missing database is only an error without IF EXISTS.
+func executeDropDatabase(stmt *DropDatabaseAST) ([]string, error) {
+	dbMutex.Lock()
+	defer dbMutex.Unlock()
+
+	name, tables, exists := lookupDatabase(stmt.Name)
+	if !exists {
+		if stmt.IfExists {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("database '%s' not found", stmt.Name)
+	}
+	if name == DEFAULT_DATABASE {
+		return nil, fmt.Errorf("the '%s' database can't be dropped", DEFAULT_DATABASE)
+	}
+
+	names := make([]string, 0, len(tables))
+	for _, table := range tables {
+		names = append(names, table.Name)
+		bumpGeneration(table.Name)
+	}
+	sort.Strings(names)
+	delete(Databases, name)
+	return names, nil
+}
@@ -0,0 +1,60 @@
+package command
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDatabasesIsolateSameNamedTables(t *testing.T) {
+	resetSQL(t)
+	for _, stmt := range []string{
+		"CREATE DATABASE a",
+		"CREATE DATABASE b",
+		"CREATE TABLE a.users (id INT, name TEXT, age INT)",
+		"CREATE TABLE b.users (id INT, name TEXT, age INT)",
+		"INSERT INTO a.users (id, name, age) VALUES (1, 'Ann', 50)",
+		"INSERT INTO a.users (id, name, age) VALUES (2, 'Abe', 70)",
+		"INSERT INTO b.users (id, name, age) VALUES (1, 'Bea', 60)",
+	} {
+		mustSQL(t, stmt)
+	}
+	names := func(query string) []interface{} {
+		t.Helper()
+		return column(queryRows(t, query), "name")
+	}
+	if got, want := names("SELECT name FROM a.users"), []interface{}{"Ann", "Abe"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("a.users = %v, want %v", got, want)
+	}
+	if got, want := names("SELECT name FROM b.users"), []interface{}{"Bea"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("b.users = %v, want %v", got, want)
+	}
+	if n := len(queryRows(t, "SELECT * FROM users").Rows); n != 15 {
+		t.Errorf("default users has %d rows, want the 15 seeded", n)
+	}
+
+	// A cached superset in one database never answers another's query
+	mustSQL(t, "SELECT * FROM a.users WHERE age > 40")
+	if got := cacheOutcome(t, "SELECT * FROM b.users WHERE age > 50"); got != "miss" {
+		t.Errorf("b.users subset of a cached a.users query: got a %s, want a miss", got)
+	}
+	if got := cacheOutcome(t, "SELECT * FROM users WHERE age > 50"); got != "miss" {
+		t.Errorf("users subset of a cached a.users query: got a %s, want a miss", got)
+	}
+
+	// Writes and drops only invalidate their own database's entries
+	mustSQL(t, "INSERT INTO b.users (id, name, age) VALUES (2, 'Bob', 80)")
+	if _, hit := SQLCache.Peek("SELECT * FROM a.users WHERE age > 40"); !hit {
+		t.Error("an insert into b.users invalidated an a.users query")
+	}
+	mustSQL(t, "DROP DATABASE a")
+	if _, hit := SQLCache.Peek("SELECT * FROM a.users WHERE age > 40"); hit {
+		t.Error("DROP DATABASE a kept its cached queries")
+	}
+	if reply := runSQL(t, &recordConn{}, "SELECT * FROM a.users"); !strings.HasPrefix(reply, "-ERR") {
+		t.Errorf("query on a dropped database = %q, want an error", reply)
+	}
+	if got, want := names("SELECT name FROM b.users"), []interface{}{"Bea", "Bob"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("b.users after dropping a = %v, want %v", got, want)
+	}
+}
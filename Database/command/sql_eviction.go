@@ -0,0 +1,54 @@
+package command
+
+import (
+	"container/list"
+	"fmt"
+	"strings"
+)
+
+// EvictionPolicy decides which cache entry is dropped when the cache is full.
+// The cache keeps its entries in a list with new entries pushed to the front;
+// a policy may reorder that list on access and picks the victim from it.
+// Each cache shard has its own list and policy, and methods are called with
+// that shard's write lock held.
+type EvictionPolicy interface {
+	Name() string
+	RecordAccess(elem *list.Element) // A direct or semantic hit used elem
+	Victim() *list.Element           // The entry to evict, nil if the cache is empty
+}
+
+// lruPolicy evicts the least recently used entry: every access moves the
+// entry to the front, so the back of the list is the coldest.
+type lruPolicy struct {
+	entries *list.List
+}
+
+func (p *lruPolicy) Name() string { return "LRU" }
+
+func (p *lruPolicy) RecordAccess(elem *list.Element) { p.entries.MoveToFront(elem) }
+
+func (p *lruPolicy) Victim() *list.Element { return p.entries.Back() }
+
+// fifoPolicy evicts the oldest inserted entry regardless of how often it is
+// used: accesses don't reorder the list.
+type fifoPolicy struct {
+	entries *list.List
+}
+
+func (p *fifoPolicy) Name() string { return "FIFO" }
+
+func (p *fifoPolicy) RecordAccess(elem *list.Element) {}
+
+func (p *fifoPolicy) Victim() *list.Element { return p.entries.Back() }
+
+// newEvictionPolicy builds the named policy ("LRU" or "FIFO") over the
+// cache's entry list. An empty name selects LRU.
+func newEvictionPolicy(name string, entries *list.List) (EvictionPolicy, error) {
+	switch strings.ToUpper(name) {
+	case "", "LRU":
+		return &lruPolicy{entries: entries}, nil
+	case "FIFO":
+		return &fifoPolicy{entries: entries}, nil
+	}
+	return nil, fmt.Errorf("unknown eviction policy '%s'", name)
+}
@@ -0,0 +1,40 @@
+package command
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestEvictionOrder(t *testing.T) {
+	cases := []struct {
+		policy  string
+		evicted int // which of the first three queries the fourth pushes out
+	}{
+		{"LRU", 2}, // 1 was used again, so 2 is the coldest
+		{"FIFO", 1},
+		{"lru", 2},
+		{"bogus", 2}, // unknown policies fall back to LRU
+	}
+	for _, c := range cases {
+		resetSQL(t)
+		cfg := DefaultSQLCacheConfig()
+		cfg.MaxSize, cfg.Eviction, cfg.MissPenalty, cfg.RefreshInterval = 3, c.policy, 0, 0
+		InitSQLCache(cfg)
+
+		query := func(n int) string { return fmt.Sprintf("SELECT * FROM users WHERE id = %d", n) }
+		mustSQL(t, query(1))
+		mustSQL(t, query(2))
+		mustSQL(t, query(3))
+		if got := cacheOutcome(t, query(1)); got != "direct" {
+			t.Fatalf("%s: repeated query was a %s", c.policy, got)
+		}
+		mustSQL(t, query(4))
+
+		for n := 1; n <= 4; n++ {
+			_, cached := SQLCache.Peek(query(n))
+			if want := n != c.evicted; cached != want {
+				t.Errorf("%s: query %d cached = %v, want %v", c.policy, n, cached, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package command
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExplainReportsEachOutcome(t *testing.T) {
+	resetSQL(t)
+	explain := func(query string, want ...string) {
+		t.Helper()
+		total, size := SQLCache.totalQueries.Load(), SQLCache.Len()
+		plan := bulkBody(t, mustSQL(t, "EXPLAIN "+query))
+		for _, line := range want {
+			if !strings.Contains(plan, "\n"+line+"\n") && !strings.HasSuffix(plan, "\n"+line) {
+				t.Errorf("EXPLAIN %s is missing %q:\n%s", query, line, plan)
+			}
+		}
+		if SQLCache.totalQueries.Load() != total || SQLCache.Len() != size {
+			t.Errorf("EXPLAIN %s changed the stats or the cache", query)
+		}
+	}
+
+	explain("SELECT * FROM users WHERE age > 50",
+		"Cache: MISS (0s I/O penalty)",
+		"Execution: full scan of users (15 rows)",
+		"Estimated Rows: at most 15 (rows scanned)")
+	if _, hit := SQLCache.Peek("SELECT * FROM users WHERE age > 50"); hit {
+		t.Error("EXPLAIN cached the query")
+	}
+
+	mustSQL(t, "SELECT * FROM users WHERE age > 50")
+	explain("SELECT * FROM users WHERE age > 50",
+		"Cache: HIT (Direct)",
+		"Estimated Rows: 9")
+	explain("SELECT name FROM users WHERE age > 80",
+		"Cache: HIT (Semantic)",
+		"Superset: SELECT * FROM users WHERE age > 50",
+		"Estimated Rows: 5")
+
+	if reply := runSQL(t, &recordConn{}, "EXPLAIN DELETE FROM users"); !strings.HasPrefix(reply, "-ERR") {
+		t.Errorf("EXPLAIN of a DELETE = %q, want an error", reply)
+	}
+	if n := len(queryRows(t, "SELECT * FROM users").Rows); n != 15 {
+		t.Errorf("users has %d rows after EXPLAIN DELETE, want 15", n)
+	}
+}
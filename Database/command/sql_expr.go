@@ -0,0 +1,232 @@
+package command
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ArithExpr is an arithmetic expression from the select list, e.g.
+// "stock * 2" or "(price - cost) / price". Leaves hold a Column or a
+// numeric Value; inner nodes combine Left and Right with Op.
+type ArithExpr struct {
+	Op     string // "+", "-", "*" or "/"; "" for a leaf
+	Left   *ArithExpr
+	Right  *ArithExpr
+	Column string      // Leaf: the column read, "" for a literal
+	Value  interface{} // Leaf: the literal (int or float64) when Column is ""
+}
+
+// String returns the name the expression is reported under, e.g.
+// "stock * 2". Parentheses are only kept where they change the meaning.
+func (e *ArithExpr) String() string {
+	if e.Op == "" {
+		if e.Column != "" {
+			return e.Column
+		}
+		return formatValue(e.Value)
+	}
+	left, right := e.Left.String(), e.Right.String()
+	if e.Left.Op != "" && arithPrecedence(e.Left.Op) < arithPrecedence(e.Op) {
+		left = "(" + left + ")"
+	}
+	if e.Right.Op != "" && arithPrecedence(e.Right.Op) <= arithPrecedence(e.Op) {
+		right = "(" + right + ")"
+	}
+	return left + " " + e.Op + " " + right
+}
+
+func arithPrecedence(op string) int {
+	if op == "*" || op == "/" {
+		return 2
+	}
+	return 1
+}
+
+// eval computes the expression for one row. Integer +, - and * stay
+// integers; division always gives a float so 7 / 2 is 3.5. A NULL or
+// non-numeric operand, or a division by zero, makes the result NULL.
+func (e *ArithExpr) eval(row Row) interface{} {
+	if e.Op == "" {
+		if e.Column == "" {
+			return e.Value
+		}
+		val := row[e.Column]
+		if _, ok := asFloat(val); !ok {
+			return nil
+		}
+		return val
+	}
+
+	left, right := e.Left.eval(row), e.Right.eval(row)
+	if left == nil || right == nil {
+		return nil
+	}
+	if l, ok := left.(int); ok && e.Op != "/" {
+		if r, ok := right.(int); ok {
+			switch e.Op {
+			case "+":
+				return l + r
+			case "-":
+				return l - r
+			case "*":
+				return l * r
+			}
+		}
+	}
+
+	l, _ := asFloat(left)
+	r, _ := asFloat(right)
+	switch e.Op {
+	case "+":
+		return l + r
+	case "-":
+		return l - r
+	case "*":
+		return l * r
+	}
+	if r == 0 {
+		return nil
+	}
+	return l / r
+}
+
+// resolveColumns rewrites the expression's column names to the schema's
+// spelling (see resolveColumn).
+func (e *ArithExpr) resolveColumns(cols []string, table string) error {
+	if e.Op != "" {
+		if err := e.Left.resolveColumns(cols, table); err != nil {
+			return err
+		}
+		return e.Right.resolveColumns(cols, table)
+	}
+	if e.Column == "" {
+		return nil
+	}
+	resolved, err := resolveColumn(cols, e.Column, table)
+	if err != nil {
+		return err
+	}
+	e.Column = resolved
+	return nil
+}
+
+// isExpressionColumn reports whether a select-list name refers to a
+// computed expression.
+func (ast *QueryAST) isExpressionColumn(col string) bool {
+	for _, expr := range ast.Expressions {
+		if expr.String() == col {
+			return true
+		}
+	}
+	return false
+}
+
+// computeExpressions returns rows with each expression's value added under
+// its name, for projectRows to pick up. Rows that already hold a value for
+// an expression (results cached with it) keep it. The input rows are never
+// modified, since they may belong to a table or a cached result.
+func computeExpressions(rows []Row, exprs []*ArithExpr) []Row {
+	if len(exprs) == 0 {
+		return rows
+	}
+	computed := make([]Row, len(rows))
+	for i, row := range rows {
+		out := make(Row, len(row)+len(exprs))
+		for col, val := range row {
+			out[col] = val
+		}
+		for _, expr := range exprs {
+			name := expr.String()
+			if _, ok := out[name]; !ok {
+				out[name] = expr.eval(row)
+			}
+		}
+		computed[i] = out
+	}
+	return computed
+}
+
+// startsExpression reports whether tok, following a select-list item's
+// first token, continues it as an arithmetic expression.
+func startsExpression(tok sqlToken) bool {
+	return tok.Kind == tokArith || tok.Kind == tokStar ||
+		(tok.Kind == tokNumber && strings.HasPrefix(tok.Text, "-"))
+}
+
+// parseArithExpr reads "<term> {(+|-) <term>}".
+func (p *sqlParser) parseArithExpr() (*ArithExpr, error) {
+	left, err := p.parseArithTerm()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok := p.peek()
+		switch {
+		case tok.Kind == tokArith && (tok.Text == "+" || tok.Text == "-"):
+			p.next()
+		case tok.Kind == tokNumber && strings.HasPrefix(tok.Text, "-"):
+			// "stock-2" is read as "stock" and "-2": a subtraction
+			p.tokens[p.pos].Text = tok.Text[1:]
+			tok.Text = "-"
+		default:
+			return left, nil
+		}
+		right, err := p.parseArithTerm()
+		if err != nil {
+			return nil, err
+		}
+		left = &ArithExpr{Op: tok.Text, Left: left, Right: right}
+	}
+}
+
+// parseArithTerm reads "<factor> {(*|/) <factor>}".
+func (p *sqlParser) parseArithTerm() (*ArithExpr, error) {
+	left, err := p.parseArithFactor()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok := p.peek()
+		if tok.Kind != tokStar && !(tok.Kind == tokArith && tok.Text == "/") {
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseArithFactor()
+		if err != nil {
+			return nil, err
+		}
+		left = &ArithExpr{Op: tok.Text, Left: left, Right: right}
+	}
+}
+
+// parseArithFactor reads a column, a number or a parenthesized expression.
+func (p *sqlParser) parseArithFactor() (*ArithExpr, error) {
+	tok := p.next()
+	switch tok.Kind {
+	case tokNumber:
+		if n, err := strconv.Atoi(tok.Text); err == nil {
+			return &ArithExpr{Value: n}, nil
+		}
+		f, err := strconv.ParseFloat(tok.Text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number '%s'", tok.Text)
+		}
+		return &ArithExpr{Value: f}, nil
+	case tokIdent:
+		if strings.EqualFold(tok.Text, "FROM") || strings.EqualFold(tok.Text, "AS") {
+			break
+		}
+		return &ArithExpr{Column: tok.Text}, nil
+	case tokLParen:
+		expr, err := p.parseArithExpr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next().Kind != tokRParen {
+			return nil, fmt.Errorf("missing closing parenthesis in expression")
+		}
+		return expr, nil
+	}
+	return nil, fmt.Errorf("expected a column, number or '(' in expression, got %s", tok.describe())
+}
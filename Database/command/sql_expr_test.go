@@ -0,0 +1,33 @@
+package command
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestComputedColumns(t *testing.T) {
+	resetSQL(t)
+	cases := []struct {
+		query, col string
+		want       []interface{} // apple, banana, orange unless filtered
+	}{
+		{"SELECT item, stock * 2 FROM products", "stock * 2", []interface{}{1000, 400, 700}},
+		{"SELECT item, stock - 100 FROM products", "stock - 100", []interface{}{400, 100, 250}},
+		{"SELECT item, stock + 0.5 FROM products", "stock + 0.5", []interface{}{500.5, 200.5, 350.5}},
+		{"SELECT item, stock / 4 FROM products", "stock / 4", []interface{}{125.0, 50.0, 87.5}},
+		{"SELECT item, (stock + 100) * 2 FROM products WHERE stock > 300", "(stock + 100) * 2", []interface{}{1200, 900}},
+		// No number to compute with gives NULL rather than an error
+		{"SELECT item, stock / 0 FROM products", "stock / 0", []interface{}{nil, nil, nil}},
+		{"SELECT item, stock * item FROM products", "stock * item", []interface{}{nil, nil, nil}},
+	}
+	for _, c := range cases {
+		if got := column(queryRows(t, c.query), c.col); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: got %v, want %v", c.query, got, c.want)
+		}
+	}
+
+	body := bulkBody(t, mustSQL(t, "SELECT item, stock * 2 AS double_stock FROM products WHERE stock < 300"))
+	if want := "item   | double_stock\n-------+-------------\nbanana |          400\n\n(1 rows)\n"; body != want {
+		t.Errorf("aliased computed column =\n%s\nwant\n%s", body, want)
+	}
+}
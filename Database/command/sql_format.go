@@ -0,0 +1,87 @@
+package command
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// formatJSONResults renders the results as a JSON array with one object per
+// row, keyed by the output headers in column order. Numbers stay JSON
+// numbers and NULLs become null; an empty result is "[]". The row cap
+// applies as for tables, without a note since JSON has nowhere to put one.
+func formatJSONResults(table *Table, headers []string) string {
+	var sb strings.Builder
+	sb.WriteString("[")
+	if table != nil {
+		if headers == nil {
+			headers = table.Columns
+		}
+		rows := table.Rows
+		if limit := maxResultRows.Load(); limit > 0 && int64(len(rows)) > limit {
+			rows = rows[:limit]
+		}
+
+		for r, row := range rows {
+			if r > 0 {
+				sb.WriteString(",")
+			}
+			sb.WriteString("{")
+			for i, col := range table.Columns {
+				if i > 0 {
+					sb.WriteString(",")
+				}
+				key, _ := json.Marshal(headers[i])
+				val, err := json.Marshal(row[col])
+				if err != nil {
+					// Only non-finite floats fail, which JSON can't represent
+					val = []byte("null")
+				}
+				sb.Write(key)
+				sb.WriteString(":")
+				sb.Write(val)
+			}
+			sb.WriteString("}")
+		}
+	}
+	sb.WriteString("]")
+
+	body := sb.String()
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(body), body)
+}
+
+// formatCSVResults renders the results as CSV: a header row, then one line
+// per row. Fields containing commas, quotes or line breaks are quoted per
+// RFC 4180, NULLs are empty fields and the row cap applies as for JSON.
+func formatCSVResults(table *Table, headers []string) string {
+	var sb strings.Builder
+	if table != nil {
+		if headers == nil {
+			headers = table.Columns
+		}
+		rows := table.Rows
+		if limit := maxResultRows.Load(); limit > 0 && int64(len(rows)) > limit {
+			rows = rows[:limit]
+		}
+
+		w := csv.NewWriter(&sb)
+		w.UseCRLF = true // RFC 4180 line endings
+		w.Write(headers)
+		record := make([]string, len(table.Columns))
+		for _, row := range rows {
+			for i, col := range table.Columns {
+				if val, ok := row[col]; ok && val != nil {
+					record[i] = fmt.Sprintf("%v", val)
+				} else {
+					record[i] = ""
+				}
+			}
+			w.Write(record)
+		}
+		w.Flush()
+	}
+
+	body := sb.String()
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(body), body)
+}
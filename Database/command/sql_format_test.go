@@ -0,0 +1,62 @@
+package command
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestJSONFormat(t *testing.T) {
+	resetSQL(t)
+	body := bulkBody(t, mustSQL(t, "SELECT id, name AS who, age FROM users WHERE age > 90 ORDER BY id FORMAT JSON"))
+	want := `[{"id":7,"who":"Grace","age":97},{"id":13,"who":"Mike","age":91},{"id":14,"who":"Nina","age":92}]`
+	if body != want {
+		t.Errorf("FORMAT JSON = %s, want %s", body, want)
+	}
+	var objects []map[string]interface{}
+	if err := json.Unmarshal([]byte(body), &objects); err != nil {
+		t.Fatalf("FORMAT JSON isn't valid JSON: %v", err)
+	}
+
+	if body := bulkBody(t, mustSQL(t, "SELECT * FROM users WHERE age > 500 FORMAT JSON")); body != "[]" {
+		t.Errorf("empty FORMAT JSON = %s, want []", body)
+	}
+
+	mustSQL(t, "INSERT INTO users (id, name) VALUES (16, 'Zed')")
+	if body := bulkBody(t, mustSQL(t, "SELECT * FROM users WHERE id = 16 FORMAT JSON")); body != `[{"id":16,"name":"Zed","age":null}]` {
+		t.Errorf("FORMAT JSON with a NULL = %s", body)
+	}
+}
+
+func TestCSVFormatQuotesFields(t *testing.T) {
+	resetSQL(t)
+	mustSQL(t, "CREATE TABLE notes (id INT, body TEXT)")
+	mustSQL(t, "INSERT INTO notes (id, body) VALUES (1, 'plain')")
+	mustSQL(t, "INSERT INTO notes (id, body) VALUES (2, 'milk, eggs')")
+	mustSQL(t, `INSERT INTO notes (id, body) VALUES (3, 'say "hi"')`)
+	mustSQL(t, "INSERT INTO notes (id) VALUES (4)")
+
+	want := "id,body\r\n" +
+		"1,plain\r\n" +
+		"2,\"milk, eggs\"\r\n" +
+		"3,\"say \"\"hi\"\"\"\r\n" +
+		"4,\r\n"
+	if body := bulkBody(t, mustSQL(t, "SELECT * FROM notes ORDER BY id FORMAT CSV")); body != want {
+		t.Errorf("FORMAT CSV =\n%q\nwant\n%q", body, want)
+	}
+	if body := bulkBody(t, mustSQL(t, "SELECT body AS text FROM notes WHERE id > 10 FORMAT CSV")); body != "text\r\n" {
+		t.Errorf("empty FORMAT CSV = %q, want only the header", body)
+	}
+}
+
+func TestTableFormatAlignsNumbersRight(t *testing.T) {
+	resetSQL(t)
+	body := bulkBody(t, mustSQL(t, "SELECT name, age FROM users WHERE age < 20 ORDER BY age"))
+	want := "name  | age\n" +
+		"------+----\n" +
+		"Laura |   8\n" +
+		"Karl  |  19\n" +
+		"\n(2 rows)\n"
+	if body != want {
+		t.Errorf("table format =\n%s\nwant\n%s", body, want)
+	}
+}
@@ -2,97 +2,264 @@ package command
 
 import (
 	"fmt"
+	"math"
 	"net"
-	// "strconv"
+	"sort"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 )
 
+// DEFAULT_MAX_RESULT_ROWS caps how many rows a reply renders, so an
+// accidental full scan of a big table can't build a huge response string.
+const DEFAULT_MAX_RESULT_ROWS = 10000
+
+// maxResultRows is the current cap, changed with SQLCACHE MAXROWS. 0 means
+// no cap.
+var maxResultRows atomic.Int64
+
+func init() {
+	maxResultRows.Store(DEFAULT_MAX_RESULT_ROWS)
+
+	Register(CommandInfo{Name: "SQL", Aliases: []string{"SELECT"}, Usage: "SQL <statement>", Summary: "Run SQL statements through the semantic cache"}, HandlerFunc(HandleSQL))
+	Register(CommandInfo{Name: "SQLSTATS", Usage: "SQLSTATS [RESET|JSON|TABLE <name>]", Summary: "Report or reset the SQL cache statistics"}, HandlerFunc(HandleSQLStats))
+	Register(CommandInfo{Name: "SQLINVALIDATE", Usage: "SQLINVALIDATE <table>", Summary: "Drop the cached queries that read a table"}, HandlerFunc(HandleSQLInvalidate))
+	Register(CommandInfo{Name: "SQLCACHE", Usage: "SQLCACHE PENALTY|MAXROWS|TOLERANCE [n] / DUMP", Summary: "Tune the miss penalty, row cap and numeric tolerance, or list the cached queries"}, HandlerFunc(HandleSQLCache))
+}
+
 // HandleSQL is the main entry point for SQL queries.
-func HandleSQL(input string, c net.Conn) {
+// Several statements may be sent at once separated by semicolons; each is
+// run (and cached) on its own and the replies are written back in order.
+func HandleSQL(argv []string, c net.Conn) {
+	// SQL carries the query as its argument, while a bare SELECT is the
+	// query itself
+	if strings.EqualFold(argv[0], "SQL") {
+		argv = argv[1:]
+	}
+	statements := splitSQLStatements(strings.Join(argv, " "))
+	if len(statements) == 0 {
+		c.Write([]byte("-ERR invalid SQL command\r\n"))
+		return
+	}
+
+	for _, stmt := range statements {
+		handleSQLStatement(stmt, c)
+	}
+}
+
+// handleSQLStatement runs a single SQL statement and writes its reply.
+func handleSQLStatement(sqlQueryString string, c net.Conn) {
+	// Write statements, EXPLAIN, SHOW, DESCRIBE and USE bypass the cache (and its stats) entirely
+	switch sqlStatementKind(sqlQueryString) {
+	case "INSERT":
+		handleInsert(sqlQueryString, c)
+		return
+	case "UPDATE":
+		handleUpdate(sqlQueryString, c)
+		return
+	case "DELETE":
+		handleDelete(sqlQueryString, c)
+		return
+	case "CREATE":
+		switch secondKeyword(sqlQueryString) {
+		case "INDEX":
+			handleCreateIndex(sqlQueryString, c)
+		case "DATABASE":
+			handleCreateDatabase(sqlQueryString, c)
+		default:
+			handleCreateTable(sqlQueryString, c)
+		}
+		return
+	case "DROP":
+		if secondKeyword(sqlQueryString) == "DATABASE" {
+			handleDropDatabase(sqlQueryString, c)
+		} else {
+			handleDropTable(sqlQueryString, c)
+		}
+		return
+	case "EXPLAIN":
+		handleExplain(sqlQueryString, c)
+		return
+	case "SHOW":
+		handleShowTables(sqlQueryString, c)
+		return
+	case "DESCRIBE":
+		handleDescribe(sqlQueryString, c)
+		return
+	case "USE":
+		handleUseTable(sqlQueryString, c)
+		return
+	}
+
+	sqlQueryString, format := splitOutputFormat(sqlQueryString)
+
+	if sqlStatementKind(sqlQueryString) == "NOCACHE" {
+		handleNoCache(applyDefaultTable(strings.TrimSpace(sqlQueryString[len("NOCACHE"):]), c), format, c)
+		return
+	}
+	// A SELECT without FROM reads the table chosen with USE TABLE
+	sqlQueryString = applyDefaultTable(sqlQueryString, c)
+
 	// --- NEW: Start timer and update total queries ---
 	startTime := time.Now()
 	SQLCache.IncrementTotalQueries()
 	// --- End NEW ---
 
-	// 1. Extract the raw SQL query string.
-	sqlQueryString := extractSQLQuery(input)
-	if sqlQueryString == "" {
-		c.Write([]byte("-ERR invalid SQL command\r\n"))
+	// 2. Check for a Direct Cache Hit. The raw string is the cache key, so
+	// this needs no parsing; only semantic matching and execution do.
+	if entry, hit := lookupDirectHit(sqlQueryString, startTime); hit {
+		logQuery(c, sqlQueryString, outcomeDirectHit, time.Since(startTime), entry.Query.FromTable, len(entry.Results.Rows))
+		writeResults(c, entry.Results, entry.Query.outputHeaders(entry.Results), format)
 		return
 	}
 
-	// 2. Parse the SQL string into an AST.
+	// 3. Parse the SQL string into an AST.
 	queryAST, err := ParseSQL(sqlQueryString)
 	if err != nil {
 		c.Write([]byte(fmt.Sprintf("-ERR %s\r\n", err.Error())))
 		return
 	}
+	// Names are case-insensitive; use the schema's spelling from here on
+	if err := resolveQueryNames(queryAST); err != nil {
+		c.Write([]byte(fmt.Sprintf("-ERR %s\r\n", err.Error())))
+		return
+	}
 
-	// --- CACHE LOGIC ---
-
-	// 3. Check for a Direct Cache Hit
-	if entry, hit := SQLCache.Get(sqlQueryString); hit {
-		// Cache Hit! (Get() increments the stat)
-		// --- NEW: Improved Logging ---
-		elapsed := time.Since(startTime)
-		fmt.Printf("[QUERY: %s] \n -> Cache HIT (Direct) | Time: %s\n", sqlQueryString, elapsed)
-		// --- End NEW ---
-		resp := formatResults(entry.Results)
-		c.Write([]byte(resp))
+	results, outcome, err := answerQuery(sqlQueryString, queryAST, startTime)
+	if err != nil {
+		c.Write([]byte(fmt.Sprintf("-ERR %s\r\n", err.Error())))
 		return
 	}
+	logQuery(c, sqlQueryString, outcome, time.Since(startTime), queryAST.FromTable, len(results.Rows))
+	writeResults(c, results, queryAST.outputHeaders(results), format)
+}
+
+// lookupDirectHit returns the cache entry stored under exactly
+// sqlQueryString, timing the hit. startTime is when the query
+// arrived, for the latency stats.
+func lookupDirectHit(sqlQueryString string, startTime time.Time) (*CacheEntry, bool) {
+	entry, hit := SQLCache.Get(sqlQueryString)
+	if !hit {
+		return nil, false
+	}
+	// Cache Hit! (Get() increments the stat)
+	SQLCache.RecordLatency(outcomeDirectHit, time.Since(startTime))
+	return entry, true
+}
+
+// answerQuery returns the results of a parsed SELECT that wasn't a direct
+// hit: from a cached superset when there is one, otherwise from the backing
+// database, caching them under sqlQueryString. It also reports which of
+// the two happened. startTime is when the query arrived, for the latency
+// stats.
+func answerQuery(sqlQueryString string, queryAST *QueryAST, startTime time.Time) (*Table, queryOutcome, error) {
+	// --- CACHE LOGIC ---
 
 	// 4. Check for a Semantic Cache Hit
 	// --- NEW: Updated signature to get cachedQuery ---
 	if results, cachedQuery, hit := SQLCache.FindSemanticHit(queryAST); hit {
 		// Semantic Hit!
 		// --- NEW: Update Stat ---
-		SQLCache.IncrementSemanticHits()
-		// --- NEW: Improved Logging with AST ---
-		elapsed := time.Since(startTime)
-		fmt.Printf("[QUERY: %s] \n -> Cache HIT (Semantic) | Time: %s\n", sqlQueryString, elapsed)
-		fmt.Println("   | Fulfilling from cached superset query:")
-		fmt.Printf("   |--- Cached Query: %s\n", cachedQuery.OriginalString)
-		// This prints the AST of the *cached query*
-		fmt.Printf("   |--- Cached %s\n", cachedQuery.String()) 
-		// --- End NEW ---
-
-		resp := formatResults(results)
-		c.Write([]byte(resp))
-		return
+		SQLCache.IncrementSemanticHits(queryAST.FromTable)
+		SQLCache.RecordLatency(outcomeSemanticHit, time.Since(startTime))
+		queryLog.Debug("fulfilled from cached superset",
+			"query", sqlQueryString,
+			"cached_query", cachedQuery.OriginalString,
+			"cached_ast", cachedQuery.String(),
+		)
+
+		return results, outcomeSemanticHit, nil
 	}
 
 	// 5. Cache Miss
 	// --- NEW: Update Stat ---
-	SQLCache.IncrementCacheMisses()
+	SQLCache.IncrementCacheMisses(queryAST.FromTable)
 	// --- End NEW ---
 
 	// Simulate the I/O penalty for a cache miss
-	time.Sleep(CACHE_MISS_PENALTY)
+	penalty := SQLCache.MissPenalty()
+	if penalty > 0 {
+		time.Sleep(penalty)
+	}
 
 	// 6. Execute query against the "Backing Database"
 	results, err := executeOnBackingStore(queryAST)
 	if err != nil {
-		c.Write([]byte(fmt.Sprintf("-ERR %s\r\n", err.Error())))
-		return
+		return nil, outcomeMiss, err
 	}
 
 	// 7. Add the new result to the cache
 	SQLCache.AddToCache(sqlQueryString, queryAST, results)
 
 	// 8. Return results to client
-	// --- NEW: Improved Logging ---
-	elapsed := time.Since(startTime)
-	fmt.Printf("[QUERY: %s] \n -> Cache MISS | Time: %s (Includes %s I/O penalty)\n", sqlQueryString, elapsed, CACHE_MISS_PENALTY)
-	// --- End NEW ---
+	SQLCache.RecordLatency(outcomeMiss, time.Since(startTime))
+	queryLog.Debug("executed on backing store", "query", sqlQueryString, "penalty", penalty)
 
-	resp := formatResults(results)
-	c.Write([]byte(resp))
+	return results, outcomeMiss, nil
+}
+
+// handleNoCache runs NOCACHE <select> straight against the backing store,
+// miss penalty included, without reading or populating the cache or
+// touching its stats, so backing-store latency can be measured in isolation.
+func handleNoCache(sqlQueryString string, format string, c net.Conn) {
+	startTime := time.Now()
+	queryAST, err := ParseSQL(sqlQueryString)
+	if err != nil {
+		c.Write([]byte(fmt.Sprintf("-ERR %s\r\n", err.Error())))
+		return
+	}
+	if err := resolveQueryNames(queryAST); err != nil {
+		c.Write([]byte(fmt.Sprintf("-ERR %s\r\n", err.Error())))
+		return
+	}
+
+	if penalty := SQLCache.MissPenalty(); penalty > 0 {
+		time.Sleep(penalty)
+	}
+	results, err := executeOnBackingStore(queryAST)
+	if err != nil {
+		c.Write([]byte(fmt.Sprintf("-ERR %s\r\n", err.Error())))
+		return
+	}
+	logQuery(c, sqlQueryString, outcomeNoCache, time.Since(startTime), queryAST.FromTable, len(results.Rows))
+	writeResults(c, results, queryAST.outputHeaders(results), format)
 }
 
 // --- NEW: Handler for SQLSTATS command ---
-func HandleSQLStats(c net.Conn) {
+// SQLSTATS RESET zeroes the counters instead of reporting them,
+// SQLSTATS JSON reports them as a JSON object and SQLSTATS TABLE <name>
+// reports only the queries against one table.
+func HandleSQLStats(argv []string, c net.Conn) {
+	if len(argv) > 1 && strings.EqualFold(argv[1], "TABLE") {
+		if len(argv) < 3 || argv[2] == "" {
+			c.Write([]byte("-ERR wrong number of arguments for 'sqlstats table' command\r\n"))
+			return
+		}
+		stats, ok := SQLCache.GetTableStats(argv[2])
+		if !ok {
+			c.Write([]byte(fmt.Sprintf("-ERR no queries recorded for table '%s'\r\n", argv[2])))
+			return
+		}
+		c.Write([]byte(fmt.Sprintf("$%d\r\n%s\r\n", len(stats), stats)))
+		return
+	}
+	if len(argv) > 1 && strings.EqualFold(argv[1], "RESET") {
+		SQLCache.ResetStats()
+		queryLog.Info("cache stats reset")
+		c.Write([]byte("+OK\r\n"))
+		return
+	}
+	if len(argv) > 1 && strings.EqualFold(argv[1], "JSON") {
+		stats, err := SQLCache.GetCacheStatsJSON()
+		if err != nil {
+			c.Write([]byte(fmt.Sprintf("-ERR %s\r\n", err.Error())))
+			return
+		}
+		c.Write([]byte(fmt.Sprintf("$%d\r\n%s\r\n", len(stats), stats)))
+		return
+	}
+
 	stats := SQLCache.GetCacheStats()
 	// Format as a bulk string for the client
 	resp := fmt.Sprintf("$%d\r\n%s\r\n", len(stats), stats)
@@ -100,113 +267,193 @@ func HandleSQLStats(c net.Conn) {
 }
 // --- End NEW ---
 
+// HandleSQLInvalidate processes SQLINVALIDATE <table>, dropping that table's
+// cached queries. Replies with the number of entries removed.
+func HandleSQLInvalidate(argv []string, c net.Conn) {
+	if len(argv) < 2 || argv[1] == "" {
+		c.Write([]byte("-ERR wrong number of arguments for 'sqlinvalidate' command\r\n"))
+		return
+	}
+	table := argv[1]
+
+	removed := SQLCache.InvalidateTable(table)
+	queryLog.Info("invalidate", "table", table, "invalidated", removed)
+	c.Write([]byte(fmt.Sprintf(":%d\r\n", removed)))
+}
 
-// extractSQLQuery assumes the input is the raw buffer and finds the SQL.
-// This is fragile and mimics your existing `strings.Contains`.
-// A better way would be a proper RESP parser.
-func extractSQLQuery(input string) string {
-	// A simple heuristic: find "SELECT" and return the rest.
-	// This assumes the command is just the SQL query itself.
-	// e.g. "SELECT * FROM users WHERE age > 40"
-
-	// Let's assume the server is passing the *full* RESP buffer
-	// e.g. *2\r\n$3\r\nSQL\r\n$27\r\nSELECT * FROM users WHERE age > 40\r\n
-	// Your existing code uses `strings.Split(input, "\r\n")`
-
-	parts := strings.Split(input, "\r\n")
-
-	// Let's define a new command format: "SQL <query>"
-	// RESP: *2\r\n$3\r\nSQL\r\n$<len>\r\n<query>\r\n
-	// parts[0] = *2
-	// parts[1] = $3
-	// parts[2] = SQL
-	// parts[3] = $<len>
-	// parts[4] = <query>
-	if len(parts) > 4 && (strings.EqualFold(parts[2], "SQL") || strings.Contains(parts[4], "SELECT")) {
-		// This handles "SQL <query>" or just "SELECT ..."
-		if strings.Contains(parts[4], "SELECT") {
-			return parts[4]
-		}
-		// Fallback for just "SELECT..."
-	}
-
-	// Fallback for "SELECT ..." as the first command
-	if len(parts) > 4 && strings.Contains(parts[0], "SELECT") {
-		// This is likely wrong, let's assume `input` is just the query.
-	}
-
-	// This is the most likely good assumption based on your `server.go`
-	if strings.Contains(input, "SELECT") {
-		// Find the first "SELECT" and trim
-		idx := strings.Index(strings.ToUpper(input), "SELECT")
-		if idx != -1 {
-			// Find the end of the query (e.g., \r\n or end of string)
-			query := input[idx:]
-			endIdx := strings.Index(query, "\r\n")
-			if endIdx != -1 {
-				query = query[:endIdx]
-			}
-			return strings.TrimSpace(query)
-		}
+// HandleSQLCache processes SQLCACHE PENALTY [<ms>], SQLCACHE MAXROWS [<n>],
+// SQLCACHE TOLERANCE [<n>] and SQLCACHE DUMP, which lists the cached queries.
+func HandleSQLCache(argv []string, c net.Conn) {
+	if len(argv) < 2 {
+		c.Write([]byte("-ERR unknown SQLCACHE subcommand, expected PENALTY, MAXROWS, TOLERANCE or DUMP\r\n"))
+		return
 	}
 
-	// Let's refine the "SQL" command assumption from above
-	// *2\r\n$3\r\nSQL\r\n$27\r\nSELECT * FROM users WHERE age > 40\r\n
-	if len(parts) > 4 && strings.EqualFold(parts[2], "SQL") {
-		return parts[4]
+	switch strings.ToUpper(argv[1]) {
+	case "PENALTY":
+		handleCachePenalty(argv, c)
+	case "MAXROWS":
+		handleMaxRows(argv, c)
+	case "TOLERANCE":
+		handleTolerance(argv, c)
+	case "DUMP":
+		dump := SQLCache.DumpEntries()
+		c.Write([]byte(fmt.Sprintf("$%d\r\n%s\r\n", len(dump), dump)))
+	default:
+		c.Write([]byte("-ERR unknown SQLCACHE subcommand, expected PENALTY, MAXROWS, TOLERANCE or DUMP\r\n"))
 	}
+}
 
-	// --- NEW: Fallback for SQLSTATS command ---
-	if len(parts) >= 3 && strings.EqualFold(parts[2], "SQLSTATS") {
-		return "SQLSTATS" // Not a query, but `extract` is the wrong place.
+// handleCachePenalty processes SQLCACHE PENALTY [<ms>]. With a value it sets
+// the simulated miss delay (0 disables it) and replies +OK; without one it
+// replies with the current delay in milliseconds.
+func handleCachePenalty(argv []string, c net.Conn) {
+	if len(argv) < 3 || argv[2] == "" {
+		c.Write([]byte(fmt.Sprintf(":%d\r\n", SQLCache.MissPenalty().Milliseconds())))
+		return
 	}
-	if strings.Contains(strings.ToUpper(input), "SQLSTATS") {
-		return "SQLSTATS"
+	ms, err := strconv.Atoi(argv[2])
+	if err != nil || ms < 0 {
+		c.Write([]byte("-ERR penalty must be a non-negative number of milliseconds\r\n"))
+		return
+	}
+
+	SQLCache.SetMissPenalty(time.Duration(ms) * time.Millisecond)
+	queryLog.Info("cache miss penalty set", "penalty", time.Duration(ms)*time.Millisecond)
+	c.Write([]byte("+OK\r\n"))
+}
+
+// handleMaxRows processes SQLCACHE MAXROWS [<n>]. With a value it sets how
+// many rows a reply may render (0 removes the cap) and replies +OK; without
+// one it replies with the current cap.
+func handleMaxRows(argv []string, c net.Conn) {
+	if len(argv) < 3 || argv[2] == "" {
+		c.Write([]byte(fmt.Sprintf(":%d\r\n", maxResultRows.Load())))
+		return
+	}
+	n, err := strconv.Atoi(argv[2])
+	if err != nil || n < 0 {
+		c.Write([]byte("-ERR max rows must be a non-negative integer\r\n"))
+		return
 	}
-	// --- End NEW ---
 
-	return "" // No valid SQL found
+	maxResultRows.Store(int64(n))
+	queryLog.Info("result row cap set", "rows", n)
+	c.Write([]byte("+OK\r\n"))
 }
 
-// executeOnBackingStore runs the query against the main data.
+// handleTolerance processes SQLCACHE TOLERANCE [<n>]. With a value it sets
+// how far a cached numeric bound may miss and still answer a query (0 only
+// serves exact supersets) and replies +OK; without one it replies with the
+// current tolerance.
+func handleTolerance(argv []string, c net.Conn) {
+	if len(argv) < 3 || argv[2] == "" {
+		current := strconv.FormatFloat(SQLCache.Tolerance(), 'f', -1, 64)
+		c.Write([]byte(fmt.Sprintf("$%d\r\n%s\r\n", len(current), current)))
+		return
+	}
+	tolerance, err := strconv.ParseFloat(argv[2], 64)
+	if err != nil || tolerance < 0 || math.IsInf(tolerance, 0) || math.IsNaN(tolerance) {
+		c.Write([]byte("-ERR tolerance must be a non-negative number\r\n"))
+		return
+	}
+
+	SQLCache.SetTolerance(tolerance)
+	queryLog.Info("cache numeric tolerance set", "tolerance", tolerance)
+	c.Write([]byte("+OK\r\n"))
+}
+
+// executeOnBackingStore runs the query against the main data. The results
+// record the generations of the tables read, for AddToCache.
 func executeOnBackingStore(query *QueryAST) (*Table, error) {
 	dbMutex.RLock()
 	defer dbMutex.RUnlock()
+	results, err := executeLocked(query)
+	if err != nil {
+		return nil, err
+	}
+	recordGenerations(query, results)
+	return results, nil
+}
 
-	table, exists := BackingDatabase[query.FromTable]
-	if !exists {
-		return nil, fmt.Errorf("table '%s' not found", query.FromTable)
+// executeLocked implements executeOnBackingStore for callers that already
+// hold dbMutex, such as subqueries.
+func executeLocked(query *QueryAST) (*Table, error) {
+	where, err := bindSubqueries(query.Where)
+	if err != nil {
+		return nil, err
+	}
+
+	var sourceRows []Row
+	var sourceCols []string
+	if query.Join != nil {
+		// WHERE is applied to the joined rows below
+		sourceRows, sourceCols, err = joinTables(query)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		table, exists := lookupTable(query.FromTable)
+		if !exists {
+			return nil, fmt.Errorf("table '%s' not found", query.FromTable)
+		}
+		sourceRows, sourceCols = table.Rows, table.Columns
+
+		// An equality on an indexed column only needs to look at the matching rows
+		if candidates, ok := table.indexedRows(where); ok {
+			queryLog.Debug("using index",
+				"table", table.Name,
+				"column", where.Cond.Column,
+				"candidates", len(candidates),
+				"rows", len(table.Rows),
+			)
+			sourceRows = candidates
+		}
 	}
 
 	var resultRows []Row
 
 	// Filter rows
-	for _, row := range table.Rows {
-		if query.Where == nil || checkCondition(row, query.Where) {
+	for _, row := range sourceRows {
+		if where == nil || checkCondition(row, where) {
 			resultRows = append(resultRows, row)
 		}
 	}
 
-	// Apply column selection
-	finalRows := []Row{}
-	for _, row := range resultRows {
-		if query.SelectColumns[0] == "*" {
-			finalRows = append(finalRows, row)
-		} else {
-			newRow := make(Row)
-			for _, col := range query.SelectColumns {
-				if val, ok := row[col]; ok {
-					newRow[col] = val
+	// Collapse the filtered rows into one row per group, keeping the
+	// groups HAVING accepts
+	if isGroupedQuery(query) {
+		resultRows = groupRows(resultRows, query)
+		if query.Having != nil {
+			kept := resultRows[:0]
+			for _, row := range resultRows {
+				if checkCondition(row, query.Having) {
+					kept = append(kept, row)
 				}
 			}
-			finalRows = append(finalRows, newRow)
+			resultRows = kept
 		}
 	}
 
+	// Sort before projection so ORDER BY can use columns that aren't selected
+	resultRows = sortRows(resultRows, query.OrderBy)
+
+	// Rows are maps, so the output column order lives only in Columns: the
+	// SELECT list as written (SELECT age, name, id keeps that order), or the
+	// schema's order for *. Formatters and semantic hits follow Columns.
 	finalCols := query.SelectColumns
 	if finalCols[0] == "*" {
-		finalCols = table.Columns
+		finalCols = sourceCols
+	}
+
+	// Compute the select-list expressions, then apply column selection,
+	// DISTINCT and finally LIMIT/OFFSET
+	resultRows = computeExpressions(resultRows, query.Expressions)
+	finalRows := projectRows(resultRows, query.SelectColumns)
+	if query.Distinct {
+		finalRows = distinctRows(finalRows, finalCols)
 	}
+	finalRows = paginateRows(finalRows, query)
 
 	return &Table{
 		Name:    "results",
@@ -215,36 +462,92 @@ func executeOnBackingStore(query *QueryAST) (*Table, error) {
 	}, nil
 }
 
-// formatResults converts a Table into a RESP bulk string.
+// projectRows keeps only the selected columns of each row. "*" keeps every column.
+func projectRows(rows []Row, columns []string) []Row {
+	finalRows := []Row{}
+	for _, row := range rows {
+		if columns[0] == "*" {
+			finalRows = append(finalRows, row)
+		} else {
+			newRow := make(Row)
+			for _, col := range columns {
+				if val, ok := row[col]; ok {
+					newRow[col] = val
+				}
+			}
+			finalRows = append(finalRows, newRow)
+		}
+	}
+	return finalRows
+}
+
+// distinctRows keeps the first row for each distinct combination of values
+// in columns, preserving order.
+func distinctRows(rows []Row, columns []string) []Row {
+	seen := make(map[string]bool, len(rows))
+	unique := make([]Row, 0, len(rows))
+	for _, row := range rows {
+		key := groupKey(row, columns)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		unique = append(unique, row)
+	}
+	return unique
+}
+
+// formatResults converts a Table into a RESP bulk string. headers holds the
+// display name of each of the table's columns (e.g. AS aliases); nil uses
+// the column names themselves. A query that matches nothing still gets its
+// header and "(0 rows)"; the nil reply is kept for a missing table.
 // --- NEW: Improved formatting ---
-func formatResults(table *Table) string {
-	if table == nil || len(table.Rows) == 0 {
-		return "$-1\r\n" // Nil bulk string (empty result)
+func formatResults(table *Table, headers []string) string {
+	if table == nil {
+		return "$-1\r\n" // Nil bulk string (no result at all)
+	}
+	if headers == nil {
+		headers = table.Columns
+	}
+
+	// Only the first maxResultRows rows are rendered; the footer says so
+	rows := table.Rows
+	if limit := maxResultRows.Load(); limit > 0 && int64(len(rows)) > limit {
+		rows = rows[:limit]
 	}
 
 	var sb strings.Builder
 
 	// Calculate column widths
-	colWidths := make(map[string]int)
-	for _, col := range table.Columns {
-		colWidths[col] = len(col) // Start with header length
+	colWidths := make([]int, len(table.Columns))
+	for i := range table.Columns {
+		colWidths[i] = len(headers[i]) // Start with header length
 	}
 
-	for _, row := range table.Rows {
-		for _, col := range table.Columns {
-			valStr := fmt.Sprintf("%v", row[col])
-			if len(valStr) > colWidths[col] {
-				colWidths[col] = len(valStr)
+	for _, row := range rows {
+		for i, col := range table.Columns {
+			valStr := formatValue(row[col])
+			if len(valStr) > colWidths[i] {
+				colWidths[i] = len(valStr)
 			}
 		}
 	}
 
+	// Numbers are right-aligned so their digits line up, text left-aligned
+	numeric := numericColumns(rows, table.Columns)
+	align := func(i int, s string) string {
+		if numeric[i] {
+			return fmt.Sprintf("%*s", colWidths[i], s)
+		}
+		return fmt.Sprintf("%-*s", colWidths[i], s)
+	}
+
 	// --- Print Header ---
 	var headerLine []string
 	var separatorLine []string
-	for _, col := range table.Columns {
-		width := colWidths[col]
-		headerLine = append(headerLine, fmt.Sprintf("%-*s", width, col))
+	for i := range table.Columns {
+		width := colWidths[i]
+		headerLine = append(headerLine, align(i, headers[i]))
 		separatorLine = append(separatorLine, strings.Repeat("-", width))
 	}
 	sb.WriteString(strings.Join(headerLine, " | "))
@@ -253,11 +556,10 @@ func formatResults(table *Table) string {
 	sb.WriteString("\n")
 
 	// --- Print Rows ---
-	for _, row := range table.Rows {
+	for _, row := range rows {
 		var rowLine []string
-		for _, col := range table.Columns {
-			width := colWidths[col]
-			rowLine = append(rowLine, fmt.Sprintf("%-*v", width, row[col]))
+		for i, col := range table.Columns {
+			rowLine = append(rowLine, align(i, formatValue(row[col])))
 		}
 		sb.WriteString(strings.Join(rowLine, " | "))
 		sb.WriteString("\n")
@@ -265,40 +567,183 @@ func formatResults(table *Table) string {
 
 	tableString := sb.String()
 	// Add row count
-	tableString += fmt.Sprintf("\n(%d rows)\n", len(table.Rows))
+	if len(rows) < len(table.Rows) {
+		tableString += fmt.Sprintf("\n(%d of %d rows, truncated)\n", len(rows), len(table.Rows))
+	} else {
+		tableString += fmt.Sprintf("\n(%d rows)\n", len(table.Rows))
+	}
 
 	return fmt.Sprintf("$%d\r\n%s\r\n", len(tableString), tableString)
 }
 
+// formatValue renders a cell for formatResults. A missing column (nil) is NULL.
+func formatValue(val interface{}) string {
+	if val == nil {
+		return "NULL"
+	}
+	return fmt.Sprintf("%v", val)
+}
+
+// numericColumns reports, for each column, whether every value in rows is a
+// number (NULLs aside, and at least one value is), going by the values'
+// types rather than the schema so computed columns like COUNT(*) count too.
+func numericColumns(rows []Row, columns []string) []bool {
+	numeric := make([]bool, len(columns))
+	for i, col := range columns {
+		seen := false
+		numeric[i] = true
+		for _, row := range rows {
+			val := row[col]
+			if val == nil {
+				continue
+			}
+			if _, ok := asFloat(val); !ok {
+				numeric[i] = false
+				break
+			}
+			seen = true
+		}
+		numeric[i] = numeric[i] && seen
+	}
+	return numeric
+}
+
 // --- Semantic Logic ---
 
-// isQuerySubset checks if newQuery is a semantic subset of cachedQuery.
-func isQuerySubset(newQuery, cachedQuery *QueryAST) bool {
+// isQuerySubset checks if newQuery is a semantic subset of cachedQuery,
+// with numeric bounds allowed to miss by up to tolerance (see
+// SQLCacheConfig.Tolerance).
+func isQuerySubset(newQuery, cachedQuery *QueryAST, tolerance float64) bool {
 	if newQuery.FromTable != cachedQuery.FromTable {
 		return false
 	}
 
-	// Check select columns (new must be subset of cached)
+	// Joined rows can only be filtered down for the very same join
+	if newQuery.Join != nil || cachedQuery.Join != nil {
+		if newQuery.Join == nil || cachedQuery.Join == nil || *newQuery.Join != *cachedQuery.Join ||
+			newQuery.FromAlias != cachedQuery.FromAlias {
+			return false
+		}
+	}
+
+	// A LIMIT/OFFSET result is only a window of the matching rows, so it can't be
+	// filtered down for anything else. An unlimited cached result can still serve
+	// a limited query, since paginateRows runs after filtering.
+	if cachedQuery.HasLimit || cachedQuery.Offset > 0 {
+		return false
+	}
+
+	// Pages are cut after a stable sort of the filtered rows, so they only
+	// line up with the pages the backing store (or another superset) would
+	// cut if the cached rows are in table order, or already sorted the same
+	// way. Otherwise two pages served from differently ordered supersets
+	// could overlap.
+	if (newQuery.HasLimit || newQuery.Offset > 0) && cachedQuery.OrderBy != nil &&
+		(newQuery.OrderBy == nil || *newQuery.OrderBy != *cachedQuery.OrderBy) {
+		return false
+	}
+
+	// Grouped rows no longer correspond to individual table rows, and a grouped
+	// query needs every matching row, so neither side can use the semantic path.
+	if isGroupedQuery(cachedQuery) || isGroupedQuery(newQuery) {
+		return false
+	}
+
+	// A subquery's values are only known once it runs, so the conditions
+	// can't be compared
+	if hasSubquery(newQuery.Where) || hasSubquery(cachedQuery.Where) {
+		return false
+	}
+
+	// A DISTINCT result has already dropped repeated rows, so filtering it
+	// could miss rows (or lose duplicates) the new query should return.
+	if cachedQuery.Distinct {
+		return false
+	}
+
+	// Check select columns (new must be subset of cached). FindSemanticHit
+	// projects the served rows down to exactly the new query's columns.
 	if cachedQuery.SelectColumns[0] != "*" {
+		// "*" needs every table column, which a cached projection doesn't hold
+		if newQuery.SelectColumns[0] == "*" {
+			return false
+		}
 		// If cached isn't "*", new must have columns <= cached
 		colMap := make(map[string]bool)
 		for _, col := range cachedQuery.SelectColumns {
 			colMap[col] = true
 		}
 		for _, col := range newQuery.SelectColumns {
-			if col != "*" && !colMap[col] {
+			if !colMap[col] {
 				return false // New query asks for a column not in cache
 			}
 		}
 	}
 	// If cached is "*", new can be anything (including "*" or "col1, col2")
 
+	// The cached rows must also carry every column the new query filters
+	// and sorts by, or filtering would treat them as NULL and drop the rows
+	if cachedQuery.SelectColumns[0] != "*" {
+		if !whereColumnsIn(newQuery.Where, cachedQuery.SelectColumns) {
+			return false
+		}
+		if newQuery.OrderBy != nil && !containsColumn(cachedQuery.SelectColumns, newQuery.OrderBy.Column) {
+			return false
+		}
+	}
+
 	// Check WHERE clause (new must be stricter than cached)
-	return isConditionSubset(newQuery.Where, cachedQuery.Where)
+	return isConditionSubset(newQuery.Where, cachedQuery.Where, tolerance)
+}
+
+// whereColumnsIn reports whether every column the WHERE tree reads is one
+// of cols.
+func whereColumnsIn(node *WhereNode, cols []string) bool {
+	if node == nil {
+		return true
+	}
+	if node.Cond != nil {
+		if !containsColumn(cols, node.Cond.Column) {
+			return false
+		}
+		if node.Cond.ValueColumn != "" && !containsColumn(cols, node.Cond.ValueColumn) {
+			return false
+		}
+	}
+	return whereColumnsIn(node.Left, cols) && whereColumnsIn(node.Right, cols)
 }
 
-// isConditionSubset is the core semantic logic.
-func isConditionSubset(newCond, cachedCond *WhereCondition) bool {
+func isGroupedQuery(query *QueryAST) bool {
+	return len(query.GroupBy) > 0 || len(query.Aggregates) > 0
+}
+
+func containsColumn(cols []string, col string) bool {
+	for _, c := range cols {
+		if c == col {
+			return true
+		}
+	}
+	return false
+}
+
+// isConditionSubset is the core semantic logic: it reports whether every row
+// matching newCond is guaranteed to also match cachedCond. Compound
+// conditions are broken down with these rules, each of which is sound on its
+// own (a false answer only means "not provably a subset"):
+//
+//	new  ⊆ X AND Y  if new ⊆ X and new ⊆ Y
+//	A OR B  ⊆ cached if A ⊆ cached and B ⊆ cached
+//	A AND B ⊆ cached if A ⊆ cached or B ⊆ cached
+//	new  ⊆ X OR Y   if new ⊆ X or new ⊆ Y
+//
+// The new query's OR is split before the cached one so that e.g.
+// "a OR b" still fits inside "b OR a". NOT nodes are only matched when the
+// two expressions are identical; otherwise they are never proven a subset
+// (though "A AND NOT B" still fits wherever A does).
+//
+// A non-zero tolerance widens the cached numeric bounds by that much, so
+// the answer becomes "a subset, give or take rows near the bounds".
+func isConditionSubset(newCond, cachedCond *WhereNode, tolerance float64) bool {
 	if cachedCond == nil {
 		// Cached query was "SELECT * FROM table"
 		// New query is always a subset (e.g., "... WHERE age > 50")
@@ -312,40 +757,119 @@ func isConditionSubset(newCond, cachedCond *WhereCondition) bool {
 		return false
 	}
 
-	// Both queries have WHERE clauses.
-	if newCond.Column != cachedCond.Column {
-		return false // Conditions are on different columns
+	// Identical expressions are trivially a subset of each other.
+	if newCond.String() == cachedCond.String() {
+		return true
 	}
 
-	// Try to compare as integers
-	newVal, newIsInt := newCond.GetAsInt()
-	cachedVal, cachedIsInt := cachedCond.GetAsInt()
+	// cached = A AND B: the new rows must satisfy both A and B.
+	if cachedCond.Op == "AND" {
+		return isConditionSubset(newCond, cachedCond.Left, tolerance) && isConditionSubset(newCond, cachedCond.Right, tolerance)
+	}
 
-	if newIsInt && cachedIsInt {
-		// This is where we implement your example:
-		// new = "age > 50", cached = "age > 40"
-		if newCond.Operator == ">" && cachedCond.Operator == ">" {
-			return newVal >= cachedVal // 50 >= 40 -> true
+	// new = A OR B: rows may come from either side, so both must fit,
+	// e.g. new "cpu_load > 90 OR cpu_load < 5", cached "cpu_load > 80 OR cpu_load < 10".
+	if newCond.Op == "OR" {
+		return isConditionSubset(newCond.Left, cachedCond, tolerance) && isConditionSubset(newCond.Right, cachedCond, tolerance)
+	}
+
+	// new = A AND B bounding one column: the bounds together form a range
+	// that may fit where neither bound does alone,
+	// e.g. new "age > 30 AND age < 50", cached "age BETWEEN 20 AND 60".
+	if newCond.Op == "AND" && cachedCond.Cond != nil {
+		if newRange, ok := conjunctionInterval(newCond, cachedCond.Cond.Column); ok {
+			if cachedRange, ok := conditionInterval(cachedCond.Cond); ok && cachedRange.widen(tolerance).containsInterval(newRange) {
+				return true
+			}
 		}
-		// new = "age < 30", cached = "age < 40"
-		if newCond.Operator == "<" && cachedCond.Operator == "<" {
-			return newVal <= cachedVal // 30 <= 40 -> true
+	}
+
+	// new = A AND B: it is enough for either side to fit inside the cached condition,
+	// e.g. new "cpu_load > 90 AND status = 'ERROR'", cached "cpu_load > 80".
+	if newCond.Op == "AND" {
+		return isConditionSubset(newCond.Left, cachedCond, tolerance) || isConditionSubset(newCond.Right, cachedCond, tolerance)
+	}
+
+	// cached = X OR Y: fitting inside either side is enough,
+	// e.g. new "cpu_load > 90", cached "cpu_load > 80 OR status = 'ERROR'".
+	if cachedCond.Op == "OR" {
+		return isConditionSubset(newCond, cachedCond.Left, tolerance) || isConditionSubset(newCond, cachedCond.Right, tolerance)
+	}
+
+	if newCond.Cond == nil || cachedCond.Cond == nil {
+		return false
+	}
+
+	return isPredicateSubset(newCond.Cond, cachedCond.Cond, tolerance)
+}
+
+// isPredicateSubset compares two single "col op val" predicates.
+func isPredicateSubset(newCond, cachedCond *WhereCondition, tolerance float64) bool {
+	if newCond.Column != cachedCond.Column {
+		return false // Conditions are on different columns
+	}
+
+	// new = "col IN (a, b)": every element must fit, as if it were "col = a" etc.
+	if newCond.Operator == "IN" {
+		for _, v := range newCond.Values {
+			eq := &WhereCondition{Column: newCond.Column, Operator: "=", Value: v}
+			if !isPredicateSubset(eq, cachedCond, tolerance) {
+				return false
+			}
 		}
-		// new = "age = 55", cached = "age > 50"
-		if newCond.Operator == "=" && cachedCond.Operator == ">" {
-			return newVal > cachedVal // 55 > 50 -> true
+		return true
+	}
+
+	// cached = "col IN (a, b)": only an equality on one of its elements fits
+	if cachedCond.Operator == "IN" {
+		if newCond.Operator != "=" {
+			return false
 		}
-		// new = "age = 45", cached = "age < 50"
-		if newCond.Operator == "=" && cachedCond.Operator == "<" {
-			return newVal < cachedVal // 45 < 50 -> true
+		for _, v := range cachedCond.Values {
+			if literalsEqual(newCond.Value, v) {
+				return true
+			}
 		}
-		// ... more rules could be added here ...
+		return false
+	}
+
+	// BETWEEN on either side: compare the numeric intervals
+	if newCond.Operator == "BETWEEN" || cachedCond.Operator == "BETWEEN" {
+		return isNumericSubset(newCond, cachedCond, tolerance)
+	}
+
+	// NULL checks only fit inside an identical condition, which
+	// isConditionSubset already accepted by comparing the expressions
+	if isNullCheck(newCond) || isNullCheck(cachedCond) {
+		return false
+	}
+
+	// LIKE patterns get their own, conservative containment rules
+	if newCond.Operator == "LIKE" || cachedCond.Operator == "LIKE" {
+		return isLikeSubset(newCond, cachedCond)
+	}
+
+	// Try to compare as numbers (ints and floats alike)
+	_, newIsNum := newCond.GetAsFloat()
+	_, cachedIsNum := cachedCond.GetAsFloat()
+
+	if newIsNum && cachedIsNum {
+		return isNumericSubset(newCond, cachedCond, tolerance)
 	}
 
 	// Fallback for string comparison
 	if newCond.Operator == "=" && cachedCond.Operator == "=" {
 		return newCond.Value == cachedCond.Value
 	}
+	if cachedCond.Operator == "!=" {
+		// "status = 'OK'" or "status != 'OK'" both fit inside "status != 'OK'"
+		if newCond.Operator == "=" {
+			return newCond.Value != cachedCond.Value
+		}
+		if newCond.Operator == "!=" {
+			return newCond.Value == cachedCond.Value
+		}
+	}
 	
 	// --- NEW: Handle subset for string equals ---
 	// e.g. newCond = "status = 'ERROR'"
@@ -362,8 +886,145 @@ func isConditionSubset(newCond, cachedCond *WhereCondition) bool {
 	return false
 }
 
+// isNumericSubset reports whether every number matching newCond also
+// matches cachedCond, once cachedCond's bounds are widened by tolerance.
+// Values are compared as float64 with open/closed bounds, so no assumption
+// is made that a column only holds integers.
+// With a tolerance above 0 the answer is only approximate: rows in the
+// widened band (e.g. cpu_load 79 for new "> 78" and cached "> 80") match
+// newCond but were never in the cached superset, and re-filtering the
+// cached rows can't bring them back, so such a hit silently drops them.
+// e.g. new = "age >= 50",            cached = "age > 40"  -> true
+//      new = "age BETWEEN 30 AND 50", cached = "age < 60" -> true
+//      new = "age > 40",             cached = "age >= 41" -> false (40.5 fits new only)
+func isNumericSubset(newCond, cachedCond *WhereCondition, tolerance float64) bool {
+	if cachedCond.Operator == "!=" {
+		// The new condition must never produce the excluded value.
+		excluded, ok := cachedCond.GetAsFloat()
+		if !ok {
+			return false
+		}
+		if newCond.Operator == "!=" {
+			val, ok := newCond.GetAsFloat()
+			return ok && val == excluded
+		}
+		newRange, ok := conditionInterval(newCond)
+		return ok && !newRange.containsValue(excluded)
+	}
+
+	newRange, ok := conditionInterval(newCond)
+	if !ok {
+		return false
+	}
+	cachedRange, ok := conditionInterval(cachedCond)
+	return ok && cachedRange.widen(tolerance).containsInterval(newRange)
+}
+
+// numericInterval is the set of numbers a condition matches, e.g. "age > 40"
+// is (40, +Inf) and "age BETWEEN 1 AND 5" is [1, 5].
+type numericInterval struct {
+	low, high         float64
+	lowOpen, highOpen bool // true if the bound itself is excluded
+}
+
+// conditionInterval expresses a numeric condition as the interval it matches.
+func conditionInterval(cond *WhereCondition) (numericInterval, bool) {
+	if cond.Operator == "BETWEEN" {
+		low, high, ok := cond.GetBounds()
+		return numericInterval{low: low, high: high}, ok
+	}
+
+	val, isNum := cond.GetAsFloat()
+	if !isNum {
+		return numericInterval{}, false
+	}
+	inf := math.Inf(1)
+	switch cond.Operator {
+	case "=":
+		return numericInterval{low: val, high: val}, true
+	case ">":
+		return numericInterval{low: val, high: inf, lowOpen: true, highOpen: true}, true
+	case ">=":
+		return numericInterval{low: val, high: inf, highOpen: true}, true
+	case "<":
+		return numericInterval{low: -inf, high: val, lowOpen: true, highOpen: true}, true
+	case "<=":
+		return numericInterval{low: -inf, high: val, lowOpen: true}, true
+	}
+	return numericInterval{}, false
+}
+
+// conjunctionInterval intersects the numeric bounds an AND tree places on
+// column, e.g. "age > 30 AND status = 'OK' AND age <= 50" gives (30, 50].
+// OR and NOT subtrees are ignored, which only widens the result. It reports
+// false if nothing in the tree bounds the column.
+func conjunctionInterval(node *WhereNode, column string) (numericInterval, bool) {
+	if node == nil {
+		return numericInterval{}, false
+	}
+	if node.Cond != nil {
+		if node.Cond.Column != column || node.Cond.ValueColumn != "" {
+			return numericInterval{}, false
+		}
+		return conditionInterval(node.Cond)
+	}
+	if node.Op != "AND" {
+		return numericInterval{}, false
+	}
+
+	left, leftOk := conjunctionInterval(node.Left, column)
+	right, rightOk := conjunctionInterval(node.Right, column)
+	switch {
+	case leftOk && rightOk:
+		return left.intersect(right), true
+	case leftOk:
+		return left, true
+	case rightOk:
+		return right, true
+	}
+	return numericInterval{}, false
+}
+
+// intersect returns the values lying in both intervals. The result may be
+// empty (low above high).
+func (in numericInterval) intersect(other numericInterval) numericInterval {
+	result := in
+	if other.low > result.low || (other.low == result.low && other.lowOpen) {
+		result.low, result.lowOpen = other.low, other.lowOpen
+	}
+	if other.high < result.high || (other.high == result.high && other.highOpen) {
+		result.high, result.highOpen = other.high, other.highOpen
+	}
+	return result
+}
+
+func (in numericInterval) containsValue(v float64) bool {
+	if v < in.low || v > in.high {
+		return false
+	}
+	return !(v == in.low && in.lowOpen) && !(v == in.high && in.highOpen)
+}
+
+// widen moves both (finite) bounds outwards by by.
+func (in numericInterval) widen(by float64) numericInterval {
+	in.low -= by
+	in.high += by
+	return in
+}
+
+// containsInterval reports whether every value of other also lies in in.
+func (in numericInterval) containsInterval(other numericInterval) bool {
+	if other.low < in.low || (other.low == in.low && in.lowOpen && !other.lowOpen) {
+		return false
+	}
+	if other.high > in.high || (other.high == in.high && in.highOpen && !other.highOpen) {
+		return false
+	}
+	return true
+}
+
 // filterResultsFromSuperset takes a cached superset and applies the new, stricter filter.
-func filterResultsFromSuperset(superset *Table, newCondition *WhereCondition) *Table {
+func filterResultsFromSuperset(superset *Table, newCondition *WhereNode) *Table {
 	if newCondition == nil {
 		return superset // Should not happen if isConditionSubset is correct
 	}
@@ -382,17 +1043,222 @@ func filterResultsFromSuperset(superset *Table, newCondition *WhereCondition) *T
 	}
 }
 
-// checkCondition evaluates a row against a WHERE condition.
-func checkCondition(row Row, cond *WhereCondition) bool {
-	if cond == nil {
+// literalsEqual compares two literal values numerically when both are
+// numbers (so "007" equals "7" and "2.50" equals "2.5"), and as plain
+// strings otherwise.
+func literalsEqual(a, b string) bool {
+	aNum, aOk := parseNumber(a)
+	bNum, bOk := parseNumber(b)
+	if aOk && bOk {
+		return aNum == bNum
+	}
+	return a == b
+}
+
+// sortRows returns a stably sorted copy of rows according to the ORDER BY clause.
+// The input slice is never reordered, since it may belong to a cached table.
+func sortRows(rows []Row, orderBy *OrderByClause) []Row {
+	if orderBy == nil || len(rows) < 2 {
+		return rows
+	}
+
+	sorted := make([]Row, len(rows))
+	copy(sorted, rows)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		cmp := compareValues(sorted[i][orderBy.Column], sorted[j][orderBy.Column])
+		if orderBy.Desc {
+			return cmp > 0
+		}
+		return cmp < 0
+	})
+	return sorted
+}
+
+// paginateRows applies the query's OFFSET and LIMIT to already sorted rows.
+func paginateRows(rows []Row, query *QueryAST) []Row {
+	if query.Offset >= len(rows) {
+		return nil
+	}
+	rows = rows[query.Offset:]
+	if query.HasLimit && query.Limit < len(rows) {
+		rows = rows[:query.Limit]
+	}
+	return rows
+}
+
+// compareValues orders two row values: NULLs first, numbers numerically,
+// everything else lexically.
+func compareValues(a, b interface{}) int {
+	// NULLs (missing columns) sort before every value
+	if a == nil || b == nil {
+		switch {
+		case a == nil && b == nil:
+			return 0
+		case a == nil:
+			return -1
+		}
+		return 1
+	}
+	aInt, aIsInt := a.(int)
+	bInt, bIsInt := b.(int)
+	if aIsInt && bIsInt {
+		switch {
+		case aInt < bInt:
+			return -1
+		case aInt > bInt:
+			return 1
+		}
+		return 0
+	}
+	aNum, aIsNum := asFloat(a)
+	bNum, bIsNum := asFloat(b)
+	if aIsNum && bIsNum {
+		switch {
+		case aNum < bNum:
+			return -1
+		case aNum > bNum:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(fmt.Sprintf("%v", a), fmt.Sprintf("%v", b))
+}
+
+// asFloat converts a numeric row value (int or float64) to float64.
+func asFloat(val interface{}) (float64, bool) {
+	switch v := val.(type) {
+	case int:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// checkCondition evaluates a row against a WHERE expression tree. Only rows
+// for which the whole condition is true pass (see evalCondition).
+func checkCondition(row Row, node *WhereNode) bool {
+	if node == nil {
 		return true // No condition means the row passes
 	}
-	
+	return evalCondition(row, node) == condTrue
+}
+
+// condResult is the three-valued outcome of a condition, as in SQL: a
+// comparison with NULL is neither true nor false but unknown.
+type condResult int
+
+const (
+	condFalse condResult = iota
+	condTrue
+	condUnknown
+)
+
+// evalCondition evaluates a WHERE tree with SQL's three-valued logic, so
+// that NOT of an unknown comparison stays unknown: a row without cpu_load
+// matches neither "cpu_load > 50" nor "NOT cpu_load > 50".
+func evalCondition(row Row, node *WhereNode) condResult {
+	switch node.Op {
+	case "AND":
+		left, right := evalCondition(row, node.Left), evalCondition(row, node.Right)
+		switch {
+		case left == condFalse || right == condFalse:
+			return condFalse
+		case left == condTrue && right == condTrue:
+			return condTrue
+		}
+		return condUnknown
+	case "OR":
+		left, right := evalCondition(row, node.Left), evalCondition(row, node.Right)
+		switch {
+		case left == condTrue || right == condTrue:
+			return condTrue
+		case left == condFalse && right == condFalse:
+			return condFalse
+		}
+		return condUnknown
+	case "NOT":
+		switch evalCondition(row, node.Left) {
+		case condTrue:
+			return condFalse
+		case condFalse:
+			return condTrue
+		}
+		return condUnknown
+	}
+
+	if !isNullCheck(node.Cond) && comparesNull(row, node.Cond) {
+		return condUnknown
+	}
+	if checkPredicate(row, node.Cond) {
+		return condTrue
+	}
+	return condFalse
+}
+
+// comparesNull reports whether a comparison involves a NULL: its column,
+// or the column it is compared against, is missing from the row or nil.
+func comparesNull(row Row, cond *WhereCondition) bool {
+	if val, ok := row[cond.Column]; !ok || val == nil {
+		return true
+	}
+	if cond.ValueColumn != "" {
+		if val, ok := row[cond.ValueColumn]; !ok || val == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPredicate evaluates a row against a single "col op val" condition.
+// A column missing from the row (or nil, as an aggregate over no values
+// is) is NULL: only IS NULL matches it.
+func checkPredicate(row Row, cond *WhereCondition) bool {
 	val, ok := row[cond.Column]
+	ok = ok && val != nil
+	switch cond.Operator {
+	case "IS NULL":
+		return !ok
+	case "IS NOT NULL":
+		return ok
+	}
 	if !ok {
 		return false // Column doesn't exist in row
 	}
 
+	// Column-to-column: compare against the other column's value as if it
+	// were a literal. NULL compares false, like any other comparison.
+	if cond.ValueColumn != "" {
+		other, ok := row[cond.ValueColumn]
+		if !ok {
+			return false
+		}
+		literal := *cond
+		literal.ValueColumn = ""
+		literal.Value = fmt.Sprintf("%v", other)
+		return checkPredicate(row, &literal)
+	}
+
+	if cond.Operator == "BETWEEN" {
+		rowVal, rowIsNum := asFloat(val)
+		low, high, ok := cond.GetBounds()
+		return rowIsNum && ok && rowVal >= low && rowVal <= high
+	}
+
+	if cond.Operator == "IN" {
+		rowValStr := fmt.Sprintf("%v", val)
+		if cond.valueSet != nil {
+			_, found := cond.valueSet[literalKey(rowValStr)]
+			return found
+		}
+		for _, v := range cond.Values {
+			if literalsEqual(rowValStr, v) {
+				return true
+			}
+		}
+		return false
+	}
+
 	// Try integer comparison
 	condVal, condIsInt := cond.GetAsInt()
 	rowVal, rowIsInt := val.(int)
@@ -405,14 +1271,54 @@ func checkCondition(row Row, cond *WhereCondition) bool {
 			return rowVal < condVal
 		case "=":
 			return rowVal == condVal
+		case ">=":
+			return rowVal >= condVal
+		case "<=":
+			return rowVal <= condVal
+		case "!=":
+			return rowVal != condVal
+		}
+	}
+
+	// Either side has a fractional part: compare as floats
+	condNum, condIsNum := cond.GetAsFloat()
+	rowNum, rowIsNum := asFloat(val)
+
+	if condIsNum && rowIsNum {
+		switch cond.Operator {
+		case ">":
+			return rowNum > condNum
+		case "<":
+			return rowNum < condNum
+		case "=":
+			return rowNum == condNum
+		case ">=":
+			return rowNum >= condNum
+		case "<=":
+			return rowNum <= condNum
+		case "!=":
+			return rowNum != condNum
 		}
 	}
 
-	// Try string comparison
+	// Try string comparison (lexical ordering for the range operators)
 	condValStr := cond.Value
 	rowValStr := fmt.Sprintf("%v", val)
-	if cond.Operator == "=" {
+	switch cond.Operator {
+	case "=":
 		return rowValStr == condValStr
+	case "!=":
+		return rowValStr != condValStr
+	case ">":
+		return rowValStr > condValStr
+	case "<":
+		return rowValStr < condValStr
+	case ">=":
+		return rowValStr >= condValStr
+	case "<=":
+		return rowValStr <= condValStr
+	case "LIKE":
+		return matchLike(cond, rowValStr)
 	}
 
 	return false // Unsupported operation
@@ -0,0 +1,764 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNotIsUnknownForNull(t *testing.T) {
+	resetSQL(t)
+	mustSQL(t, "INSERT INTO server_logs (id, server_name, status) VALUES (2001, 'web-04', 'OK')")
+	mustSQL(t, "INSERT INTO server_logs (id, server_name, status) VALUES (2002, 'web-05', 'DOWN')")
+
+	cases := []struct {
+		where string
+		want  []interface{} // ids among 2001 and 2002 that match
+	}{
+		{"cpu_load > 50", nil},
+		{"NOT cpu_load > 50", nil},
+		{"NOT (cpu_load BETWEEN 20 AND 90)", nil},
+		{"cpu_load IS NULL", []interface{}{2001, 2002}},
+		{"NOT cpu_load IS NULL", nil},
+		// unknown AND true is unknown, unknown AND false is false
+		{"NOT (cpu_load > 50 AND status = 'OK')", []interface{}{2002}},
+		// unknown OR true is true
+		{"cpu_load > 50 OR status = 'DOWN'", []interface{}{2002}},
+		{"NOT (cpu_load > 50 OR status = 'DOWN')", nil},
+	}
+	for _, c := range cases {
+		results := queryRows(t, "SELECT id FROM server_logs WHERE id > 2000 AND ("+c.where+")")
+		if got := column(results, "id"); !reflect.DeepEqual(got, c.want) && !(len(got) == 0 && len(c.want) == 0) {
+			t.Errorf("WHERE %s: got ids %v, want %v", c.where, got, c.want)
+		}
+	}
+}
+
+func TestToleranceWidensNumericSubset(t *testing.T) {
+	where := func(cond string) *WhereCondition {
+		ast, err := ParseSQL("SELECT * FROM t WHERE " + cond)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ast.Where.Cond
+	}
+	cases := []struct {
+		newCond, cachedCond string
+		tolerance           float64
+		want                bool
+	}{
+		{"a > 78", "a > 80", 0, false},
+		{"a > 78", "a > 80", 5, true},
+		{"a > 74", "a > 80", 5, false},
+		{"a < 105", "a < 100", 5, true},
+		{"a BETWEEN 18 AND 62", "a BETWEEN 20 AND 60", 2, true},
+		{"a BETWEEN 18 AND 62", "a BETWEEN 20 AND 60", 1, false},
+	}
+	for _, c := range cases {
+		if got := isNumericSubset(where(c.newCond), where(c.cachedCond), c.tolerance); got != c.want {
+			t.Errorf("%s within %s ±%v = %v, want %v", c.newCond, c.cachedCond, c.tolerance, got, c.want)
+		}
+	}
+}
+
+func TestCompoundWhere(t *testing.T) {
+	resetSQL(t)
+
+	cases := []struct {
+		where string
+		want  []interface{}
+	}{
+		{"cpu_load > 80 AND status = 'WARNING'", []interface{}{1002, 1003, 1006, 1008, 1009, 1012, 1014}},
+		{"status = 'ERROR' OR cpu_load < 20", []interface{}{1007, 1010, 1013}},
+		{"status = 'ERROR' OR cpu_load < 20 AND server_name = 'web-01'", []interface{}{1007, 1013}},
+		{"(status = 'ERROR' OR cpu_load < 20) AND server_name = 'cache-01'", []interface{}{1010}},
+	}
+	for _, c := range cases {
+		results := queryRows(t, "SELECT id FROM server_logs WHERE "+c.where)
+		if got := column(results, "id"); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("WHERE %s: got ids %v, want %v", c.where, got, c.want)
+		}
+	}
+}
+
+func TestLimitedResultNeverServesMoreRows(t *testing.T) {
+	resetSQL(t)
+
+	cacheOutcome(t, "SELECT * FROM server_logs WHERE cpu_load > 80 LIMIT 5")
+	if got := cacheOutcome(t, "SELECT * FROM server_logs WHERE cpu_load > 90"); got != "miss" {
+		t.Errorf("unlimited query after a cached LIMIT 5 was a %s hit", got)
+	}
+	if got := cacheOutcome(t, "SELECT * FROM server_logs WHERE cpu_load > 95 LIMIT 5"); got != "semantic" {
+		t.Errorf("LIMIT 5 within the cached unlimited cpu_load > 90 was a %s, want semantic", got)
+	}
+}
+
+func TestUnlimitedResultServesPages(t *testing.T) {
+	resetSQL(t)
+
+	cacheOutcome(t, "SELECT * FROM server_logs WHERE cpu_load > 80")
+	for _, q := range []string{
+		"SELECT * FROM server_logs WHERE cpu_load > 90 LIMIT 2",
+		"SELECT * FROM server_logs WHERE cpu_load > 80 LIMIT 3 OFFSET 3",
+		"SELECT id FROM server_logs WHERE cpu_load > 80 LIMIT 5 OFFSET 20",
+	} {
+		if got := cacheOutcome(t, q); got != "semantic" {
+			t.Errorf("%s: %s, want a semantic hit", q, got)
+		}
+	}
+
+	results := queryRows(t, "SELECT id FROM server_logs WHERE cpu_load > 90 LIMIT 2 OFFSET 1")
+	if got, want := column(results, "id"), []interface{}{1007, 1008}; !reflect.DeepEqual(got, want) {
+		t.Errorf("LIMIT 2 OFFSET 1: got ids %v, want %v", got, want)
+	}
+}
+
+func TestInLists(t *testing.T) {
+	resetSQL(t)
+
+	cases := []struct {
+		where string
+		want  []interface{}
+	}{
+		{"status IN ('WARNING', 'ERROR') AND cpu_load > 95", []interface{}{1007, 1013}},
+		{"id IN (1001, 1010, 5000)", []interface{}{1001, 1010}},
+		{"server_name IN ('cache-01')", []interface{}{1010}},
+	}
+	for _, c := range cases {
+		results := queryRows(t, "SELECT id FROM server_logs WHERE "+c.where)
+		if got := column(results, "id"); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("WHERE %s: got ids %v, want %v", c.where, got, c.want)
+		}
+	}
+}
+
+func TestInListServesSubsets(t *testing.T) {
+	resetSQL(t)
+
+	const inQuery = "SELECT * FROM server_logs WHERE status IN ('WARNING', 'ERROR')"
+	if got := cacheOutcome(t, inQuery); got != "miss" {
+		t.Fatalf("first IN query was a %s", got)
+	}
+	if got := cacheOutcome(t, inQuery); got != "direct" {
+		t.Errorf("repeated IN query was a %s, want direct", got)
+	}
+	if got := cacheOutcome(t, "SELECT * FROM server_logs WHERE status = 'ERROR'"); got != "semantic" {
+		t.Errorf("status = 'ERROR' was a %s, want semantic", got)
+	}
+	if got := cacheOutcome(t, "SELECT * FROM server_logs WHERE status IN ('ERROR')"); got != "semantic" {
+		t.Errorf("a smaller IN list was a %s, want semantic", got)
+	}
+	if got := cacheOutcome(t, "SELECT * FROM server_logs WHERE status = 'OK'"); got != "miss" {
+		t.Errorf("status = 'OK' was a %s, want miss", got)
+	}
+
+	cacheOutcome(t, "SELECT * FROM users WHERE age IN (25, 31, 45)")
+	if got := cacheOutcome(t, "SELECT * FROM users WHERE age = 31"); got != "semantic" {
+		t.Errorf("age = 31 from a cached integer IN list was a %s, want semantic", got)
+	}
+	if got := cacheOutcome(t, "SELECT * FROM users WHERE age = 32"); got != "miss" {
+		t.Errorf("age = 32 from a cached integer IN list was a %s, want miss", got)
+	}
+}
+
+func TestBetweenRanges(t *testing.T) {
+	resetSQL(t)
+
+	results := queryRows(t, "SELECT name FROM users WHERE age BETWEEN 42 AND 55")
+	if got, want := column(results, "name"), []interface{}{"Bob", "Charlie", "Frank"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("BETWEEN 42 AND 55 (inclusive): got %v, want %v", got, want)
+	}
+	if _, err := ParseSQL("SELECT * FROM users WHERE age BETWEEN 50 AND 30"); err == nil {
+		t.Error("reversed BETWEEN bounds parsed without error")
+	}
+
+	cacheOutcome(t, "SELECT * FROM users WHERE age BETWEEN 20 AND 60")
+	cases := []struct {
+		where, want string
+	}{
+		{"age BETWEEN 30 AND 50", "semantic"},
+		{"age BETWEEN 20 AND 60", "direct"},
+		{"age = 60", "semantic"},
+		{"age BETWEEN 50 AND 70", "miss"}, // overlaps but isn't contained
+		{"age BETWEEN 70 AND 90", "miss"}, // disjoint
+	}
+	for _, c := range cases {
+		if got := cacheOutcome(t, "SELECT * FROM users WHERE "+c.where); got != c.want {
+			t.Errorf("%s from a cached BETWEEN 20 AND 60 was a %s, want %s", c.where, got, c.want)
+		}
+	}
+}
+
+func TestDistinct(t *testing.T) {
+	resetSQL(t)
+
+	results := queryRows(t, "SELECT DISTINCT status FROM server_logs")
+	if got, want := column(results, "status"), []interface{}{"OK", "WARNING", "ERROR"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("DISTINCT status = %v, want %v", got, want)
+	}
+	// Only the projected columns count towards a duplicate
+	results = queryRows(t, "SELECT DISTINCT server_name, status FROM server_logs WHERE status = 'WARNING'")
+	if got, want := column(results, "server_name"), []interface{}{"web-02", "db-01", "web-03", "api-01"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("DISTINCT server_name, status = %v, want %v", got, want)
+	}
+
+	mustSQL(t, "INSERT INTO products (id, item, stock) VALUES (101, 'apple', 500)")
+	if n := len(queryRows(t, "SELECT DISTINCT * FROM products").Rows); n != 3 {
+		t.Errorf("DISTINCT * kept %d rows, want 3", n)
+	}
+	if n := len(queryRows(t, "SELECT * FROM products").Rows); n != 4 {
+		t.Errorf("without DISTINCT %d rows, want 4", n)
+	}
+
+	// A semantic hit dedupes the superset's rows too
+	mustSQL(t, "SELECT * FROM server_logs WHERE cpu_load > 10")
+	if got := cacheOutcome(t, "SELECT DISTINCT status FROM server_logs WHERE cpu_load > 20"); got != "semantic" {
+		t.Errorf("DISTINCT from a cached superset was a %s, want semantic", got)
+	}
+}
+
+func TestFloatAndNegativeConditions(t *testing.T) {
+	resetSQL(t)
+	mustSQL(t, "INSERT INTO server_logs (id, server_name, cpu_load, status) VALUES (2001, 'web-04', 80.7, 'OK')")
+	mustSQL(t, "INSERT INTO server_logs (id, server_name, cpu_load, status) VALUES (2002, 'web-05', -3, 'OK')")
+
+	cases := []struct {
+		where string
+		want  []interface{}
+	}{
+		{"cpu_load > 80.5 AND cpu_load < 81.5", []interface{}{1012, 2001}},
+		{"cpu_load <= 80.7 AND cpu_load >= 80.7", []interface{}{2001}},
+		{"cpu_load < 0", []interface{}{2002}},
+		{"cpu_load > -10 AND cpu_load < 16", []interface{}{1010, 2002}},
+		{"cpu_load BETWEEN -5 AND -1", []interface{}{2002}},
+		{"cpu_load = -3.0", []interface{}{2002}},
+	}
+	for _, c := range cases {
+		results := queryRows(t, "SELECT id FROM server_logs WHERE "+c.where)
+		if got := column(results, "id"); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("WHERE %s: got ids %v, want %v", c.where, got, c.want)
+		}
+	}
+
+	// Subset checks compare int and float bounds numerically
+	mustSQL(t, "SELECT * FROM server_logs WHERE cpu_load > 80.5")
+	if got := cacheOutcome(t, "SELECT * FROM server_logs WHERE cpu_load > 81"); got != "semantic" {
+		t.Errorf("cpu_load > 81 from a cached > 80.5 was a %s, want semantic", got)
+	}
+	if got := cacheOutcome(t, "SELECT * FROM server_logs WHERE cpu_load > 80.2"); got != "miss" {
+		t.Errorf("cpu_load > 80.2 from a cached > 80.5 was a %s, want miss", got)
+	}
+	mustSQL(t, "SELECT * FROM server_logs WHERE cpu_load > -10")
+	if got := cacheOutcome(t, "SELECT * FROM server_logs WHERE cpu_load BETWEEN -5 AND 20"); got != "semantic" {
+		t.Errorf("BETWEEN -5 AND 20 from a cached > -10 was a %s, want semantic", got)
+	}
+}
+
+func TestIsNull(t *testing.T) {
+	resetSQL(t)
+	mustSQL(t, "INSERT INTO users (id, name) VALUES (16, 'Mallory')")
+	mustSQL(t, "INSERT INTO users (id, name, age) VALUES (17, 'Niaj', NULL)")
+
+	results := queryRows(t, "SELECT id FROM users WHERE age IS NULL")
+	if got, want := column(results, "id"), []interface{}{16, 17}; !reflect.DeepEqual(got, want) {
+		t.Errorf("age IS NULL: got ids %v, want %v", got, want)
+	}
+	if n := len(queryRows(t, "SELECT id FROM users WHERE age IS NOT NULL").Rows); n != 15 {
+		t.Errorf("age IS NOT NULL matched %d rows, want the 15 seeded users", n)
+	}
+	if n := len(queryRows(t, "SELECT id FROM users WHERE age < 1000").Rows); n != 15 {
+		t.Errorf("age < 1000 matched %d rows, want NULLs excluded", n)
+	}
+
+	// A superset over age never held the NULL rows
+	mustSQL(t, "SELECT * FROM users WHERE age > 0")
+	if got := cacheOutcome(t, "SELECT * FROM users WHERE age IS NULL"); got != "miss" {
+		t.Errorf("IS NULL from a cached age > 0 was a %s, want miss", got)
+	}
+	mustSQL(t, "SELECT * FROM users")
+	if got := cacheOutcome(t, "SELECT * FROM users WHERE age IS NOT NULL"); got == "miss" {
+		t.Error("IS NOT NULL wasn't served from the cached full table")
+	}
+}
+
+func TestMissPenalty(t *testing.T) {
+	resetSQL(t)
+	if got := runCommand(t, "SQLCACHE", "PENALTY"); got != ":0\r\n" {
+		t.Fatalf("penalty after reset = %q, want :0", got)
+	}
+
+	timedMiss := func(query string) time.Duration {
+		start := time.Now()
+		if outcome := cacheOutcome(t, query); outcome != "miss" {
+			t.Fatalf("%s: got a %s, want a miss", query, outcome)
+		}
+		return time.Since(start)
+	}
+
+	if got := runCommand(t, "SQLCACHE", "PENALTY", "80"); got != "+OK\r\n" {
+		t.Fatalf("SQLCACHE PENALTY 80 = %q", got)
+	}
+	if got := runCommand(t, "SQLCACHE", "PENALTY"); got != ":80\r\n" {
+		t.Errorf("penalty = %q, want :80", got)
+	}
+	if elapsed := timedMiss("SELECT * FROM users WHERE age > 50"); elapsed < 80*time.Millisecond {
+		t.Errorf("miss with an 80ms penalty took %v", elapsed)
+	}
+
+	runCommand(t, "SQLCACHE", "PENALTY", "0")
+	if elapsed := timedMiss("SELECT * FROM products"); elapsed >= 40*time.Millisecond {
+		t.Errorf("miss with the penalty off took %v", elapsed)
+	}
+
+	for _, bad := range []string{"-5", "soon"} {
+		if got := runCommand(t, "SQLCACHE", "PENALTY", bad); got[0] != '-' {
+			t.Errorf("SQLCACHE PENALTY %s = %q, want an error", bad, got)
+		}
+	}
+}
+
+func TestMultipleStatements(t *testing.T) {
+	resetSQL(t)
+	users := mustSQL(t, "SELECT * FROM users")
+	products := mustSQL(t, "SELECT * FROM products")
+	quoted := mustSQL(t, "SELECT * FROM users WHERE name = 'a;b'")
+
+	before := SQLCache.totalQueries.Load()
+	if got := mustSQL(t, "SELECT * FROM users; SELECT * FROM products"); got != users+products {
+		t.Errorf("two statements replied\n%s\nwant users then products\n%s", got, users+products)
+	}
+	if got := SQLCache.totalQueries.Load() - before; got != 2 {
+		t.Errorf("two statements counted as %d queries, want 2", got)
+	}
+	if got := mustSQL(t, "SELECT * FROM products; SELECT * FROM users;"); got != products+users {
+		t.Errorf("trailing semicolon: got\n%s\nwant products then users", got)
+	}
+	if got := mustSQL(t, "SELECT * FROM users WHERE name = 'a;b'; SELECT * FROM products"); got != quoted+products {
+		t.Errorf("quoted semicolon split the statement: got\n%s", got)
+	}
+}
+
+func TestColumnAliases(t *testing.T) {
+	resetSQL(t)
+	query := "SELECT name AS username, age AS years FROM users WHERE age > 90"
+	want := "username | years\n" +
+		"---------+------\n" +
+		"Grace    |    97\n" +
+		"Mike     |    91\n" +
+		"Nina     |    92\n" +
+		"\n(3 rows)\n"
+	if got := mustSQL(t, query); got != bulkString(want) {
+		t.Errorf("%s:\n%s\nwant\n%s", query, got, want)
+	}
+
+	ast := parseQuery(t, "SELECT id, name AS username FROM users")
+	if want := []string{"", "username"}; !reflect.DeepEqual(ast.ColumnAliases, want) {
+		t.Errorf("aliases = %q, want %q", ast.ColumnAliases, want)
+	}
+
+	// A semantic hit on the unaliased superset still shows the aliases
+	mustSQL(t, "SELECT * FROM users WHERE age > 80")
+	if outcome := cacheOutcome(t, "SELECT name AS who FROM users WHERE age > 85"); outcome != "semantic" {
+		t.Errorf("aliased subset: got a %s, want a semantic hit", outcome)
+	}
+
+	// WHERE names real columns, not aliases
+	if reply := runSQL(t, &recordConn{}, "SELECT age AS years FROM users WHERE years > 90"); reply[0] != '-' {
+		t.Errorf("WHERE on an alias = %q, want an error", reply)
+	}
+}
+
+// BenchmarkDirectHitWorkload answers a workload where nine queries in ten
+// repeat a cached one. "parse-first" parses every query before the direct
+// lookup, as HandleSQL used to; "lookup-first" is HandleSQL's order now,
+// parsing only what the direct cache can't answer. Each reports parses/op.
+func BenchmarkDirectHitWorkload(b *testing.B) {
+	hot := []string{
+		"SELECT * FROM users WHERE age > 50",
+		"SELECT name FROM users WHERE age < 30",
+		"SELECT * FROM products",
+		"SELECT * FROM server_logs WHERE cpu_load > 80",
+		"SELECT id, status FROM server_logs WHERE status = 'ERROR'",
+	}
+	parsed := 0
+	parse := func(q string) *QueryAST {
+		parsed++
+		ast, err := ParseSQL(q)
+		if err == nil {
+			err = resolveQueryNames(ast)
+		}
+		if err != nil {
+			b.Fatal(err)
+		}
+		return ast
+	}
+	answer := func(q string, ast *QueryAST) {
+		if _, _, err := answerQuery(q, ast, time.Now()); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	for _, parseFirst := range []bool{true, false} {
+		name := "lookup-first"
+		if parseFirst {
+			name = "parse-first"
+		}
+		b.Run(name, func(b *testing.B) {
+			cfg := DefaultSQLCacheConfig()
+			cfg.MaxSize, cfg.MissPenalty, cfg.RefreshInterval = 64, 0, 0
+			InitSQLCache(cfg)
+			InitBackingDB()
+			for _, q := range hot {
+				answer(q, parse(q))
+			}
+			parsed = 0
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				q := hot[i%len(hot)]
+				if i%10 == 9 {
+					q = fmt.Sprintf("SELECT * FROM users WHERE id = %d", i)
+				}
+				if parseFirst {
+					ast := parse(q)
+					if _, hit := lookupDirectHit(q, time.Now()); !hit {
+						answer(q, ast)
+					}
+					continue
+				}
+				if _, hit := lookupDirectHit(q, time.Now()); !hit {
+					answer(q, parse(q))
+				}
+			}
+			b.ReportMetric(float64(parsed)/float64(b.N), "parses/op")
+		})
+	}
+}
+
+func TestConjunctionsAndDisjunctionsSubsets(t *testing.T) {
+	cases := []struct {
+		newCond, cachedCond string
+		want                bool
+	}{
+		// AND-new against a single predicate: one side fitting is enough
+		{"cpu_load > 90 AND status = 'ERROR'", "cpu_load > 80", true},
+		{"status = 'ERROR' AND cpu_load > 90", "cpu_load > 80", true},
+		{"cpu_load > 70 AND status = 'ERROR'", "cpu_load > 80", false},
+		{"cpu_load > 85 AND cpu_load < 95", "cpu_load BETWEEN 80 AND 100", true},
+		// single predicate against OR-cached: fitting either side is enough
+		{"cpu_load > 90", "cpu_load > 80 OR status = 'ERROR'", true},
+		{"status = 'ERROR'", "cpu_load > 80 OR status = 'ERROR'", true},
+		{"status = 'OK'", "cpu_load > 80 OR status = 'ERROR'", false},
+		// AND-new against OR-cached
+		{"cpu_load > 90 AND status = 'OK'", "cpu_load > 80 OR status = 'ERROR'", true},
+		{"cpu_load > 50 AND status = 'ERROR'", "cpu_load > 80 OR status = 'ERROR'", true},
+		{"cpu_load > 50 AND status = 'OK'", "cpu_load > 80 OR status = 'ERROR'", false},
+		// OR-new against OR-cached: both sides must fit, in either order
+		{"cpu_load > 90 OR status = 'ERROR'", "cpu_load > 80 OR status = 'ERROR'", true},
+		{"status = 'ERROR' OR cpu_load > 90", "cpu_load > 80 OR status = 'ERROR'", true},
+		{"cpu_load > 90 OR status = 'OK'", "cpu_load > 80 OR status = 'ERROR'", false},
+		// against AND-cached: every side must be implied
+		{"cpu_load > 90 AND status = 'ERROR'", "cpu_load > 80 AND status = 'ERROR'", true},
+		{"cpu_load > 90", "cpu_load > 80 AND status = 'ERROR'", false},
+		{"cpu_load > 90 OR status = 'ERROR'", "cpu_load > 80", false},
+	}
+
+	for _, c := range cases {
+		resetSQL(t)
+		newAST, cachedAST := parseQuery(t, "SELECT * FROM server_logs WHERE "+c.newCond), parseQuery(t, "SELECT * FROM server_logs WHERE "+c.cachedCond)
+		if got := isConditionSubset(newAST.Where, cachedAST.Where, 0); got != c.want {
+			t.Errorf("%s ⊆ %s = %v, want %v", c.newCond, c.cachedCond, got, c.want)
+		}
+
+		// The cache agrees, and a hit serves the same rows as the tables
+		mustSQL(t, "SELECT * FROM server_logs WHERE "+c.cachedCond)
+		want := "miss"
+		if c.want {
+			want = "semantic"
+		}
+		if got := cacheOutcome(t, "SELECT * FROM server_logs WHERE "+c.newCond); got != want {
+			t.Errorf("%s after caching %s: got a %s, want a %s", c.newCond, c.cachedCond, got, want)
+		}
+	}
+}
+
+func TestColumnToColumnComparisons(t *testing.T) {
+	resetSQL(t)
+	mustSQL(t, "CREATE TABLE limits (id INT, cpu_load INT, threshold INT)")
+	for _, values := range []string{"(1, 90, 80)", "(2, 50, 60)", "(3, 70, 70)", "(4, 20, 25)"} {
+		mustSQL(t, "INSERT INTO limits (id, cpu_load, threshold) VALUES "+values)
+	}
+	mustSQL(t, "INSERT INTO limits (id, cpu_load) VALUES (5, 99)") // threshold is NULL
+
+	cases := []struct {
+		where string
+		want  []interface{}
+	}{
+		{"cpu_load > threshold", []interface{}{1}},
+		{"cpu_load < threshold", []interface{}{2, 4}},
+		{"cpu_load = threshold", []interface{}{3}},
+		{"cpu_load >= threshold", []interface{}{1, 3}},
+		{"NOT cpu_load = threshold", []interface{}{1, 2, 4}},
+		{"threshold < cpu_load AND id > 1", nil},
+		{"cpu_load < threshold OR id = 5", []interface{}{2, 4, 5}},
+	}
+	for _, c := range cases {
+		results := queryRows(t, "SELECT id FROM limits WHERE "+c.where)
+		if got := column(results, "id"); !reflect.DeepEqual(got, c.want) && !(len(got) == 0 && len(c.want) == 0) {
+			t.Errorf("WHERE %s: got ids %v, want %v", c.where, got, c.want)
+		}
+	}
+
+	// Never answered from a cached superset, even the whole table
+	mustSQL(t, "SELECT * FROM limits")
+	if outcome := cacheOutcome(t, "SELECT * FROM limits WHERE cpu_load > threshold"); outcome == "semantic" {
+		t.Error("a column comparison was answered from a cached superset")
+	}
+
+	// A bare word that isn't a column is still a literal
+	if got, want := column(queryRows(t, "SELECT id FROM server_logs WHERE status = ERROR"), "id"), column(queryRows(t, "SELECT id FROM server_logs WHERE status = 'ERROR'"), "id"); !reflect.DeepEqual(got, want) {
+		t.Errorf("status = ERROR matched %v, want the ids of status = 'ERROR' %v", got, want)
+	}
+}
+
+func TestNegatedConditions(t *testing.T) {
+	resetSQL(t)
+	all := queryRows(t, "SELECT * FROM server_logs")
+	complement := func(keep func(Row) bool) []interface{} {
+		var ids []interface{}
+		for _, row := range all.Rows {
+			if !keep(row) {
+				ids = append(ids, row["id"])
+			}
+		}
+		return ids
+	}
+
+	cases := []struct {
+		where string
+		want  []interface{}
+	}{
+		{"NOT status = 'OK'", complement(func(r Row) bool { return r["status"] == "OK" })},
+		{"NOT cpu_load > 80", complement(func(r Row) bool { return r["cpu_load"].(int) > 80 })},
+		{"NOT cpu_load BETWEEN 30 AND 90", complement(func(r Row) bool { l := r["cpu_load"].(int); return l >= 30 && l <= 90 })},
+		{"NOT NOT status = 'OK'", complement(func(r Row) bool { return r["status"] != "OK" })},
+	}
+	for _, c := range cases {
+		if got := column(queryRows(t, "SELECT id FROM server_logs WHERE "+c.where), "id"); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("WHERE %s: got ids %v, want %v", c.where, got, c.want)
+		}
+	}
+
+	// A cached negation only serves queries it provably contains
+	mustSQL(t, "SELECT * FROM server_logs WHERE NOT status = 'OK'")
+	for query, want := range map[string]string{
+		"SELECT * FROM server_logs WHERE NOT status = 'OK' AND cpu_load > 90": "semantic",
+		"SELECT * FROM server_logs WHERE status = 'ERROR'":                    "miss",
+		"SELECT * FROM server_logs WHERE NOT status = 'WARNING'":              "miss",
+	} {
+		if got := cacheOutcome(t, query); got != want {
+			t.Errorf("%s: got a %s, want a %s", query, got, want)
+		}
+	}
+}
+
+func TestRowCapTruncatesReplies(t *testing.T) {
+	resetSQL(t)
+	addEventsTable(t, 25)
+	t.Cleanup(func() { maxResultRows.Store(DEFAULT_MAX_RESULT_ROWS) })
+	if got := runCommand(t, "SQLCACHE", "MAXROWS"); got != fmt.Sprintf(":%d\r\n", DEFAULT_MAX_RESULT_ROWS) {
+		t.Errorf("default row cap = %q, want %d", got, DEFAULT_MAX_RESULT_ROWS)
+	}
+	if got := runCommand(t, "SQLCACHE", "MAXROWS", "10"); got != "+OK\r\n" {
+		t.Fatalf("SQLCACHE MAXROWS 10 = %q", got)
+	}
+
+	reply := mustSQL(t, "SELECT id FROM events")
+	if !strings.HasSuffix(reply, "\n(10 of 25 rows, truncated)\n\r\n") {
+		t.Errorf("reply over the cap doesn't end with the truncation note:\n%s", reply)
+	}
+	// header, separator, 10 rows, blank line, note
+	if lines := strings.Count(bulkBody(t, reply), "\n"); lines != 14 {
+		t.Errorf("reply over the cap has %d lines, want 14:\n%s", lines, reply)
+	}
+	if reply := mustSQL(t, "SELECT id FROM events WHERE code = 3"); !strings.HasSuffix(reply, "\n(3 rows)\n\r\n") {
+		t.Errorf("reply under the cap:\n%s\nwant a plain row count", reply)
+	}
+
+	// The cap applies to the other formats too, and to cached results
+	var objects []map[string]interface{}
+	if err := json.Unmarshal([]byte(bulkBody(t, mustSQL(t, "SELECT id FROM events FORMAT JSON"))), &objects); err != nil || len(objects) != 10 {
+		t.Errorf("FORMAT JSON over the cap: %d objects (err %v), want 10", len(objects), err)
+	}
+	if entry, hit := SQLCache.Peek("SELECT id FROM events"); !hit || len(entry.Results.Rows) != 25 {
+		t.Error("the cache doesn't hold all 25 rows")
+	}
+
+	runCommand(t, "SQLCACHE", "MAXROWS", "0")
+	if reply := mustSQL(t, "SELECT id FROM events"); !strings.HasSuffix(reply, "\n(25 rows)\n\r\n") {
+		t.Errorf("with the cap off the reply ends\n%s", reply[len(reply)-30:])
+	}
+}
+
+func TestRangeConjunctionsServeNarrowerRanges(t *testing.T) {
+	cases := []struct {
+		query, want string
+	}{
+		{"SELECT * FROM users WHERE age > 30 AND age < 50", "semantic"},
+		{"SELECT * FROM users WHERE age < 50 AND age > 30", "semantic"},
+		{"SELECT * FROM users WHERE age >= 21 AND age <= 59", "semantic"},
+		{"SELECT * FROM users WHERE age BETWEEN 25 AND 55", "semantic"},
+		{"SELECT name FROM users WHERE age > 30 AND age < 50 AND name LIKE '%a%'", "semantic"},
+		{"SELECT * FROM users WHERE age > 30", "miss"},
+		{"SELECT * FROM users WHERE age > 10 AND age < 50", "miss"},
+		{"SELECT * FROM users WHERE age > 30 AND age < 70", "miss"},
+		{"SELECT * FROM users WHERE age >= 20 AND age < 50", "miss"},
+	}
+	for _, c := range cases {
+		resetSQL(t)
+		mustSQL(t, "SELECT * FROM users WHERE age > 20 AND age < 60")
+		if got := cacheOutcome(t, c.query); got != c.want {
+			t.Errorf("%s within age > 20 AND age < 60: got a %s, want a %s", c.query, got, c.want)
+		}
+	}
+}
+
+func TestNoCacheLeavesTheCacheAlone(t *testing.T) {
+	resetSQL(t)
+	mustSQL(t, "SELECT * FROM users WHERE age > 50")
+	counters := func() [4]uint64 {
+		return [4]uint64{SQLCache.totalQueries.Load(), SQLCache.directHits.Load(), SQLCache.semanticHits.Load(), SQLCache.cacheMisses.Load()}
+	}
+	before, size := counters(), SQLCache.Len()
+
+	for _, query := range []string{
+		"NOCACHE SELECT * FROM products",
+		"NOCACHE SELECT * FROM users WHERE age > 50", // cached, but not read
+		"nocache SELECT name FROM users WHERE age > 80",
+	} {
+		mustSQL(t, query)
+	}
+	if got := SQLCache.Len(); got != size {
+		t.Errorf("NOCACHE queries changed the cache size from %d to %d", size, got)
+	}
+	if _, hit := SQLCache.Peek("SELECT * FROM products"); hit {
+		t.Error("a NOCACHE query was cached")
+	}
+	if after := counters(); after != before {
+		t.Errorf("NOCACHE queries changed the total, direct, semantic and miss counts from %v to %v", before, after)
+	}
+}
+
+func TestExplicitColumnsKeepTheRequestedOrder(t *testing.T) {
+	resetSQL(t)
+	cacheOutcome(t, "SELECT * FROM users WHERE age > 80")
+	cases := []struct {
+		query, outcome, want string
+	}{
+		{"SELECT age, name, id FROM users WHERE age > 90 ORDER BY id", "semantic",
+			"age | name  | id\n----+-------+---\n 97 | Grace |  7\n 91 | Mike  | 13\n 92 | Nina  | 14\n\n(3 rows)\n"},
+		{"SELECT name, id FROM users WHERE age < 10", "miss",
+			"name  | id\n------+---\nLaura | 12\n\n(1 rows)\n"},
+		{"SELECT name, id FROM users WHERE age < 10", "direct",
+			"name  | id\n------+---\nLaura | 12\n\n(1 rows)\n"},
+	}
+	for _, c := range cases {
+		if got := cacheOutcome(t, c.query); got != c.outcome {
+			t.Errorf("%s was a %s, want %s", c.query, got, c.outcome)
+		}
+		if got := bulkBody(t, mustSQL(t, c.query)); got != c.want {
+			t.Errorf("%s returned\n%s\nwant\n%s", c.query, got, c.want)
+		}
+	}
+}
+
+func TestEmptyResultsAreNotErrors(t *testing.T) {
+	resetSQL(t)
+	const empty = "id | name | age\n---+------+----\n\n(0 rows)\n"
+	cacheOutcome(t, "SELECT * FROM users WHERE age > 80")
+	for _, c := range []struct{ query, outcome string }{
+		{"SELECT * FROM users WHERE age > 100", "semantic"}, // Filtered down to nothing
+		{"SELECT * FROM users WHERE age < 0", "miss"},
+		{"SELECT * FROM users WHERE age < 0", "direct"},
+	} {
+		if got := cacheOutcome(t, c.query); got != c.outcome {
+			t.Errorf("%s was a %s, want %s", c.query, got, c.outcome)
+		}
+		if got := bulkBody(t, mustSQL(t, c.query)); got != empty {
+			t.Errorf("%s returned\n%s\nwant\n%s", c.query, got, empty)
+		}
+	}
+
+	if reply := runSQL(t, &recordConn{}, "SELECT * FROM nope"); !strings.HasPrefix(reply, "-ERR ") {
+		t.Errorf("query of a missing table replied %q, want an error", reply)
+	}
+	if got := formatResults(nil, nil); got != "$-1\r\n" {
+		t.Errorf("formatResults(nil) = %q, want a nil bulk string", got)
+	}
+}
+
+func TestToleranceServesApproximateHits(t *testing.T) {
+	resetSQL(t)
+	if got := runCommand(t, "SQLCACHE", "TOLERANCE"); got != "$1\r\n0\r\n" {
+		t.Fatalf("tolerance after reset = %q, want 0", got)
+	}
+
+	// No seeded cpu_load lies in (78, 80], so re-filtering the cached rows
+	// gives exactly what the tables would (cacheOutcome checks the rows)
+	cacheOutcome(t, "SELECT * FROM server_logs WHERE cpu_load > 80")
+	if got := cacheOutcome(t, "SELECT * FROM server_logs WHERE cpu_load > 78"); got != "miss" {
+		t.Errorf("bound outside the cached one with no tolerance was a %s, want miss", got)
+	}
+
+	resetSQL(t)
+	if got := runCommand(t, "SQLCACHE", "TOLERANCE", "5"); got != "+OK\r\n" {
+		t.Fatalf("SQLCACHE TOLERANCE 5 = %q", got)
+	}
+	cacheOutcome(t, "SELECT * FROM server_logs WHERE cpu_load > 80")
+	for _, query := range []string{
+		"SELECT * FROM server_logs WHERE cpu_load > 78",
+		"SELECT id FROM server_logs WHERE cpu_load > 76 AND status = 'WARNING'",
+		"SELECT * FROM server_logs WHERE cpu_load > 90",
+	} {
+		if got := cacheOutcome(t, query); got != "semantic" {
+			t.Errorf("%s with tolerance 5 was a %s, want semantic", query, got)
+		}
+	}
+	if got := cacheOutcome(t, "SELECT * FROM server_logs WHERE cpu_load > 74"); got != "miss" {
+		t.Errorf("bound beyond the tolerance was a %s, want miss", got)
+	}
+
+	for _, bad := range []string{"-1", "wide", "NaN"} {
+		if got := runCommand(t, "SQLCACHE", "TOLERANCE", bad); got[0] != '-' {
+			t.Errorf("SQLCACHE TOLERANCE %s = %q, want an error", bad, got)
+		}
+	}
+}
+
+func TestPagesOfACachedResultDontOverlap(t *testing.T) {
+	resetSQL(t)
+	cacheOutcome(t, "SELECT * FROM users WHERE age > 40")
+	want := column(queryRows(t, "SELECT * FROM users WHERE age > 40"), "id")
+
+	seen := map[interface{}]int{}
+	var paged []interface{}
+	for offset := 0; offset < len(want)+3; offset += 3 {
+		query := fmt.Sprintf("SELECT * FROM users WHERE age > 40 LIMIT 3 OFFSET %d", offset)
+		if got := cacheOutcome(t, query); got != "semantic" {
+			t.Errorf("%s was a %s, want semantic", query, got)
+		}
+		for _, id := range column(queryRows(t, query), "id") {
+			if page, ok := seen[id]; ok {
+				t.Errorf("id %v on the pages at offsets %d and %d", id, page, offset)
+			}
+			seen[id] = offset
+			paged = append(paged, id)
+		}
+	}
+	if !reflect.DeepEqual(paged, want) {
+		t.Errorf("pages gave ids %v, want %v", paged, want)
+	}
+}
@@ -0,0 +1,130 @@
+package command
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+)
+
+// hashIndex maps a column value (see indexKey) to the positions of the rows
+// in Table.Rows holding it.
+type hashIndex map[string][]int
+
+// indexKey normalizes a row value for hashIndex. Numbers are keyed by value,
+// so 42 and 42.0 share a key; everything else by its string form.
+func indexKey(val interface{}) string {
+	if n, ok := asFloat(val); ok {
+		return "n:" + strconv.FormatFloat(n, 'g', -1, 64)
+	}
+	return fmt.Sprintf("s:%v", val)
+}
+
+// buildIndex indexes every row of the table on col.
+func buildIndex(table *Table, col string) hashIndex {
+	idx := make(hashIndex)
+	for pos, row := range table.Rows {
+		idx.add(row, col, pos)
+	}
+	return idx
+}
+
+func (idx hashIndex) add(row Row, col string, pos int) {
+	val, ok := row[col]
+	if !ok {
+		return // Rows without the column can never match an equality
+	}
+	key := indexKey(val)
+	idx[key] = append(idx[key], pos)
+}
+
+// appendToIndexes adds the table's last row (just inserted) to every index.
+// NOTE: This function is not thread-safe, callers must hold dbMutex!
+func (t *Table) appendToIndexes() {
+	pos := len(t.Rows) - 1
+	for col, idx := range t.Indexes {
+		idx.add(t.Rows[pos], col, pos)
+	}
+}
+
+// rebuildIndexes recomputes every index after rows were changed or removed,
+// since both can move values and row positions.
+// NOTE: This function is not thread-safe, callers must hold dbMutex!
+func (t *Table) rebuildIndexes() {
+	for col := range t.Indexes {
+		t.Indexes[col] = buildIndex(t, col)
+	}
+}
+
+// indexedRows returns the candidate rows for a WHERE clause that is a single
+// equality on an indexed column, in table order. ok is false when no index
+// applies and the caller should scan. Candidates may include rows the
+// condition rejects, so the WHERE clause must still be applied to them.
+// NOTE: This function is not thread-safe, callers must hold dbMutex!
+func (t *Table) indexedRows(where *WhereNode) ([]Row, bool) {
+	if where == nil || where.Cond == nil || where.Cond.Operator != "=" || where.Cond.ValueColumn != "" {
+		return nil, false
+	}
+	idx, ok := t.Indexes[where.Cond.Column]
+	if !ok {
+		return nil, false
+	}
+
+	// checkPredicate compares numerically when it can and by string form
+	// otherwise, so a numeric literal may match rows under either key.
+	positions := append([]int(nil), idx["s:"+where.Cond.Value]...)
+	if n, isNum := where.Cond.GetAsFloat(); isNum {
+		positions = append(positions, idx["n:"+strconv.FormatFloat(n, 'g', -1, 64)]...)
+		sort.Ints(positions)
+	}
+
+	rows := make([]Row, len(positions))
+	for i, pos := range positions {
+		rows[i] = t.Rows[pos]
+	}
+	return rows, true
+}
+
+// handleCreateIndex parses and runs a CREATE INDEX statement.
+func handleCreateIndex(query string, c net.Conn) {
+	stmt, err := ParseCreateIndex(query)
+	if err != nil {
+		c.Write([]byte(fmt.Sprintf("-ERR %s\r\n", err.Error())))
+		return
+	}
+
+	indexed, err := executeCreateIndex(stmt)
+	if err != nil {
+		c.Write([]byte(fmt.Sprintf("-ERR %s\r\n", err.Error())))
+		return
+	}
+
+	queryLog.Info("create index", "query", query, "table", stmt.Table, "column", stmt.Column, "rows", indexed)
+	c.Write([]byte("+OK\r\n"))
+}
+
+// executeCreateIndex builds a hash index on the column and attaches it to
+// the table. Returns the number of rows indexed.
+func executeCreateIndex(stmt *CreateIndexAST) (int, error) {
+	dbMutex.Lock()
+	defer dbMutex.Unlock()
+
+	table, exists := lookupTable(stmt.Table)
+	if !exists {
+		return 0, fmt.Errorf("table '%s' not found", stmt.Table)
+	}
+	col, err := resolveColumn(table.Columns, stmt.Column, table.Name)
+	if err != nil {
+		return 0, err
+	}
+	stmt.Table, stmt.Column = table.Name, col
+
+	if _, exists := table.Indexes[col]; exists {
+		return 0, fmt.Errorf("column '%s' of table '%s' is already indexed", col, table.Name)
+	}
+	if table.Indexes == nil {
+		table.Indexes = make(map[string]hashIndex)
+	}
+	table.Indexes[col] = buildIndex(table, col)
+	return len(table.Rows), nil
+}
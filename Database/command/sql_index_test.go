@@ -0,0 +1,95 @@
+package command
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// addEventsTable registers an "events" table of n rows whose status cycles
+// through eight values, so an equality matches an eighth of the rows.
+func addEventsTable(t testing.TB, n int) {
+	t.Helper()
+	table := &Table{Name: "events", Columns: []string{"id", "status", "code"}}
+	for i := 0; i < n; i++ {
+		table.Rows = append(table.Rows, Row{"id": i, "status": fmt.Sprintf("s%d", i%8), "code": i % 8})
+	}
+	dbMutex.Lock()
+	BackingDatabase["events"] = table
+	dbMutex.Unlock()
+}
+
+func TestIndexedLookupsMatchScans(t *testing.T) {
+	resetSQL(t)
+	addEventsTable(t, 1000)
+
+	queries := []string{
+		"SELECT * FROM events WHERE status = 's3'",
+		"SELECT * FROM events WHERE code = 5",
+		"SELECT * FROM events WHERE code = 5.0",
+		"SELECT * FROM events WHERE code = '5'",
+		"SELECT * FROM events WHERE status = 'none'",
+	}
+	scanned := make([]*Table, len(queries))
+	for i, q := range queries {
+		scanned[i] = queryRows(t, q)
+	}
+
+	mustSQL(t, "CREATE INDEX ON events (status)")
+	mustSQL(t, "CREATE INDEX idx_code ON events (code)")
+	for i, q := range queries {
+		if got := queryRows(t, q); !reflect.DeepEqual(got.Rows, scanned[i].Rows) {
+			t.Errorf("%s: indexed lookup returned %d rows, the scan %d", q, len(got.Rows), len(scanned[i].Rows))
+		}
+	}
+
+	// The index only hands the executor the matching rows
+	dbMutex.RLock()
+	candidates, ok := BackingDatabase["events"].indexedRows(parseQuery(t, queries[0]).Where)
+	dbMutex.RUnlock()
+	if !ok || len(candidates) != 125 {
+		t.Errorf("index gave %d candidates (used: %v), want the 125 matching rows", len(candidates), ok)
+	}
+
+	// Writes keep the index in step
+	mustSQL(t, "INSERT INTO events (id, status, code) VALUES (1000, 's3', 3)")
+	mustSQL(t, "UPDATE events SET status = 's9' WHERE id = 3")
+	mustSQL(t, "DELETE FROM events WHERE id = 11")
+	results := queryRows(t, queries[0])
+	if n := len(results.Rows); n != 124 {
+		t.Errorf("s3 after the writes: %d rows, want 124", n)
+	}
+	for _, row := range results.Rows {
+		if row["status"] != "s3" {
+			t.Fatalf("index returned a row with status %v", row["status"])
+		}
+	}
+}
+
+func BenchmarkEqualityLookup(b *testing.B) {
+	for _, indexed := range []bool{false, true} {
+		name := "scan"
+		if indexed {
+			name = "indexed"
+		}
+		b.Run(name, func(b *testing.B) {
+			resetSQL(b)
+			addEventsTable(b, 100000)
+			if indexed {
+				if _, err := executeCreateIndex(&CreateIndexAST{Table: "events", Column: "status"}); err != nil {
+					b.Fatal(err)
+				}
+			}
+			query, err := ParseSQL("SELECT * FROM events WHERE status = 's3'")
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := executeOnBackingStore(query); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
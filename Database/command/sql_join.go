@@ -0,0 +1,127 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+)
+
+// JoinClause is the optional "[INNER] JOIN <table> [alias] ON <col> = <col>"
+// part of a SELECT.
+type JoinClause struct {
+	Table       string
+	Alias       string // Empty means the table name is used as the qualifier
+	LeftColumn  string // As written in the ON clause, e.g. "a.server_name"
+	RightColumn string
+}
+
+// String renders the join the way it would be written in a query.
+func (jc *JoinClause) String() string {
+	target := jc.Table
+	if jc.Alias != "" {
+		target += " " + jc.Alias
+	}
+	return fmt.Sprintf("JOIN %s ON %s = %s", target, jc.LeftColumn, jc.RightColumn)
+}
+
+// joinTables runs a nested-loop inner join for the query's FROM and JOIN
+// tables. Every column of the combined rows is qualified with its table's
+// alias ("a.server_name"); columns whose name appears in only one of the two
+// tables are also available unqualified. Rows whose join values are missing
+// never match. Returns the joined rows and the qualified column list.
+// NOTE: This function is not thread-safe, callers must hold dbMutex!
+func joinTables(query *QueryAST) ([]Row, []string, error) {
+	left, exists := lookupTable(query.FromTable)
+	if !exists {
+		return nil, nil, fmt.Errorf("table '%s' not found", query.FromTable)
+	}
+	right, exists := lookupTable(query.Join.Table)
+	if !exists {
+		return nil, nil, fmt.Errorf("table '%s' not found", query.Join.Table)
+	}
+
+	leftAlias, rightAlias := query.qualifiers()
+	if strings.EqualFold(leftAlias, rightAlias) {
+		return nil, nil, fmt.Errorf("tables in a JOIN need distinct aliases, both are '%s'", leftAlias)
+	}
+
+	// The ON columns may be written in either order
+	leftCol, okL := joinColumn(query.Join.LeftColumn, leftAlias, left)
+	rightCol, okR := joinColumn(query.Join.RightColumn, rightAlias, right)
+	if !okL || !okR {
+		leftCol, okL = joinColumn(query.Join.RightColumn, leftAlias, left)
+		rightCol, okR = joinColumn(query.Join.LeftColumn, rightAlias, right)
+	}
+	if !okL || !okR {
+		return nil, nil, fmt.Errorf("ON must compare a column of '%s' with a column of '%s'", leftAlias, rightAlias)
+	}
+
+	columns := make([]string, 0, len(left.Columns)+len(right.Columns))
+	for _, col := range left.Columns {
+		columns = append(columns, leftAlias+"."+col)
+	}
+	for _, col := range right.Columns {
+		columns = append(columns, rightAlias+"."+col)
+	}
+
+	var rows []Row
+	for _, l := range left.Rows {
+		lv := l[leftCol]
+		if lv == nil {
+			continue
+		}
+		for _, r := range right.Rows {
+			rv := r[rightCol]
+			if rv == nil || compareValues(lv, rv) != 0 {
+				continue
+			}
+			rows = append(rows, mergeJoinedRow(l, r, leftAlias, rightAlias, left, right))
+		}
+	}
+	return rows, columns, nil
+}
+
+// joinColumn resolves an ON column reference like "a.server_name" against one
+// side of the join, returning the bare column name.
+func joinColumn(ref, alias string, table *Table) (string, bool) {
+	prefix := alias + "."
+	if !strings.HasPrefix(ref, prefix) {
+		return "", false
+	}
+	col := strings.TrimPrefix(ref, prefix)
+	return col, containsColumn(table.Columns, col)
+}
+
+// mergeJoinedRow combines a matched pair of rows into one joined row.
+func mergeJoinedRow(l, r Row, leftAlias, rightAlias string, left, right *Table) Row {
+	row := make(Row, 2*(len(l)+len(r)))
+	for col, val := range l {
+		row[leftAlias+"."+col] = val
+		if !containsColumn(right.Columns, col) {
+			row[col] = val
+		}
+	}
+	for col, val := range r {
+		row[rightAlias+"."+col] = val
+		if !containsColumn(left.Columns, col) {
+			row[col] = val
+		}
+	}
+	return row
+}
+
+// qualifiers returns the names the FROM and JOIN tables are referred to by:
+// their alias if one was given, else the table name.
+func (ast *QueryAST) qualifiers() (string, string) {
+	leftAlias := ast.FromAlias
+	if leftAlias == "" {
+		leftAlias = ast.FromTable
+	}
+	rightAlias := ""
+	if ast.Join != nil {
+		rightAlias = ast.Join.Alias
+		if rightAlias == "" {
+			rightAlias = ast.Join.Table
+		}
+	}
+	return leftAlias, rightAlias
+}
@@ -0,0 +1,59 @@
+package command
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// createHosts adds a hosts table naming two of server_logs' servers and
+// one that has no logs.
+func createHosts(t *testing.T) {
+	t.Helper()
+	mustSQL(t, "CREATE TABLE hosts (name TEXT, region TEXT)")
+	mustSQL(t, "INSERT INTO hosts (name, region) VALUES ('web-01', 'us')")
+	mustSQL(t, "INSERT INTO hosts (name, region) VALUES ('db-01', 'eu')")
+	mustSQL(t, "INSERT INTO hosts (name, region) VALUES ('ghost-01', 'us')")
+}
+
+func TestInnerJoin(t *testing.T) {
+	resetSQL(t)
+	createHosts(t)
+
+	results := queryRows(t, "SELECT a.id, b.region FROM server_logs a JOIN hosts b ON a.server_name = b.name")
+	if got, want := results.Columns, []string{"a.id", "b.region"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("columns = %v, want %v", got, want)
+	}
+	// Only logs of known hosts, and never the host without logs
+	want := []Row{
+		{"a.id": 1001, "b.region": "us"},
+		{"a.id": 1003, "b.region": "eu"},
+		{"a.id": 1005, "b.region": "us"},
+		{"a.id": 1008, "b.region": "eu"},
+		{"a.id": 1011, "b.region": "us"},
+	}
+	if !reflect.DeepEqual(results.Rows, want) {
+		t.Errorf("rows = %v, want %v", results.Rows, want)
+	}
+
+	// WHERE filters the joined rows; ON may name the columns either way round
+	results = queryRows(t, "SELECT a.id FROM server_logs a JOIN hosts b ON b.name = a.server_name WHERE region = 'eu' AND cpu_load > 91")
+	if got := column(results, "a.id"); !reflect.DeepEqual(got, []interface{}{1008}) {
+		t.Errorf("filtered join: got ids %v, want [1008]", got)
+	}
+}
+
+func TestJoinErrors(t *testing.T) {
+	resetSQL(t)
+	createHosts(t)
+	c := &recordConn{}
+	for _, q := range []string{
+		"SELECT * FROM server_logs a JOIN nowhere b ON a.server_name = b.name",
+		"SELECT * FROM server_logs a JOIN hosts a ON a.server_name = a.name",
+		"SELECT * FROM server_logs a JOIN hosts b ON a.server_name = a.status",
+	} {
+		if reply := runSQL(t, c, q); !strings.HasPrefix(reply, "-ERR") {
+			t.Errorf("%s: replied %q, want an error", q, reply)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package command
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// compileLikePattern translates a SQL LIKE pattern into an anchored,
+// case-insensitive regex: '%' matches any run of characters, '_' matches
+// exactly one, and a backslash makes the next character literal ("50\%").
+func compileLikePattern(pattern string) (*regexp.Regexp, error) {
+	var sb strings.Builder
+	sb.WriteString("(?is)^")
+
+	escaped := false
+	for _, ch := range pattern {
+		switch {
+		case escaped:
+			sb.WriteString(regexp.QuoteMeta(string(ch)))
+			escaped = false
+		case ch == '\\':
+			escaped = true
+		case ch == '%':
+			sb.WriteString(".*")
+		case ch == '_':
+			sb.WriteString(".")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+	if escaped {
+		return nil, fmt.Errorf("LIKE pattern '%s' ends with a dangling escape", pattern)
+	}
+
+	sb.WriteString("$")
+	return regexp.Compile(sb.String())
+}
+
+// matchLike reports whether a row value matches the condition's LIKE pattern.
+func matchLike(cond *WhereCondition, value string) bool {
+	re := cond.likeRegex
+	if re == nil {
+		// Conditions built outside ParseSQL won't have the pattern compiled yet
+		var err error
+		re, err = compileLikePattern(cond.Value)
+		if err != nil {
+			return false
+		}
+	}
+	return re.MatchString(value)
+}
+
+// likePrefix splits a LIKE pattern into its literal (unescaped) prefix and
+// whatever follows the first wildcard. "Al%" -> ("Al", "%").
+func likePrefix(pattern string) (string, string) {
+	var prefix strings.Builder
+	escaped := false
+	for i, ch := range pattern {
+		switch {
+		case escaped:
+			prefix.WriteRune(ch)
+			escaped = false
+		case ch == '\\':
+			escaped = true
+		case ch == '%' || ch == '_':
+			return prefix.String(), pattern[i:]
+		default:
+			prefix.WriteRune(ch)
+		}
+	}
+	return prefix.String(), ""
+}
+
+// isLikeSubset handles the semantic subset check when LIKE is involved.
+// We only prove containment for a cached "prefix%" pattern: any new LIKE or
+// equality whose fixed prefix starts with that prefix is a subset.
+// e.g. cached "name LIKE 'A%'" serves "name LIKE 'Al%'" and "name = 'Alice'".
+func isLikeSubset(newCond, cachedCond *WhereCondition) bool {
+	if cachedCond.Operator != "LIKE" {
+		return false
+	}
+	cachedPrefix, cachedRest := likePrefix(cachedCond.Value)
+	if cachedRest != "%" {
+		return false // Only trailing-wildcard patterns are reasoned about
+	}
+
+	var newPrefix string
+	switch newCond.Operator {
+	case "LIKE":
+		newPrefix, _ = likePrefix(newCond.Value)
+	case "=":
+		newPrefix = newCond.Value
+	default:
+		return false
+	}
+	return strings.HasPrefix(strings.ToLower(newPrefix), strings.ToLower(cachedPrefix))
+}
@@ -0,0 +1,75 @@
+package command
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLikePatternsAreAnchored(t *testing.T) {
+	cases := []struct {
+		pattern, value string
+		want           bool
+	}{
+		{"A%", "Alice", true},
+		{"A%", "Karl", false}, // 'a' only in the middle
+		{"%e", "Alice", true},
+		{"%e", "Eve!", false},
+		{"_ve", "Eve", true},
+		{"_ve", "Steve", false},
+		{"al", "Alice", false}, // no wildcard: the whole value must match
+		{"ALICE", "alice", true},
+	}
+	for _, c := range cases {
+		re, err := compileLikePattern(c.pattern)
+		if err != nil {
+			t.Fatalf("%q: %v", c.pattern, err)
+		}
+		if got := re.MatchString(c.value); got != c.want {
+			t.Errorf("%q LIKE %q = %v, want %v", c.value, c.pattern, got, c.want)
+		}
+	}
+}
+
+func TestLikeEscapedWildcardsAreLiteral(t *testing.T) {
+	cases := []struct {
+		pattern, value string
+		want           bool
+	}{
+		{`50\%`, "50%", true},
+		{`50\%`, "500", false},
+		{`50\%%`, "50% off", true},
+		{`50\%%`, "500 off", false},
+		{`a\_b`, "a_b", true},
+		{`a\_b`, "axb", false},
+		{`a.c`, "abc", false}, // regex metacharacters are literal too
+	}
+	for _, c := range cases {
+		re, err := compileLikePattern(c.pattern)
+		if err != nil {
+			t.Fatalf("%q: %v", c.pattern, err)
+		}
+		if got := re.MatchString(c.value); got != c.want {
+			t.Errorf("%q LIKE %q = %v, want %v", c.value, c.pattern, got, c.want)
+		}
+	}
+	if _, err := compileLikePattern(`50\`); err == nil {
+		t.Error("a dangling escape compiled without error")
+	}
+}
+
+func TestLikeQueries(t *testing.T) {
+	resetSQL(t)
+
+	results := queryRows(t, "SELECT name FROM users WHERE name LIKE 'a%'")
+	if got, want := column(results, "name"), []interface{}{"Alice"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("LIKE 'a%%': got %v, want %v", got, want)
+	}
+
+	cacheOutcome(t, "SELECT * FROM users WHERE name LIKE 'A%'")
+	if got := cacheOutcome(t, "SELECT * FROM users WHERE name LIKE 'Al%'"); got != "semantic" {
+		t.Errorf("LIKE 'Al%%' from a cached LIKE 'A%%' was a %s, want semantic", got)
+	}
+	if got := cacheOutcome(t, "SELECT * FROM users WHERE name LIKE '%li%'"); got != "miss" {
+		t.Errorf("LIKE '%%li%%' from a cached LIKE 'A%%' was a %s, want miss", got)
+	}
+}
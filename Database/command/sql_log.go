@@ -0,0 +1,77 @@
+package command
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// queryLogLevel is the minimum level the query logger emits. Every answered
+// SELECT, write and schema change is logged at INFO, risky statements such
+// as a DELETE without WHERE at WARN; cache internals (the superset used for
+// a semantic hit, index use) are logged at DEBUG.
+var queryLogLevel = new(slog.LevelVar)
+
+// queryLog receives one structured record per SQL statement.
+var queryLog = newQueryLogger(os.Stdout)
+
+func newQueryLogger(w io.Writer) *slog.Logger {
+	return slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{Level: queryLogLevel}))
+}
+
+// SetQueryLogLevel sets the query log level by name: DEBUG, INFO, WARN,
+// ERROR, or OFF to silence query logging entirely (e.g. for benchmarks).
+func SetQueryLogLevel(name string) error {
+	if strings.EqualFold(name, "OFF") {
+		queryLogLevel.Set(slog.Level(math.MaxInt))
+		return nil
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(name)); err != nil {
+		return fmt.Errorf("unknown log level '%s'", name)
+	}
+	queryLogLevel.Set(level)
+	return nil
+}
+
+func (o queryOutcome) String() string {
+	switch o {
+	case outcomeDirectHit:
+		return "direct"
+	case outcomeSemanticHit:
+		return "semantic"
+	case outcomeNoCache:
+		return "nocache"
+	}
+	return "miss"
+}
+
+// logQuery emits the record for one answered SELECT.
+func logQuery(c net.Conn, query string, outcome queryOutcome, elapsed time.Duration, table string, rows int) {
+	queryLog.Info("query",
+		"query", query,
+		"outcome", outcome.String(),
+		"latency", elapsed,
+		"table", table,
+		"rows", rows,
+		"remote", remoteAddr(c),
+	)
+}
+
+// remoteAddr returns the client's address, or "" for a connection without
+// one.
+func remoteAddr(c net.Conn) string {
+	if c == nil {
+		return ""
+	}
+	addr := c.RemoteAddr()
+	if addr == nil {
+		return ""
+	}
+	return addr.String()
+}
@@ -0,0 +1,128 @@
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+)
+
+// clientConn is a recordConn with a client address, for the remote field.
+type clientConn struct {
+	recordConn
+}
+
+func (c *clientConn) RemoteAddr() net.Addr {
+	return &net.TCPAddr{IP: net.IPv4(10, 0, 0, 7), Port: 51234}
+}
+
+// captureQueryLog sends query log records to a buffer as JSON lines until
+// the test ends.
+func captureQueryLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	saved, savedLevel := queryLog, queryLogLevel.Level()
+	queryLog = slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: queryLogLevel}))
+	queryLogLevel.Set(slog.LevelInfo)
+	t.Cleanup(func() {
+		queryLog = saved
+		queryLogLevel.Set(savedLevel)
+	})
+	return &buf
+}
+
+func TestQueryLogRecords(t *testing.T) {
+	resetSQL(t)
+	buf := captureQueryLog(t)
+	c := &clientConn{}
+	const query = "SELECT name FROM users WHERE age > 90"
+	HandleSQL([]string{"SQL", query}, c)
+	HandleSQL([]string{"SQL", query}, c)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d log records, want 2:\n%s", len(lines), buf.String())
+	}
+	for i, outcome := range []string{"miss", "direct"} {
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(lines[i]), &record); err != nil {
+			t.Fatalf("record %d isn't JSON: %v\n%s", i, err, lines[i])
+		}
+		want := map[string]interface{}{
+			"level":   "INFO",
+			"msg":     "query",
+			"query":   query,
+			"outcome": outcome,
+			"table":   "users",
+			"rows":    float64(3),
+			"remote":  "10.0.0.7:51234",
+		}
+		for field, value := range want {
+			if record[field] != value {
+				t.Errorf("%s record: %s = %v, want %v", outcome, field, record[field], value)
+			}
+		}
+		if latency, ok := record["latency"].(float64); !ok || latency <= 0 {
+			t.Errorf("%s record: latency = %v, want a positive duration", outcome, record["latency"])
+		}
+	}
+}
+
+func TestQueryLogLevelOffSilencesQueries(t *testing.T) {
+	resetSQL(t)
+	buf := captureQueryLog(t)
+	if err := SetQueryLogLevel("OFF"); err != nil {
+		t.Fatal(err)
+	}
+	mustSQL(t, "SELECT * FROM products")
+	if buf.Len() != 0 {
+		t.Errorf("logged with the level OFF:\n%s", buf.String())
+	}
+	if err := SetQueryLogLevel("LOUD"); err == nil {
+		t.Error("SetQueryLogLevel accepted an unknown level")
+	}
+}
+
+func TestQueryLogRecordsWrites(t *testing.T) {
+	resetSQL(t)
+	buf := captureQueryLog(t)
+	mustSQL(t, "SELECT * FROM users WHERE age > 90")
+	mustSQL(t, "UPDATE users SET age = 98 WHERE id = 7")
+	mustSQL(t, "DELETE FROM products")
+
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("record isn't JSON: %v\n%s", err, line)
+		}
+		records = append(records, record)
+	}
+	want := []map[string]interface{}{
+		{"level": "INFO", "msg": "query"},
+		{"level": "INFO", "msg": "update", "rows": float64(1), "invalidated": float64(1)},
+		{"level": "WARN", "table": "products"},
+		{"level": "INFO", "msg": "delete", "rows": float64(3), "invalidated": float64(0)},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("got %d log records, want %d:\n%s", len(records), len(want), buf.String())
+	}
+	for i, fields := range want {
+		for field, value := range fields {
+			if records[i][field] != value {
+				t.Errorf("record %d: %s = %v, want %v", i, field, records[i][field], value)
+			}
+		}
+	}
+
+	buf.Reset()
+	SetQueryLogLevel("OFF")
+	mustSQL(t, "INSERT INTO users (id, name, age) VALUES (16, 'Zed', 30)")
+	mustSQL(t, "CREATE TABLE notes (id INT)")
+	runCommand(t, "SQLINVALIDATE", "users")
+	if buf.Len() != 0 {
+		t.Errorf("logged with the level OFF:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,250 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Table and column names are matched case-insensitively: a query's names are
+// rewritten to the names the schema declares before anything else looks at
+// them, so results (and cache entries) always use the declared spelling.
+// Names that don't exist in the schema are rejected at the same point.
+
+// lookupTable finds a table by name, preferring an exact match and falling
+// back to a case-insensitive one ("USERS" finds "users"). "<db>.<table>"
+// looks in the named database, a bare name in the default one.
+// NOTE: This function is not thread-safe, callers must hold dbMutex!
+func lookupTable(name string) (*Table, bool) {
+	tables, key, _, err := tableSlot(name)
+	if err != nil {
+		return nil, false
+	}
+	if table, ok := tables[key]; ok {
+		return table, true
+	}
+	for k, table := range tables {
+		if strings.EqualFold(k, key) {
+			return table, true
+		}
+	}
+	return nil, false
+}
+
+// canonicalColumn returns the declared spelling of name from cols, or name
+// unchanged if no column matches.
+func canonicalColumn(cols []string, name string) string {
+	if containsColumn(cols, name) {
+		return name
+	}
+	for _, col := range cols {
+		if strings.EqualFold(col, name) {
+			return col
+		}
+	}
+	return name
+}
+
+// containsColumnFold is containsColumn ignoring case.
+func containsColumnFold(cols []string, col string) bool {
+	for _, c := range cols {
+		if strings.EqualFold(c, col) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveColumn returns the declared spelling of name, or an error naming
+// the table if no such column exists.
+func resolveColumn(cols []string, name, table string) (string, error) {
+	col := canonicalColumn(cols, name)
+	if !containsColumn(cols, col) {
+		return "", fmt.Errorf("unknown column '%s' in table '%s'", name, table)
+	}
+	return col, nil
+}
+
+// canonicalizeWhere rewrites every column in a WHERE tree to its declared
+// spelling, failing on the first column the table doesn't have. Subqueries
+// are resolved against their own tables.
+// NOTE: This function is not thread-safe, callers must hold dbMutex!
+func canonicalizeWhere(node *WhereNode, cols []string, table string) error {
+	if node == nil {
+		return nil
+	}
+	if node.Cond != nil {
+		col, err := resolveColumn(cols, node.Cond.Column, table)
+		if err != nil {
+			return err
+		}
+		node.Cond.Column = col
+		if node.Cond.ValueColumn != "" {
+			// A bare word that isn't a column is still a string literal,
+			// e.g. "status = active"
+			other := canonicalColumn(cols, node.Cond.ValueColumn)
+			if !containsColumn(cols, other) {
+				other = ""
+			}
+			node.Cond.ValueColumn = other
+		}
+		if node.Cond.Subquery != nil {
+			if err := resolveQueryNamesLocked(node.Cond.Subquery); err != nil {
+				return err
+			}
+		}
+	}
+	if err := canonicalizeWhere(node.Left, cols, table); err != nil {
+		return err
+	}
+	return canonicalizeWhere(node.Right, cols, table)
+}
+
+// resolveQueryNames rewrites the table and column names of a parsed SELECT
+// to the spelling used by the schema, and rejects columns that don't exist
+// (otherwise a typo would just produce empty results).
+func resolveQueryNames(query *QueryAST) error {
+	dbMutex.RLock()
+	defer dbMutex.RUnlock()
+	return resolveQueryNamesLocked(query)
+}
+
+// resolveQueryNamesLocked implements resolveQueryNames for callers that
+// already hold dbMutex.
+func resolveQueryNamesLocked(query *QueryAST) error {
+	table, ok := lookupTable(query.FromTable)
+	if !ok {
+		return fmt.Errorf("table '%s' not found", query.FromTable)
+	}
+	query.FromTable = table.Name
+	cols := table.Columns
+	source := table.Name
+
+	if query.Join != nil {
+		right, ok := lookupTable(query.Join.Table)
+		if !ok {
+			return fmt.Errorf("table '%s' not found", query.Join.Table)
+		}
+		query.Join.Table = right.Name
+		cols = joinedColumnNames(query, table, right)
+		source = table.Name + " JOIN " + right.Name
+		// Bad ON columns are reported by joinTables, which knows which side is which
+		query.Join.LeftColumn = canonicalColumn(cols, query.Join.LeftColumn)
+		query.Join.RightColumn = canonicalColumn(cols, query.Join.RightColumn)
+	}
+
+	for i, col := range query.SelectColumns {
+		if col == "*" || query.isAggregateColumn(col) || query.isExpressionColumn(col) {
+			continue
+		}
+		resolved, err := resolveColumn(cols, col, source)
+		if err != nil {
+			return err
+		}
+		query.SelectColumns[i] = resolved
+	}
+	for _, agg := range query.Aggregates {
+		if agg.Column == "*" {
+			continue
+		}
+		resolved, err := resolveColumn(cols, agg.Column, source)
+		if err != nil {
+			return err
+		}
+		// Keep the select-list name of the aggregate in step with its column
+		before := agg.String()
+		agg.Column = resolved
+		for j, col := range query.SelectColumns {
+			if col == before {
+				query.SelectColumns[j] = agg.String()
+			}
+		}
+	}
+	for _, expr := range query.Expressions {
+		// As for aggregates, the select-list name follows the resolved columns
+		before := expr.String()
+		if err := expr.resolveColumns(cols, source); err != nil {
+			return err
+		}
+		for j, col := range query.SelectColumns {
+			if col == before {
+				query.SelectColumns[j] = expr.String()
+			}
+		}
+	}
+	for i, col := range query.GroupBy {
+		resolved, err := resolveColumn(cols, col, source)
+		if err != nil {
+			return err
+		}
+		query.GroupBy[i] = resolved
+	}
+	if query.OrderBy != nil && !query.isAggregateColumn(query.OrderBy.Column) {
+		resolved, err := resolveColumn(cols, query.OrderBy.Column, source)
+		if err != nil {
+			return err
+		}
+		query.OrderBy.Column = resolved
+	}
+	if err := canonicalizeHaving(query, cols, source); err != nil {
+		return err
+	}
+	return canonicalizeWhere(query.Where, cols, source)
+}
+
+// canonicalizeHaving resolves the columns of the HAVING aggregates and then
+// the HAVING condition, where a column is either an aggregate's name or a
+// GROUP BY column.
+func canonicalizeHaving(query *QueryAST, cols []string, source string) error {
+	if query.Having == nil {
+		return nil
+	}
+	names := append([]string(nil), cols...)
+	for _, agg := range query.HavingAggs {
+		before := agg.String()
+		if agg.Column != "*" {
+			resolved, err := resolveColumn(cols, agg.Column, source)
+			if err != nil {
+				return err
+			}
+			agg.Column = resolved
+		}
+		renameWhereColumn(query.Having, before, agg.String())
+		names = append(names, agg.String())
+	}
+	for _, agg := range query.Aggregates {
+		names = append(names, agg.String())
+	}
+	return canonicalizeWhere(query.Having, names, source)
+}
+
+// renameWhereColumn replaces every condition column spelled from with to.
+func renameWhereColumn(node *WhereNode, from, to string) {
+	if node == nil {
+		return
+	}
+	if node.Cond != nil && node.Cond.Column == from {
+		node.Cond.Column = to
+	}
+	renameWhereColumn(node.Left, from, to)
+	renameWhereColumn(node.Right, from, to)
+}
+
+// joinedColumnNames lists every name a joined row can be addressed by: the
+// qualified "<alias>.<col>" names plus the columns unique to one table.
+func joinedColumnNames(query *QueryAST, left, right *Table) []string {
+	leftAlias, rightAlias := query.qualifiers()
+	var cols []string
+	for _, col := range left.Columns {
+		cols = append(cols, leftAlias+"."+col)
+		if !containsColumn(right.Columns, col) {
+			cols = append(cols, col)
+		}
+	}
+	for _, col := range right.Columns {
+		cols = append(cols, rightAlias+"."+col)
+		if !containsColumn(left.Columns, col) {
+			cols = append(cols, col)
+		}
+	}
+	return cols
+}
@@ -0,0 +1,54 @@
+package command
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNamesAreCaseInsensitive(t *testing.T) {
+	resetSQL(t)
+
+	results := queryRows(t, "SELECT NAME, Age FROM USERS WHERE AGE > 90 ORDER BY aGe DESC")
+	if got, want := results.Columns, []string{"name", "age"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("columns = %v, want the declared spelling %v", got, want)
+	}
+	if got, want := column(results, "name"), []interface{}{"Grace", "Nina", "Mike"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("names = %v, want %v", got, want)
+	}
+
+	// The reply shows the declared names too
+	reply := mustSQL(t, "SELECT Item FROM Products WHERE STOCK > 300")
+	if !strings.Contains(reply, "item") || strings.Contains(reply, "Item") {
+		t.Errorf("reply doesn't use the declared column name:\n%s", reply)
+	}
+
+	mustSQL(t, "CREATE TABLE Metrics (Host TEXT, Value INT)")
+	mustSQL(t, "INSERT INTO METRICS (host, VALUE) VALUES ('web', 3)")
+	results = queryRows(t, "SELECT host FROM metrics WHERE value = 3")
+	if got, want := results.Columns, []string{"Host"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("columns = %v, want %v", got, want)
+	}
+	if got := column(results, "Host"); !reflect.DeepEqual(got, []interface{}{"web"}) {
+		t.Errorf("hosts = %v, want [web]", got)
+	}
+}
+
+func TestUnknownColumnsAreErrors(t *testing.T) {
+	resetSQL(t)
+	const want = "-ERR unknown column 'salary' in table 'users'\r\n"
+	for _, q := range []string{
+		"SELECT salary FROM users",
+		"SELECT name, salary FROM users",
+		"SELECT * FROM users WHERE salary > 3",
+		"SELECT name FROM users WHERE age > 1 OR salary = 2",
+		"SELECT name FROM users ORDER BY salary",
+	} {
+		if got := runSQL(t, &recordConn{}, q); got != want {
+			t.Errorf("%s: replied %q, want %q", q, got, want)
+		}
+	}
+	if SQLCache.Len() != 0 {
+		t.Errorf("failed queries left %d cache entries", SQLCache.Len())
+	}
+}
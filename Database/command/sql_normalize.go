@@ -0,0 +1,100 @@
+package command
+
+import "strings"
+
+// sqlKeywords are the words normalizeQueryKey upper-cases.
+var sqlKeywords = map[string]bool{
+	"SELECT": true, "DISTINCT": true, "FROM": true, "AS": true, "JOIN": true, "INNER": true, "ON": true,
+	"WHERE": true, "AND": true, "OR": true, "NOT": true, "IN": true, "LIKE": true, "BETWEEN": true,
+	"IS": true, "NULL": true, "GROUP": true, "HAVING": true, "ORDER": true, "BY": true, "ASC": true, "DESC": true,
+	"LIMIT": true, "OFFSET": true, "COUNT": true, "SUM": true, "AVG": true, "MIN": true, "MAX": true,
+}
+
+// normalizeQueryKey rewrites a query into the form the cache stores it
+// under, so formatting variants such as "select  *  from USERS;" and
+// "SELECT * FROM users" share one entry: tokens are separated by single
+// spaces, a trailing semicolon is dropped, keywords are upper-cased and
+// table and column names lower-cased (names are matched case-insensitively
+// anyway). Words whose case can change the result are kept as written:
+// unquoted values (an unknown word is a string), AS aliases, which become
+// the result headers, and table aliases, which name joined columns. A
+// query the tokenizer rejects is only trimmed.
+func normalizeQueryKey(query string) string {
+	query = strings.TrimRight(strings.TrimSpace(query), "; \t\r\n")
+	tokens, err := tokenizeSQL(query)
+	if err != nil {
+		return query
+	}
+	tokens = tokens[:len(tokens)-1] // Drop tokEOF
+
+	parts := make([]string, 0, len(tokens))
+	clause := ""    // The last clause keyword seen, e.g. "FROM" or "WHERE"
+	inList := false // Inside the parentheses of an IN list
+	for i, tok := range tokens {
+		switch tok.Kind {
+		case tokString:
+			parts = append(parts, quoteSQLLiteral(tok.Text))
+			continue
+		case tokLParen:
+			inList = i > 0 && strings.EqualFold(tokens[i-1].Text, "IN")
+		case tokRParen:
+			inList = false
+		}
+		if tok.Kind != tokIdent {
+			parts = append(parts, tok.Text)
+			continue
+		}
+
+		if inList || isValuePosition(tokens, i) {
+			parts = append(parts, tok.Text)
+			continue
+		}
+		upper := strings.ToUpper(tok.Text)
+		if sqlKeywords[upper] {
+			if upper != "AS" && upper != "AND" && upper != "OR" && upper != "NOT" {
+				clause = upper
+			}
+			parts = append(parts, upper)
+			continue
+		}
+		// In FROM and JOIN the word after the table name is its alias
+		if (clause == "FROM" || clause == "JOIN") && i > 0 && tokens[i-1].Kind == tokIdent &&
+			!sqlKeywords[strings.ToUpper(tokens[i-1].Text)] {
+			parts = append(parts, tok.Text)
+			continue
+		}
+		parts = append(parts, strings.ToLower(tok.Text))
+	}
+	return strings.Join(parts, " ")
+}
+
+// isValuePosition reports whether tokens[i] stands where a value goes: after
+// a comparison operator, LIKE or AS, or as a BETWEEN bound.
+func isValuePosition(tokens []sqlToken, i int) bool {
+	if i == 0 {
+		return false
+	}
+	prev := tokens[i-1]
+	if prev.Kind == tokOperator {
+		return true
+	}
+	if prev.Kind != tokIdent {
+		return false
+	}
+	switch strings.ToUpper(prev.Text) {
+	case "AS", "LIKE", "BETWEEN":
+		return true
+	case "AND":
+		return i >= 3 && strings.EqualFold(tokens[i-3].Text, "BETWEEN")
+	}
+	return false
+}
+
+// quoteSQLLiteral quotes a string literal for normalizeQueryKey, using
+// single quotes unless the text contains one.
+func quoteSQLLiteral(text string) string {
+	if strings.Contains(text, "'") {
+		return `"` + text + `"`
+	}
+	return "'" + text + "'"
+}
@@ -0,0 +1,43 @@
+package command
+
+import "testing"
+
+func TestNormalizeQueryKey(t *testing.T) {
+	cases := []struct{ query, want string }{
+		{"select  *  from USERS;", "SELECT * FROM users"},
+		{"  SELECT Name,AGE FROM users\n\tWHERE age>40 ;; ", "SELECT name , age FROM users WHERE age > 40"},
+		{"select * from server_logs where status = 'OK'", "SELECT * FROM server_logs WHERE status = 'OK'"},
+		{"select * from server_logs where status = OK", "SELECT * FROM server_logs WHERE status = OK"},
+		{"select name as Who from users", "SELECT name AS Who FROM users"},
+		{"select * from users where name in ('Bob', Eve)", "SELECT * FROM users WHERE name IN ( 'Bob' , Eve )"},
+	}
+	for _, c := range cases {
+		if got := normalizeQueryKey(c.query); got != c.want {
+			t.Errorf("normalizeQueryKey(%q) = %q, want %q", c.query, got, c.want)
+		}
+	}
+}
+
+func TestFormattingVariantsShareACacheEntry(t *testing.T) {
+	resetSQL(t)
+	cacheOutcome(t, "SELECT * FROM users WHERE age > 40")
+	for _, variant := range []string{
+		"select * from users where age > 40",
+		"SELECT  *  FROM  users  WHERE  age>40",
+		"Select * From USERS Where Age > 40;",
+		"\tSELECT *\nFROM users\nWHERE age > 40 ; ",
+	} {
+		if got := cacheOutcome(t, variant); got != "direct" {
+			t.Errorf("%q was a %s, want direct", variant, got)
+		}
+	}
+	if n := SQLCache.Len(); n != 1 {
+		t.Errorf("%d cache entries for one query's variants, want 1", n)
+	}
+
+	// A value's case can change the rows, so it gets an entry of its own
+	cacheOutcome(t, "SELECT * FROM server_logs WHERE status = 'OK'")
+	if got := cacheOutcome(t, "SELECT * FROM server_logs WHERE status = 'ok'"); got == "direct" {
+		t.Errorf("query for 'ok' was a direct hit on the entry for 'OK'")
+	}
+}
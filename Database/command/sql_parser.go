@@ -3,6 +3,7 @@ package command
 import (
 	"errors"
 	"fmt"
+	"math"
 	"regexp"
 	"strconv"
 	"strings"
@@ -12,72 +13,1208 @@ import (
 type QueryAST struct {
 	OriginalString string
 	SelectColumns  []string
+	ColumnAliases  []string         // "AS" name for each SelectColumns entry, "" if none; nil without aliases
+	Distinct       bool             // SELECT DISTINCT: drop rows whose selected values repeat
+	Aggregates     []*AggregateExpr // Aggregate calls from the select list, also named in SelectColumns
+	Expressions    []*ArithExpr     // Computed select-list items such as "stock * 2", also named in SelectColumns
 	FromTable      string
-	Where          *WhereCondition
+	FromAlias      string      // Optional alias after the FROM table, e.g. "a"
+	Join           *JoinClause // nil unless the query joins a second table
+	Where          *WhereNode
+	GroupBy        []string
+	Having         *WhereNode       // Condition on the grouped rows; nil without HAVING
+	HavingAggs     []*AggregateExpr // Aggregate calls from HAVING, computed per group like Aggregates
+	OrderBy        *OrderByClause
+	Limit          int
+	HasLimit       bool // false means "no LIMIT clause", since LIMIT 0 is valid
+	Offset         int
+	Params         []paramSlot // "?" placeholders in order, only set by PrepareSQL
+}
+
+// outputHeaders returns the display names for a result table's columns:
+// the AS alias where one was given, the column name otherwise. nil means the
+// column names can be used as they are.
+func (ast *QueryAST) outputHeaders(results *Table) []string {
+	if ast.ColumnAliases == nil || results == nil || len(results.Columns) != len(ast.ColumnAliases) {
+		return nil
+	}
+	headers := make([]string, len(results.Columns))
+	for i, col := range results.Columns {
+		headers[i] = col
+		if ast.ColumnAliases[i] != "" {
+			headers[i] = ast.ColumnAliases[i]
+		}
+	}
+	return headers
+}
+
+// OrderByClause is the optional "ORDER BY <col> [ASC|DESC]" suffix.
+type OrderByClause struct {
+	Column string
+	Desc   bool
+}
+
+// WhereNode is one node of a WHERE clause expression tree.
+// Leaves hold a single Cond; inner nodes join Left and Right with Op ("AND" or "OR"),
+// except "NOT" nodes, which negate Left and have no Right.
+type WhereNode struct {
+	Op    string
+	Left  *WhereNode
+	Right *WhereNode
+	Cond  *WhereCondition
 }
 
 // WhereCondition represents the simple "col op val" condition.
 type WhereCondition struct {
 	Column   string
 	Operator string
-	Value    string // Store as string initially
+	Value    string   // Store as string initially
+	Values   []string // The element list for IN, or [low, high] for BETWEEN
+	// ValueColumn names a second column to compare against instead of Value,
+	// e.g. "cpu_load > threshold". Empty for comparisons with a literal.
+	ValueColumn string
+	// Subquery is the SELECT of "col IN (SELECT ...)"; its results take the
+	// place of Values when the query runs (see bindSubqueries).
+	Subquery *QueryAST
+
+	likeRegex *regexp.Regexp      // Compiled pattern, only set for LIKE
+	valueSet  map[string]struct{} // literalKey of each subquery value, set by bindSubqueries
 }
 
-// Regex to parse "SELECT <cols> FROM <table> WHERE <col> <op> <val>"
-// It's simplified and assumes 'WHERE' is present.
-var sqlRegex = regexp.MustCompile(`(?i)SELECT\s+(.+)\s+FROM\s+([^\s]+)\s+WHERE\s+([^\s]+)\s*([<>=])\s*(.+)`)
+// InsertAST represents a parsed "INSERT INTO <table> [(cols)] VALUES (vals)" statement.
+type InsertAST struct {
+	Table   string
+	Columns []string      // Empty means "every table column, in table order"
+	Values  []interface{} // int for numeric literals, string otherwise, nil for NULL
+}
 
-// Regex for queries without a WHERE clause
-var sqlRegexNoWhere = regexp.MustCompile(`(?i)SELECT\s+(.+)\s+FROM\s+([^\s]+)`)
+// UpdateAST represents a parsed "UPDATE <table> SET <col> = <val>, ... [WHERE <expr>]" statement.
+type UpdateAST struct {
+	Table       string
+	Assignments []Assignment
+	Where       *WhereNode // nil updates every row
+}
+
+// Assignment is one "<col> = <val>" pair from an UPDATE's SET list.
+type Assignment struct {
+	Column string
+	Value  interface{} // int for numeric literals, string otherwise, nil for NULL
+}
 
+// DeleteAST represents a parsed "DELETE FROM <table> [WHERE <expr>]" statement.
+type DeleteAST struct {
+	Table string
+	Where *WhereNode // nil deletes every row
+}
+
+// CreateTableAST represents a parsed "CREATE TABLE <table> (<col> <type>, ...)" statement.
+type CreateTableAST struct {
+	Table   string
+	Columns []ColumnDef
+}
+
+// DropTableAST represents a parsed "DROP TABLE [IF EXISTS] <table>" statement.
+type DropTableAST struct {
+	Table    string
+	IfExists bool // Dropping a missing table is not an error
+}
+
+// CreateDatabaseAST represents a parsed "CREATE DATABASE <name>" statement.
+type CreateDatabaseAST struct {
+	Name string
+}
+
+// DropDatabaseAST represents a parsed "DROP DATABASE [IF EXISTS] <name>" statement.
+type DropDatabaseAST struct {
+	Name     string
+	IfExists bool // Dropping a missing database is not an error
+}
+
+// CreateIndexAST represents a parsed "CREATE INDEX [name] ON <table> (<col>)" statement.
+type CreateIndexAST struct {
+	Name   string // Optional, only used for logging
+	Table  string
+	Column string
+}
+
+// ColumnDef is one column declaration from a CREATE TABLE statement.
+type ColumnDef struct {
+	Name string
+	Type string // Normalized to "INT" or "TEXT"
+}
+
+// sqlParser walks the token stream produced by tokenizeSQL.
+type sqlParser struct {
+	tokens []sqlToken
+	pos    int
+
+	allowParams bool        // Accept "?" placeholders (PrepareSQL only)
+	params      []paramSlot // Placeholders seen so far, in order
+
+	inHaving   bool             // Parsing HAVING, where conditions may test aggregates
+	havingAggs []*AggregateExpr // Aggregate calls seen in HAVING so far
+	inSubquery bool             // Parsing the SELECT inside "IN (...)"
+}
+
+// addParam records a "?" placeholder standing for cond's Value (index -1)
+// or for cond.Values[index].
+func (p *sqlParser) addParam(cond *WhereCondition, index int) error {
+	if !p.allowParams {
+		return errors.New("placeholders (?) are only allowed in SQLPREPARE statements")
+	}
+	if p.inSubquery {
+		return errors.New("placeholders (?) are not supported inside subqueries")
+	}
+	p.params = append(p.params, paramSlot{cond: cond, index: index})
+	return nil
+}
+
+func (p *sqlParser) peek() sqlToken {
+	return p.tokens[p.pos]
+}
+
+func (p *sqlParser) next() sqlToken {
+	tok := p.tokens[p.pos]
+	if tok.Kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// peekKeyword reports whether the next token is the given keyword (case-insensitive).
+func (p *sqlParser) peekKeyword(keyword string) bool {
+	tok := p.peek()
+	return tok.Kind == tokIdent && strings.EqualFold(tok.Text, keyword)
+}
+
+// acceptKeyword consumes the next token if it is the given keyword.
+func (p *sqlParser) acceptKeyword(keyword string) bool {
+	if p.peekKeyword(keyword) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// ParseSQL parses
+//
+//	SELECT [DISTINCT] <cols> FROM <table> [alias] [[INNER] JOIN <table> [alias] ON <col> = <col>]
+//	    [WHERE <expr>] [GROUP BY <cols> [HAVING <expr>]] [ORDER BY <col> [ASC|DESC]] [LIMIT n [OFFSET m]]
+//
+// into a QueryAST. <cols> may mix plain columns with aggregate calls such as
+// COUNT(*), COUNT(DISTINCT server_name) or SUM(cpu_load) and arithmetic on
+// columns and numbers, such as stock * 2 or (a + b) / 2.
+// <expr> is any mix of "col op val" predicates joined by AND / OR, with
+// parentheses for grouping. AND binds tighter than OR. In HAVING the
+// columns may also be aggregate calls. An IN list may be a one-column
+// subquery, "col IN (SELECT ...)".
+// A SELECT without FROM must be rewritten first (see withDefaultTable).
 func ParseSQL(input string) (*QueryAST, error) {
+	return parseSelect(input, false)
+}
+
+// parseSelect implements ParseSQL and PrepareSQL; only the latter allows
+// "?" placeholders.
+func parseSelect(input string, allowParams bool) (*QueryAST, error) {
 	// Trim trailing semicolon if present
 	input = strings.TrimSpace(input)
 	if strings.HasSuffix(input, ";") {
 		input = input[:len(input)-1]
 	}
 
-	ast := &QueryAST{OriginalString: input}
+	tokens, err := tokenizeSQL(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &sqlParser{tokens: tokens, allowParams: allowParams}
+
+	ast, err := p.parseSelectBody()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.Kind != tokEOF {
+		return nil, fmt.Errorf("unexpected '%s' at end of query", tok.Text)
+	}
+
+	ast.OriginalString = input
+	ast.Params = p.params
+	return ast, nil
+}
+
+// parseSelectBody reads a SELECT statement up to the first token that can't
+// continue it: the end of the query, or the ")" closing a subquery.
+func (p *sqlParser) parseSelectBody() (*QueryAST, error) {
+	ast := &QueryAST{}
+	var err error
+
+	if !p.acceptKeyword("SELECT") {
+		return nil, fmt.Errorf("expected SELECT at the start of the query, got %s", p.peek().describe())
+	}
+	ast.Distinct = p.acceptKeyword("DISTINCT")
+
+	ast.SelectColumns, ast.ColumnAliases, ast.Aggregates, ast.Expressions, err = p.parseSelectColumns()
+	if err != nil {
+		return nil, err
+	}
 
-	// Try parsing with WHERE clause
-	matches := sqlRegex.FindStringSubmatch(input)
+	if !p.acceptKeyword("FROM") {
+		return nil, fmt.Errorf("expected FROM after column list, got %s", p.peek().describe())
+	}
+	table := p.next()
+	if table.Kind != tokIdent {
+		return nil, fmt.Errorf("expected a table name after FROM, got %s", table.describe())
+	}
+	ast.FromTable = table.Text
+	ast.FromAlias = p.parseAlias()
 
-	if matches != nil {
-		// Matched: SELECT ... FROM ... WHERE ...
-		colStr := strings.TrimSpace(matches[1])
-		if colStr == "*" {
-			ast.SelectColumns = []string{"*"}
-		} else {
-			ast.SelectColumns = strings.Split(strings.ReplaceAll(colStr, " ", ""), ",")
+	if p.peekKeyword("JOIN") || p.peekKeyword("INNER") {
+		ast.Join, err = p.parseJoin()
+		if err != nil {
+			return nil, err
 		}
+	}
 
-		ast.FromTable = strings.TrimSpace(matches[2])
+	if p.acceptKeyword("WHERE") {
+		ast.Where, err = p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+	}
 
-		ast.Where = &WhereCondition{
-			Column:   strings.TrimSpace(matches[3]),
-			Operator: strings.TrimSpace(matches[4]),
-			Value:    strings.Trim(strings.TrimSpace(matches[5]), "'\""), // Remove quotes
+	if p.acceptKeyword("GROUP") {
+		ast.GroupBy, err = p.parseGroupBy()
+		if err != nil {
+			return nil, err
 		}
-	} else {
-		// Try parsing without WHERE clause
-		matchesNoWhere := sqlRegexNoWhere.FindStringSubmatch(input)
-		if matchesNoWhere != nil {
-			// Matched: SELECT ... FROM ...
-			colStr := strings.TrimSpace(matchesNoWhere[1])
-			if colStr == "*" {
-				ast.SelectColumns = []string{"*"}
+	}
+	if p.acceptKeyword("HAVING") {
+		p.inHaving = true
+		ast.Having, err = p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		p.inHaving = false
+		ast.HavingAggs = p.havingAggs
+	}
+	if err := validateGrouping(ast); err != nil {
+		return nil, err
+	}
+
+	if p.acceptKeyword("ORDER") {
+		ast.OrderBy, err = p.parseOrderBy()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if p.acceptKeyword("LIMIT") {
+		ast.Limit, err = p.parseCount("LIMIT")
+		if err != nil {
+			return nil, err
+		}
+		ast.HasLimit = true
+
+		if p.acceptKeyword("OFFSET") {
+			ast.Offset, err = p.parseCount("OFFSET")
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return ast, nil
+}
+
+// parseSubquery reads "SELECT ... )" inside "IN (", up to and including the
+// closing parenthesis. The subquery must select exactly one column, whose
+// values form the IN list.
+func (p *sqlParser) parseSubquery() (*QueryAST, error) {
+	start := p.pos
+	inHaving, havingAggs, inSubquery := p.inHaving, p.havingAggs, p.inSubquery
+	p.inHaving, p.havingAggs, p.inSubquery = false, nil, true
+	sub, err := p.parseSelectBody()
+	p.inHaving, p.havingAggs, p.inSubquery = inHaving, havingAggs, inSubquery
+	if err != nil {
+		return nil, err
+	}
+	if p.next().Kind != tokRParen {
+		return nil, errors.New("missing closing parenthesis after subquery")
+	}
+	if len(sub.SelectColumns) != 1 || sub.SelectColumns[0] == "*" {
+		return nil, errors.New("a subquery in IN must select exactly one column")
+	}
+	sub.OriginalString = joinSQLTokens(p.tokens[start : p.pos-1])
+	return sub, nil
+}
+
+// ParseInsert parses "INSERT INTO <table> [(<cols>)] VALUES (<vals>)".
+func ParseInsert(input string) (*InsertAST, error) {
+	p, err := newStatementParser(input)
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.acceptKeyword("INSERT") || !p.acceptKeyword("INTO") {
+		return nil, errors.New("expected INSERT INTO <table>")
+	}
+	table := p.next()
+	if table.Kind != tokIdent {
+		return nil, errors.New("expected a table name after INSERT INTO")
+	}
+	stmt := &InsertAST{Table: table.Text}
+
+	if p.peek().Kind == tokLParen {
+		p.next()
+		stmt.Columns, err = p.parseIdentList()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !p.acceptKeyword("VALUES") {
+		return nil, errors.New("expected VALUES in INSERT statement")
+	}
+	if p.next().Kind != tokLParen {
+		return nil, errors.New("expected '(' after VALUES")
+	}
+	for {
+		val, err := p.parseLiteral()
+		if err != nil {
+			return nil, err
+		}
+		stmt.Values = append(stmt.Values, val)
+
+		tok := p.next()
+		if tok.Kind == tokRParen {
+			break
+		}
+		if tok.Kind != tokComma {
+			return nil, errors.New("missing closing parenthesis after VALUES list")
+		}
+	}
+
+	if tok := p.peek(); tok.Kind != tokEOF {
+		return nil, fmt.Errorf("unexpected '%s' at end of statement", tok.Text)
+	}
+	return stmt, nil
+}
+
+// ParseUpdate parses "UPDATE <table> SET <col> = <val> {, <col> = <val>} [WHERE <expr>]".
+func ParseUpdate(input string) (*UpdateAST, error) {
+	p, err := newStatementParser(input)
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.acceptKeyword("UPDATE") {
+		return nil, errors.New("expected UPDATE <table>")
+	}
+	table := p.next()
+	if table.Kind != tokIdent {
+		return nil, errors.New("expected a table name after UPDATE")
+	}
+	stmt := &UpdateAST{Table: table.Text}
+
+	if !p.acceptKeyword("SET") {
+		return nil, errors.New("expected SET in UPDATE statement")
+	}
+	for {
+		col := p.next()
+		if col.Kind != tokIdent {
+			return nil, errors.New("expected a column name in SET list")
+		}
+		if op := p.next(); op.Kind != tokOperator || op.Text != "=" {
+			return nil, fmt.Errorf("expected '=' after SET column '%s'", col.Text)
+		}
+		val, err := p.parseLiteral()
+		if err != nil {
+			return nil, err
+		}
+		stmt.Assignments = append(stmt.Assignments, Assignment{Column: col.Text, Value: val})
+
+		if p.peek().Kind != tokComma {
+			break
+		}
+		p.next()
+	}
+
+	if p.acceptKeyword("WHERE") {
+		stmt.Where, err = p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if tok := p.peek(); tok.Kind != tokEOF {
+		return nil, fmt.Errorf("unexpected '%s' at end of statement", tok.Text)
+	}
+	return stmt, nil
+}
+
+// ParseDelete parses "DELETE FROM <table> [WHERE <expr>]".
+func ParseDelete(input string) (*DeleteAST, error) {
+	p, err := newStatementParser(input)
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.acceptKeyword("DELETE") || !p.acceptKeyword("FROM") {
+		return nil, errors.New("expected DELETE FROM <table>")
+	}
+	table := p.next()
+	if table.Kind != tokIdent {
+		return nil, errors.New("expected a table name after DELETE FROM")
+	}
+	stmt := &DeleteAST{Table: table.Text}
+
+	if p.acceptKeyword("WHERE") {
+		stmt.Where, err = p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if tok := p.peek(); tok.Kind != tokEOF {
+		return nil, fmt.Errorf("unexpected '%s' at end of statement", tok.Text)
+	}
+	return stmt, nil
+}
+
+// ParseCreateTable parses "CREATE TABLE <table> (<col> <type> {, <col> <type>})".
+// INT/INTEGER and TEXT/VARCHAR/STRING are accepted as column types.
+func ParseCreateTable(input string) (*CreateTableAST, error) {
+	p, err := newStatementParser(input)
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.acceptKeyword("CREATE") || !p.acceptKeyword("TABLE") {
+		return nil, errors.New("expected CREATE TABLE <table>")
+	}
+	table := p.next()
+	if table.Kind != tokIdent {
+		return nil, errors.New("expected a table name after CREATE TABLE")
+	}
+	stmt := &CreateTableAST{Table: table.Text}
+
+	if p.next().Kind != tokLParen {
+		return nil, errors.New("expected '(' after the table name")
+	}
+	for {
+		name := p.next()
+		if name.Kind != tokIdent {
+			return nil, errors.New("expected a column name in CREATE TABLE")
+		}
+		for _, def := range stmt.Columns {
+			if strings.EqualFold(def.Name, name.Text) {
+				return nil, fmt.Errorf("column '%s' declared more than once", name.Text)
+			}
+		}
+
+		typeTok := p.next()
+		colType, ok := normalizeColumnType(typeTok.Text)
+		if typeTok.Kind != tokIdent || !ok {
+			return nil, fmt.Errorf("unsupported type '%s' for column '%s'", typeTok.Text, name.Text)
+		}
+		stmt.Columns = append(stmt.Columns, ColumnDef{Name: name.Text, Type: colType})
+
+		tok := p.next()
+		if tok.Kind == tokRParen {
+			break
+		}
+		if tok.Kind != tokComma {
+			return nil, errors.New("missing closing parenthesis after column definitions")
+		}
+	}
+
+	if tok := p.peek(); tok.Kind != tokEOF {
+		return nil, fmt.Errorf("unexpected '%s' at end of statement", tok.Text)
+	}
+	return stmt, nil
+}
+
+// ParseCreateIndex parses "CREATE INDEX [name] ON <table> (<col>)".
+func ParseCreateIndex(input string) (*CreateIndexAST, error) {
+	p, err := newStatementParser(input)
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.acceptKeyword("CREATE") || !p.acceptKeyword("INDEX") {
+		return nil, errors.New("expected CREATE INDEX")
+	}
+	stmt := &CreateIndexAST{}
+	if !p.peekKeyword("ON") {
+		name := p.next()
+		if name.Kind != tokIdent {
+			return nil, fmt.Errorf("expected an index name or ON, got %s", name.describe())
+		}
+		stmt.Name = name.Text
+	}
+	if !p.acceptKeyword("ON") {
+		return nil, fmt.Errorf("expected ON after CREATE INDEX, got %s", p.peek().describe())
+	}
+
+	table := p.next()
+	if table.Kind != tokIdent {
+		return nil, errors.New("expected a table name after ON")
+	}
+	stmt.Table = table.Text
+
+	if p.next().Kind != tokLParen {
+		return nil, errors.New("expected '(' after the table name")
+	}
+	col := p.next()
+	if col.Kind != tokIdent {
+		return nil, errors.New("expected a column name to index")
+	}
+	stmt.Column = col.Text
+	if tok := p.next(); tok.Kind != tokRParen {
+		if tok.Kind == tokComma {
+			return nil, errors.New("only single-column indexes are supported")
+		}
+		return nil, errors.New("missing closing parenthesis after the indexed column")
+	}
+
+	if tok := p.peek(); tok.Kind != tokEOF {
+		return nil, fmt.Errorf("unexpected '%s' at end of statement", tok.Text)
+	}
+	return stmt, nil
+}
+
+// ParseDropTable parses "DROP TABLE [IF EXISTS] <table>".
+func ParseDropTable(input string) (*DropTableAST, error) {
+	p, err := newStatementParser(input)
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.acceptKeyword("DROP") || !p.acceptKeyword("TABLE") {
+		return nil, errors.New("expected DROP TABLE <table>")
+	}
+	stmt := &DropTableAST{}
+	if p.acceptKeyword("IF") {
+		if !p.acceptKeyword("EXISTS") {
+			return nil, errors.New("expected EXISTS after IF")
+		}
+		stmt.IfExists = true
+	}
+
+	table := p.next()
+	if table.Kind != tokIdent {
+		return nil, errors.New("expected a table name after DROP TABLE")
+	}
+	stmt.Table = table.Text
+
+	if tok := p.peek(); tok.Kind != tokEOF {
+		return nil, fmt.Errorf("unexpected '%s' at end of statement", tok.Text)
+	}
+	return stmt, nil
+}
+
+// ParseCreateDatabase parses "CREATE DATABASE <name>".
+func ParseCreateDatabase(input string) (*CreateDatabaseAST, error) {
+	p, err := newStatementParser(input)
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.acceptKeyword("CREATE") || !p.acceptKeyword("DATABASE") {
+		return nil, errors.New("expected CREATE DATABASE <name>")
+	}
+	name := p.next()
+	if name.Kind != tokIdent || strings.Contains(name.Text, ".") {
+		return nil, errors.New("expected a database name after CREATE DATABASE")
+	}
+
+	if tok := p.peek(); tok.Kind != tokEOF {
+		return nil, fmt.Errorf("unexpected '%s' at end of statement", tok.Text)
+	}
+	return &CreateDatabaseAST{Name: name.Text}, nil
+}
+
+// ParseDropDatabase parses "DROP DATABASE [IF EXISTS] <name>".
+func ParseDropDatabase(input string) (*DropDatabaseAST, error) {
+	p, err := newStatementParser(input)
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.acceptKeyword("DROP") || !p.acceptKeyword("DATABASE") {
+		return nil, errors.New("expected DROP DATABASE <name>")
+	}
+	stmt := &DropDatabaseAST{}
+	if p.acceptKeyword("IF") {
+		if !p.acceptKeyword("EXISTS") {
+			return nil, errors.New("expected EXISTS after IF")
+		}
+		stmt.IfExists = true
+	}
+
+	name := p.next()
+	if name.Kind != tokIdent || strings.Contains(name.Text, ".") {
+		return nil, errors.New("expected a database name after DROP DATABASE")
+	}
+	stmt.Name = name.Text
+
+	if tok := p.peek(); tok.Kind != tokEOF {
+		return nil, fmt.Errorf("unexpected '%s' at end of statement", tok.Text)
+	}
+	return stmt, nil
+}
+
+// normalizeColumnType maps the accepted spellings of a column type onto "INT" or "TEXT".
+func normalizeColumnType(name string) (string, bool) {
+	switch strings.ToUpper(name) {
+	case "INT", "INTEGER":
+		return "INT", true
+	case "TEXT", "VARCHAR", "STRING":
+		return "TEXT", true
+	}
+	return "", false
+}
+
+// newStatementParser tokenizes a statement, dropping a trailing semicolon.
+func newStatementParser(input string) (*sqlParser, error) {
+	input = strings.TrimSuffix(strings.TrimSpace(input), ";")
+	tokens, err := tokenizeSQL(input)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlParser{tokens: tokens}, nil
+}
+
+// parseIdentList reads "<col> {, <col>} )"; the opening parenthesis is already consumed.
+func (p *sqlParser) parseIdentList() ([]string, error) {
+	var cols []string
+	for {
+		col := p.next()
+		if col.Kind != tokIdent {
+			return nil, errors.New("expected a column name in column list")
+		}
+		cols = append(cols, col.Text)
+
+		tok := p.next()
+		if tok.Kind == tokRParen {
+			return cols, nil
+		}
+		if tok.Kind != tokComma {
+			return nil, errors.New("missing closing parenthesis after column list")
+		}
+	}
+}
+
+// parseLiteral reads a value to be stored in a Row. Numeric literals become
+// int (or float64 if they have a fractional part) so checkCondition can
+// compare them numerically later. The NULL keyword becomes nil.
+func (p *sqlParser) parseLiteral() (interface{}, error) {
+	if p.acceptKeyword("NULL") {
+		return nil, nil
+	}
+	tok := p.next()
+	switch tok.Kind {
+	case tokNumber:
+		if strings.Contains(tok.Text, ".") {
+			f, err := strconv.ParseFloat(tok.Text, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number '%s'", tok.Text)
+			}
+			return f, nil
+		}
+		n, err := strconv.Atoi(tok.Text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number '%s'", tok.Text)
+		}
+		return n, nil
+	case tokString:
+		return tok.Text, nil
+	}
+	return nil, fmt.Errorf("expected a number, quoted string or NULL, got '%s'", tok.Text)
+}
+
+// parseSelectColumns reads either "*" or a comma-separated list of column names,
+// aggregate calls and arithmetic expressions, each optionally followed by
+// "AS <alias>". Aggregates and expressions are returned separately as well as
+// being named (e.g. "COUNT(*)", "stock * 2") in the column list, so output
+// column order is preserved. aliases is parallel to the column list and nil if
+// no item has an alias.
+func (p *sqlParser) parseSelectColumns() (cols []string, aliases []string, aggs []*AggregateExpr, exprs []*ArithExpr, err error) {
+	if p.peek().Kind == tokStar {
+		p.next()
+		return []string{"*"}, nil, nil, nil, nil
+	}
+
+	hasAlias := false
+	for {
+		start := p.pos
+		tok := p.next()
+		isExpr := tok.Kind == tokNumber || tok.Kind == tokLParen ||
+			(tok.Kind == tokIdent && startsExpression(p.peek()))
+		if !isExpr && (tok.Kind != tokIdent || strings.EqualFold(tok.Text, "FROM")) {
+			if len(cols) == 0 {
+				return nil, nil, nil, nil, fmt.Errorf("empty column list: expected '*' or column names after SELECT, got %s", tok.describe())
+			}
+			return nil, nil, nil, nil, fmt.Errorf("expected a column name after ',', got %s", tok.describe())
+		}
+
+		switch {
+		case isExpr:
+			p.pos = start
+			expr, err := p.parseArithExpr()
+			if err != nil {
+				return nil, nil, nil, nil, err
+			}
+			if expr.Op == "" && expr.Column != "" {
+				cols = append(cols, expr.Column) // Just a parenthesized column
 			} else {
-				ast.SelectColumns = strings.Split(strings.ReplaceAll(colStr, " ", ""), ",")
+				exprs = append(exprs, expr)
+				cols = append(cols, expr.String())
 			}
+		case p.peek().Kind == tokLParen:
+			agg, err := p.parseAggregate(tok.Text)
+			if err != nil {
+				return nil, nil, nil, nil, err
+			}
+			aggs = append(aggs, agg)
+			cols = append(cols, agg.String())
+		default:
+			cols = append(cols, tok.Text)
+		}
 
-			ast.FromTable = strings.TrimSpace(matchesNoWhere[2])
-			ast.Where = nil // No WHERE clause
-		} else {
-			return nil, errors.New("ERR invalid or unsupported SQL query format")
+		alias := ""
+		if p.acceptKeyword("AS") {
+			name := p.next()
+			if name.Kind != tokIdent || strings.EqualFold(name.Text, "FROM") || isClauseKeyword(name.Text) {
+				return nil, nil, nil, nil, fmt.Errorf("expected an alias after AS, got %s", name.describe())
+			}
+			alias, hasAlias = name.Text, true
 		}
+		aliases = append(aliases, alias)
+
+		if p.peek().Kind != tokComma {
+			if !hasAlias {
+				aliases = nil
+			}
+			return cols, aliases, aggs, exprs, nil
+		}
+		p.next()
 	}
+}
 
-	return ast, nil
+// parseAggregate reads "(<col>)", "(*)" or, for COUNT, "(DISTINCT <col>)"
+// after an aggregate function name.
+func (p *sqlParser) parseAggregate(name string) (*AggregateExpr, error) {
+	fn := strings.ToUpper(name)
+	if !isAggregateFunc(fn) {
+		return nil, fmt.Errorf("unknown function '%s'", name)
+	}
+	p.next() // "("
+
+	distinct := p.acceptKeyword("DISTINCT")
+	if distinct && fn != "COUNT" {
+		return nil, fmt.Errorf("DISTINCT is only supported in COUNT(), not %s()", fn)
+	}
+	arg := p.next()
+	if distinct && arg.Kind == tokStar {
+		return nil, errors.New("COUNT(DISTINCT *) is not supported, name a column")
+	}
+	if arg.Kind == tokStar {
+		if fn != "COUNT" {
+			return nil, fmt.Errorf("%s(*) is not supported, only COUNT(*)", fn)
+		}
+	} else if arg.Kind != tokIdent {
+		return nil, fmt.Errorf("expected a column name inside %s()", fn)
+	}
+
+	if p.next().Kind != tokRParen {
+		return nil, fmt.Errorf("missing closing parenthesis after %s(", fn)
+	}
+	return &AggregateExpr{Func: fn, Column: arg.Text, Distinct: distinct}, nil
+}
+
+// parseAlias reads an optional "[AS] <alias>" after a table name.
+func (p *sqlParser) parseAlias() string {
+	hasAs := p.acceptKeyword("AS")
+	tok := p.peek()
+	if tok.Kind != tokIdent || (!hasAs && isClauseKeyword(tok.Text)) {
+		return ""
+	}
+	p.next()
+	return tok.Text
+}
+
+// isClauseKeyword reports whether an identifier starts the next clause, so a
+// bare table name isn't mistaken for "<table> <alias>".
+func isClauseKeyword(word string) bool {
+	switch strings.ToUpper(word) {
+	case "WHERE", "GROUP", "HAVING", "ORDER", "LIMIT", "OFFSET", "JOIN", "INNER", "ON":
+		return true
+	}
+	return false
+}
+
+// parseJoin reads "[INNER] JOIN <table> [alias] ON <col> = <col>".
+func (p *sqlParser) parseJoin() (*JoinClause, error) {
+	p.acceptKeyword("INNER")
+	if !p.acceptKeyword("JOIN") {
+		return nil, errors.New("expected JOIN after INNER")
+	}
+	table := p.next()
+	if table.Kind != tokIdent {
+		return nil, errors.New("expected a table name after JOIN")
+	}
+	join := &JoinClause{Table: table.Text, Alias: p.parseAlias()}
+
+	if !p.acceptKeyword("ON") {
+		return nil, fmt.Errorf("expected ON after JOIN %s", table.Text)
+	}
+	left := p.next()
+	op := p.next()
+	right := p.next()
+	if left.Kind != tokIdent || right.Kind != tokIdent {
+		return nil, errors.New("expected ON <col> = <col>")
+	}
+	if op.Kind != tokOperator || op.Text != "=" {
+		return nil, fmt.Errorf("only equality joins are supported, got '%s'", op.Text)
+	}
+	join.LeftColumn = left.Text
+	join.RightColumn = right.Text
+	return join, nil
+}
+
+// parseGroupBy reads "BY <col> {, <col>}"; the GROUP keyword is already consumed.
+func (p *sqlParser) parseGroupBy() ([]string, error) {
+	if !p.acceptKeyword("BY") {
+		return nil, errors.New("expected BY after GROUP")
+	}
+
+	var cols []string
+	for {
+		col := p.next()
+		if col.Kind != tokIdent {
+			return nil, errors.New("expected a column name in GROUP BY")
+		}
+		cols = append(cols, col.Text)
+
+		if p.peek().Kind != tokComma {
+			return cols, nil
+		}
+		p.next()
+	}
+}
+
+// validateGrouping enforces that grouped queries only select grouping columns
+// and aggregates, and that every grouping column is selected.
+func validateGrouping(ast *QueryAST) error {
+	if len(ast.GroupBy) == 0 && len(ast.Aggregates) == 0 {
+		if ast.Having != nil {
+			return errors.New("HAVING requires GROUP BY or an aggregate in the select list")
+		}
+		return nil
+	}
+	if ast.SelectColumns[0] == "*" {
+		return errors.New("SELECT * cannot be combined with GROUP BY")
+	}
+
+	for _, col := range ast.GroupBy {
+		if !containsColumnFold(ast.SelectColumns, col) {
+			return fmt.Errorf("GROUP BY column '%s' must appear in the select list", col)
+		}
+	}
+
+	for _, col := range ast.SelectColumns {
+		if !ast.isAggregateColumn(col) && !containsColumnFold(ast.GroupBy, col) {
+			return fmt.Errorf("column '%s' must appear in GROUP BY or be used in an aggregate", col)
+		}
+	}
+	return validateHaving(ast, ast.Having)
+}
+
+// validateHaving checks that a HAVING condition only tests aggregates and
+// GROUP BY columns, the only values a grouped row holds. A select-list alias
+// stands for the item it names, e.g. "HAVING hits > 3" after
+// "COUNT(*) AS hits". Subqueries are rejected: HAVING is applied after
+// grouping, where they are never run.
+func validateHaving(ast *QueryAST, node *WhereNode) error {
+	if node == nil {
+		return nil
+	}
+	if cond := node.Cond; cond != nil {
+		if cond.Subquery != nil {
+			return fmt.Errorf("subqueries are not supported in HAVING")
+		}
+		for i, alias := range ast.ColumnAliases {
+			if alias != "" && strings.EqualFold(alias, cond.Column) {
+				cond.Column = ast.SelectColumns[i]
+				break
+			}
+		}
+		if !ast.isHavingAggregate(cond.Column) && !containsColumnFold(ast.GroupBy, cond.Column) {
+			return fmt.Errorf("HAVING column '%s' must appear in GROUP BY or be used in an aggregate", cond.Column)
+		}
+	}
+	if err := validateHaving(ast, node.Left); err != nil {
+		return err
+	}
+	return validateHaving(ast, node.Right)
+}
+
+// isHavingAggregate reports whether a name refers to an aggregate call from
+// the select list or from HAVING.
+func (ast *QueryAST) isHavingAggregate(col string) bool {
+	if ast.isAggregateColumn(col) {
+		return true
+	}
+	for _, agg := range ast.HavingAggs {
+		if agg.String() == col {
+			return true
+		}
+	}
+	return false
+}
+
+// isAggregateColumn reports whether a select-list name refers to an aggregate call.
+func (ast *QueryAST) isAggregateColumn(col string) bool {
+	for _, agg := range ast.Aggregates {
+		if agg.String() == col {
+			return true
+		}
+	}
+	return false
+}
+
+// parseOrderBy reads "BY <col> [ASC|DESC]"; the ORDER keyword is already consumed.
+func (p *sqlParser) parseOrderBy() (*OrderByClause, error) {
+	if !p.acceptKeyword("BY") {
+		return nil, errors.New("expected BY after ORDER")
+	}
+	col := p.next()
+	if col.Kind != tokIdent {
+		return nil, errors.New("expected a column name after ORDER BY")
+	}
+
+	clause := &OrderByClause{Column: col.Text}
+	if p.acceptKeyword("DESC") {
+		clause.Desc = true
+	} else {
+		p.acceptKeyword("ASC") // ASC is the default
+	}
+	return clause, nil
+}
+
+// parseCount reads the non-negative integer that follows LIMIT or OFFSET.
+func (p *sqlParser) parseCount(clause string) (int, error) {
+	tok := p.next()
+	n, err := strconv.Atoi(tok.Text)
+	if tok.Kind != tokNumber || err != nil || n < 0 {
+		return 0, fmt.Errorf("%s expects a non-negative integer, got '%s'", clause, tok.Text)
+	}
+	return n, nil
+}
+
+// parseOr handles: and_expr { OR and_expr }
+func (p *sqlParser) parseOr() (*WhereNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptKeyword("OR") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &WhereNode{Op: "OR", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+// parseAnd handles: primary { AND primary }
+func (p *sqlParser) parseAnd() (*WhereNode, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptKeyword("AND") {
+		right, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		left = &WhereNode{Op: "AND", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+// parsePrimary handles: NOT primary | "(" or_expr ")" | col op val | col LIKE 'pattern' | col IN (list) | col BETWEEN low AND high
+// | col IS [NOT] NULL
+func (p *sqlParser) parsePrimary() (*WhereNode, error) {
+	if p.acceptKeyword("NOT") {
+		operand, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		return &WhereNode{Op: "NOT", Left: operand}, nil
+	}
+
+	if p.peek().Kind == tokLParen {
+		p.next()
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next().Kind != tokRParen {
+			return nil, errors.New("missing closing parenthesis in WHERE clause")
+		}
+		return node, nil
+	}
+
+	col := p.next()
+	if col.Kind != tokIdent {
+		return nil, fmt.Errorf("expected a column name in WHERE clause, got %s", col.describe())
+	}
+	if p.peek().Kind == tokLParen {
+		// An aggregate call such as COUNT(*), only meaningful per group
+		if !p.inHaving {
+			return nil, fmt.Errorf("aggregate %s() is not allowed in WHERE, use HAVING", strings.ToUpper(col.Text))
+		}
+		agg, err := p.parseAggregate(col.Text)
+		if err != nil {
+			return nil, err
+		}
+		p.havingAggs = append(p.havingAggs, agg)
+		col.Text = agg.String()
+	}
+
+	if p.acceptKeyword("LIKE") {
+		pattern := p.next()
+		if pattern.Kind != tokString {
+			return nil, errors.New("LIKE expects a quoted pattern, e.g. name LIKE 'A%'")
+		}
+		re, err := compileLikePattern(pattern.Text)
+		if err != nil {
+			return nil, err
+		}
+		return &WhereNode{Cond: &WhereCondition{
+			Column:    col.Text,
+			Operator:  "LIKE",
+			Value:     pattern.Text,
+			likeRegex: re,
+		}}, nil
+	}
+
+	if p.acceptKeyword("IN") {
+		cond := &WhereCondition{Column: col.Text, Operator: "IN"}
+		// A subquery starts with the SELECT keyword; a quoted 'SELECT' is
+		// just the first element of a value list
+		if p.peek().Kind == tokLParen && p.pos+1 < len(p.tokens) &&
+			p.tokens[p.pos+1].Kind == tokIdent && strings.EqualFold(p.tokens[p.pos+1].Text, "SELECT") {
+			p.next() // "("
+			sub, err := p.parseSubquery()
+			if err != nil {
+				return nil, err
+			}
+			cond.Subquery = sub
+			return &WhereNode{Cond: cond}, nil
+		}
+		if err := p.parseValueList(cond); err != nil {
+			return nil, err
+		}
+		return &WhereNode{Cond: cond}, nil
+	}
+
+	if p.acceptKeyword("BETWEEN") {
+		return p.parseBetween(col.Text)
+	}
+
+	if p.acceptKeyword("IS") {
+		operator := "IS NULL"
+		if p.acceptKeyword("NOT") {
+			operator = "IS NOT NULL"
+		}
+		if !p.acceptKeyword("NULL") {
+			return nil, fmt.Errorf("expected NULL after '%s %s', got %s", col.Text, strings.TrimSuffix(operator, " NULL"), p.peek().describe())
+		}
+		return &WhereNode{Cond: &WhereCondition{Column: col.Text, Operator: operator}}, nil
+	}
+
+	op := p.next()
+	if op.Kind != tokOperator {
+		return nil, fmt.Errorf("unknown operator %s after column '%s', expected one of = != < > <= >= LIKE IN BETWEEN IS", op.describe(), col.Text)
+	}
+	val := p.next()
+	if val.Kind != tokNumber && val.Kind != tokString && val.Kind != tokIdent && val.Kind != tokParam {
+		return nil, fmt.Errorf("expected a value after '%s %s', got %s", col.Text, op.Text, val.describe())
+	}
+
+	cond := &WhereCondition{
+		Column:   col.Text,
+		Operator: op.Text,
+		Value:    val.Text, // Quotes were already removed by the tokenizer
+	}
+	// An unquoted name may be another column; canonicalizeWhere decides
+	// once the schema is known
+	if val.Kind == tokIdent {
+		cond.ValueColumn = val.Text
+	}
+	if val.Kind == tokParam {
+		if err := p.addParam(cond, -1); err != nil {
+			return nil, err
+		}
+	}
+	return &WhereNode{Cond: cond}, nil
+}
+
+// parseBetween reads "<low> AND <high>" after BETWEEN. Both bounds must be
+// numbers, which may be negative or decimal (e.g. "-1.5 AND 2"), and low
+// must not exceed high.
+func (p *sqlParser) parseBetween(column string) (*WhereNode, error) {
+	low := p.next()
+	if !p.acceptKeyword("AND") {
+		return nil, errors.New("expected AND between the BETWEEN bounds")
+	}
+	high := p.next()
+
+	cond := &WhereCondition{
+		Column:   column,
+		Operator: "BETWEEN",
+		Values:   []string{low.Text, high.Text},
+	}
+	lowVal, highVal, ok := cond.GetBounds()
+	if !ok {
+		return nil, fmt.Errorf("BETWEEN bounds must be numbers, got '%s' and '%s'", low.Text, high.Text)
+	}
+	if lowVal > highVal {
+		return nil, fmt.Errorf("BETWEEN lower bound %s is greater than upper bound %s", low.Text, high.Text)
+	}
+	return &WhereNode{Cond: cond}, nil
+}
+
+// parseValueList reads "(v1, v2, ...)" for IN into cond.Values. Elements may
+// be numbers, quoted strings or (in prepared statements) "?" placeholders.
+func (p *sqlParser) parseValueList(cond *WhereCondition) error {
+	if p.next().Kind != tokLParen {
+		return errors.New("expected '(' after IN")
+	}
+
+	for {
+		val := p.next()
+		switch val.Kind {
+		case tokNumber, tokString:
+		case tokParam:
+			if err := p.addParam(cond, len(cond.Values)); err != nil {
+				return err
+			}
+		default:
+			return errors.New("IN list elements must be numbers or quoted strings")
+		}
+		cond.Values = append(cond.Values, val.Text)
+
+		tok := p.next()
+		if tok.Kind == tokRParen {
+			return nil
+		}
+		if tok.Kind != tokComma {
+			return errors.New("missing closing parenthesis after IN list")
+		}
+	}
 }
 
 // GetAsInt attempts to parse the condition's value as an integer.
@@ -89,18 +1226,105 @@ func (wc *WhereCondition) GetAsInt() (int, bool) {
 	return i, true
 }
 
+// GetAsFloat attempts to parse the condition's value as a number, so that
+// fractional thresholds like "cpu_load > 80.5" compare numerically.
+func (wc *WhereCondition) GetAsFloat() (float64, bool) {
+	return parseNumber(wc.Value)
+}
+
+// GetBounds returns the numeric [low, high] bounds of a BETWEEN condition.
+func (wc *WhereCondition) GetBounds() (float64, float64, bool) {
+	if len(wc.Values) != 2 {
+		return 0, 0, false
+	}
+	low, lowOk := parseNumber(wc.Values[0])
+	high, highOk := parseNumber(wc.Values[1])
+	return low, high, lowOk && highOk
+}
+
+// hasColumnComparison reports whether any condition in the tree compares
+// two columns. Such queries are never answered from, or used as, a cached
+// superset: the subset checks only reason about literal bounds.
+func hasColumnComparison(node *WhereNode) bool {
+	if node == nil {
+		return false
+	}
+	if node.Cond != nil && node.Cond.ValueColumn != "" {
+		return true
+	}
+	return hasColumnComparison(node.Left) || hasColumnComparison(node.Right)
+}
+
+// isNullCheck reports whether the condition is "col IS NULL" or "col IS NOT NULL".
+func isNullCheck(wc *WhereCondition) bool {
+	return wc.Operator == "IS NULL" || wc.Operator == "IS NOT NULL"
+}
+
+// parseNumber parses an integer or decimal literal. NaN and infinities are
+// rejected so that words like 'inf' are still treated as strings.
+func parseNumber(value string) (float64, bool) {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, false
+	}
+	return f, true
+}
+
 // --- NEW: String() method for pretty-printing the WhereCondition ---
 func (wc *WhereCondition) String() string {
 	if wc == nil {
 		return "N/A"
 	}
-	// Add quotes if value is not an integer
-	_, isInt := wc.GetAsInt()
-	valStr := wc.Value
-	if !isInt {
-		valStr = fmt.Sprintf("'%s'", valStr)
+	if wc.Subquery != nil {
+		return fmt.Sprintf("%s IN (%s)", wc.Column, wc.Subquery.OriginalString)
+	}
+	if wc.Operator == "IN" {
+		quoted := make([]string, len(wc.Values))
+		for i, v := range wc.Values {
+			quoted[i] = quoteLiteral(v)
+		}
+		return fmt.Sprintf("%s IN (%s)", wc.Column, strings.Join(quoted, ", "))
+	}
+	if wc.Operator == "BETWEEN" && len(wc.Values) == 2 {
+		return fmt.Sprintf("%s BETWEEN %s AND %s", wc.Column, wc.Values[0], wc.Values[1])
+	}
+	if isNullCheck(wc) {
+		return fmt.Sprintf("%s %s", wc.Column, wc.Operator)
+	}
+	if wc.ValueColumn != "" {
+		return fmt.Sprintf("%s %s %s", wc.Column, wc.Operator, wc.ValueColumn)
+	}
+	return fmt.Sprintf("%s %s %s", wc.Column, wc.Operator, quoteLiteral(wc.Value))
+}
+
+// quoteLiteral adds quotes if value is not a number
+func quoteLiteral(value string) string {
+	if _, ok := parseNumber(value); ok {
+		return value
+	}
+	return fmt.Sprintf("'%s'", value)
+}
+
+// String renders the tree in infix form, parenthesising compound children.
+func (wn *WhereNode) String() string {
+	if wn == nil {
+		return "N/A"
+	}
+	if wn.Cond != nil {
+		return wn.Cond.String()
+	}
+	if wn.Op == "NOT" {
+		return fmt.Sprintf("(NOT %s)", wn.Left.String())
 	}
-	return fmt.Sprintf("%s %s %s", wc.Column, wc.Operator, valStr)
+	return fmt.Sprintf("(%s %s %s)", wn.Left.String(), wn.Op, wn.Right.String())
+}
+
+// String renders the clause as "<col> ASC" or "<col> DESC".
+func (ob *OrderByClause) String() string {
+	if ob.Desc {
+		return ob.Column + " DESC"
+	}
+	return ob.Column + " ASC"
 }
 
 // --- NEW: String() method for pretty-printing the QueryAST ---
@@ -109,18 +1333,44 @@ func (ast *QueryAST) String() string {
 		return "<nil>"
 	}
 	
-	cols := strings.Join(ast.SelectColumns, ", ")
+	selected := make([]string, len(ast.SelectColumns))
+	for i, col := range ast.SelectColumns {
+		selected[i] = col
+		if i < len(ast.ColumnAliases) && ast.ColumnAliases[i] != "" {
+			selected[i] += " AS " + ast.ColumnAliases[i]
+		}
+	}
+	cols := strings.Join(selected, ", ")
+	if ast.Distinct {
+		cols = "DISTINCT " + cols
+	}
 	whereStr := "None"
 	if ast.Where != nil {
 		whereStr = ast.Where.String()
 	}
 
-	return fmt.Sprintf(
+	out := fmt.Sprintf(
 		"AST:\n"+
 			"  - SELECT: %s\n"+
 			"  - FROM:   %s\n"+
 			"  - WHERE:  %s",
 		cols, ast.FromTable, whereStr,
 	)
+	if ast.Join != nil {
+		out += "\n  - JOIN:   " + ast.Join.String()
+	}
+	if len(ast.GroupBy) > 0 {
+		out += "\n  - GROUP:  " + strings.Join(ast.GroupBy, ", ")
+	}
+	if ast.Having != nil {
+		out += "\n  - HAVING: " + ast.Having.String()
+	}
+	if ast.OrderBy != nil {
+		out += "\n  - ORDER:  " + ast.OrderBy.String()
+	}
+	if ast.HasLimit {
+		out += fmt.Sprintf("\n  - LIMIT:  %d OFFSET %d", ast.Limit, ast.Offset)
+	}
+	return out
 }
 // --- End NEW ---
\ No newline at end of file
@@ -0,0 +1,54 @@
+package command
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseCompoundWhere(t *testing.T) {
+	ast, err := ParseSQL("SELECT * FROM server_logs WHERE cpu_load > 80 AND status = 'WARNING' OR status = 'ERROR'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// AND binds tighter than OR
+	root := ast.Where
+	if root.Op != "OR" || root.Left == nil || root.Left.Op != "AND" {
+		t.Fatalf("WHERE parsed as %s, want (cpu_load > 80 AND status = 'WARNING') OR status = 'ERROR'", ast.String())
+	}
+	if c := root.Left.Left.Cond; c.Column != "cpu_load" || c.Operator != ">" || c.Value != "80" {
+		t.Errorf("first condition = %+v", c)
+	}
+	if c := root.Right.Cond; c.Column != "status" || c.Operator != "=" || c.Value != "ERROR" {
+		t.Errorf("OR-ed condition = %+v", c)
+	}
+}
+
+func TestParseErrorsSayWhatIsWrong(t *testing.T) {
+	cases := []struct {
+		query, want string
+	}{
+		{"SELECT name users", "expected FROM after column list, got 'users'"},
+		{"SELECT FROM users", "empty column list: expected '*' or column names after SELECT, got 'FROM'"},
+		{"SELECT name, FROM users", "expected a column name after ',', got 'FROM'"},
+		{"SELECT * FROM", "expected a table name after FROM, got end of query"},
+		{"SELEC * FROM users", "expected SELECT at the start of the query, got 'SELEC'"},
+		{"SELECT * FROM users WHERE", "expected a column name in WHERE clause, got end of query"},
+		{"SELECT * FROM users WHERE name = 'Alice", "unterminated string literal starting at position 33"},
+		{"SELECT * FROM users WHERE age ~ 3", "unexpected character '~' at position 30"},
+		{"SELECT * FROM users ORDER age", "expected BY after ORDER"},
+		{"SELECT * FROM users LIMIT -1", "LIMIT expects a non-negative integer, got '-1'"},
+	}
+	for _, c := range cases {
+		_, err := ParseSQL(c.query)
+		if err == nil || err.Error() != c.want {
+			t.Errorf("%s: error %v, want %q", c.query, err, c.want)
+		}
+	}
+
+	// The client gets the same text
+	resetSQL(t)
+	reply := runSQL(t, &recordConn{}, "SELECT name users")
+	if !strings.HasPrefix(reply, "-ERR expected FROM after column list") {
+		t.Errorf("reply = %q", reply)
+	}
+}
@@ -0,0 +1,168 @@
+package command
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// paramSlot locates one "?" placeholder of a prepared statement: the
+// condition it belongs to and, for IN lists, the element it stands for.
+type paramSlot struct {
+	cond  *WhereCondition
+	index int // Position in cond.Values, or -1 for cond.Value
+}
+
+// Prepared statements created by SQLPREPARE, keyed by the id it returned.
+var (
+	preparedStatements = make(map[int]*QueryAST)
+	nextStatementID    = 1
+	preparedMutex      sync.RWMutex
+)
+
+// PrepareSQL parses a SELECT that may contain "?" placeholders in place of
+// WHERE values (e.g. "SELECT * FROM users WHERE age > ?") and resolves its
+// names, so Execute only has to bind values. Placeholders are recorded in
+// the order they appear.
+func PrepareSQL(query string) (*QueryAST, error) {
+	ast, err := parseSelect(query, true)
+	if err != nil {
+		return nil, err
+	}
+	if err := resolveQueryNames(ast); err != nil {
+		return nil, err
+	}
+	return ast, nil
+}
+
+// Execute binds args to the placeholders of a prepared statement, in order,
+// and answers the resulting query through the cache like any other SELECT.
+// Arguments are used as literal values and are never parsed as SQL. The
+// prepared statement itself is left untouched so it can be reused. It also
+// returns the query that was answered and how the cache answered it.
+func Execute(ast *QueryAST, args []string) (*Table, *QueryAST, queryOutcome, error) {
+	if len(args) != len(ast.Params) {
+		return nil, nil, outcomeMiss, fmt.Errorf("statement expects %d arguments, got %d", len(ast.Params), len(args))
+	}
+
+	startTime := time.Now()
+	SQLCache.IncrementTotalQueries()
+
+	// Cache under the statement plus its quoted arguments, which can't be
+	// confused with another statement/argument combination
+	key := fmt.Sprintf("%s %q", ast.OriginalString, args)
+	if entry, hit := lookupDirectHit(key, startTime); hit {
+		return entry.Results, entry.Query, outcomeDirectHit, nil
+	}
+
+	bound := bindParams(ast, args)
+	bound.OriginalString = key
+
+	results, outcome, err := answerQuery(key, bound, startTime)
+	if err != nil {
+		return nil, nil, outcome, err
+	}
+	return results, bound, outcome, nil
+}
+
+// bindParams returns a copy of ast whose WHERE tree has args substituted for
+// its placeholders. Everything except the WHERE tree is shared with ast.
+func bindParams(ast *QueryAST, args []string) *QueryAST {
+	copies := make(map[*WhereCondition]*WhereCondition, len(ast.Params))
+	bound := *ast
+	bound.Where = cloneWhere(ast.Where, copies)
+	bound.Params = nil
+
+	for i, slot := range ast.Params {
+		cond := copies[slot.cond]
+		if slot.index < 0 {
+			cond.Value = args[i]
+		} else {
+			cond.Values[slot.index] = args[i]
+		}
+	}
+	return &bound
+}
+
+// cloneWhere deep-copies a WHERE tree, recording each copied condition in
+// copies under its original.
+func cloneWhere(node *WhereNode, copies map[*WhereCondition]*WhereCondition) *WhereNode {
+	if node == nil {
+		return nil
+	}
+	clone := &WhereNode{
+		Op:    node.Op,
+		Left:  cloneWhere(node.Left, copies),
+		Right: cloneWhere(node.Right, copies),
+	}
+	if node.Cond != nil {
+		cond := *node.Cond
+		cond.Values = append([]string(nil), node.Cond.Values...)
+		copies[node.Cond] = &cond
+		clone.Cond = &cond
+	}
+	return clone
+}
+
+func init() {
+	Register(CommandInfo{Name: "SQLPREPARE", Usage: "SQLPREPARE <query>", Summary: "Prepare a SELECT with ? placeholders, returning its id"}, HandlerFunc(HandleSQLPrepare))
+	Register(CommandInfo{Name: "SQLEXEC", Usage: "SQLEXEC <id> [args...]", Summary: "Run a prepared statement with the given arguments"}, HandlerFunc(HandleSQLExec))
+}
+
+// HandleSQLPrepare processes SQLPREPARE <query>
+// Replies with the id to pass to SQLEXEC.
+func HandleSQLPrepare(argv []string, c net.Conn) {
+	if len(argv) < 2 || argv[1] == "" {
+		c.Write([]byte("-ERR wrong number of arguments for 'sqlprepare' command\r\n"))
+		return
+	}
+
+	ast, err := PrepareSQL(argv[1])
+	if err != nil {
+		c.Write([]byte(fmt.Sprintf("-ERR %s\r\n", err.Error())))
+		return
+	}
+
+	preparedMutex.Lock()
+	id := nextStatementID
+	nextStatementID++
+	preparedStatements[id] = ast
+	preparedMutex.Unlock()
+
+	queryLog.Info("prepare", "query", argv[1], "statement", id, "params", len(ast.Params))
+	c.Write([]byte(fmt.Sprintf(":%d\r\n", id)))
+}
+
+// HandleSQLExec processes SQLEXEC <id> [arg ...]
+// Runs a statement from SQLPREPARE with the arguments bound to its
+// placeholders and replies with the result table.
+func HandleSQLExec(argv []string, c net.Conn) {
+	if len(argv) < 2 || argv[1] == "" {
+		c.Write([]byte("-ERR wrong number of arguments for 'sqlexec' command\r\n"))
+		return
+	}
+	id, err := strconv.Atoi(argv[1])
+	if err != nil {
+		c.Write([]byte("-ERR statement id must be an integer\r\n"))
+		return
+	}
+
+	preparedMutex.RLock()
+	ast, ok := preparedStatements[id]
+	preparedMutex.RUnlock()
+	if !ok {
+		c.Write([]byte(fmt.Sprintf("-ERR no prepared statement with id %d\r\n", id)))
+		return
+	}
+
+	startTime := time.Now()
+	results, bound, outcome, err := Execute(ast, argv[2:])
+	if err != nil {
+		c.Write([]byte(fmt.Sprintf("-ERR %s\r\n", err.Error())))
+		return
+	}
+	logQuery(c, bound.OriginalString, outcome, time.Since(startTime), bound.FromTable, len(results.Rows))
+	c.Write([]byte(formatResults(results, bound.outputHeaders(results))))
+}
@@ -0,0 +1,67 @@
+package command
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPreparedStatementsBindArguments(t *testing.T) {
+	resetSQL(t)
+	reply := runCommand(t, "SQLPREPARE", "SELECT name FROM users WHERE age > ? AND age < ?")
+	if !strings.HasPrefix(reply, ":") {
+		t.Fatalf("SQLPREPARE = %q, want a statement id", reply)
+	}
+	id := strings.TrimSpace(reply[1:])
+
+	cases := []struct {
+		args  []string
+		query string
+	}{
+		{[]string{"90", "100"}, "SELECT name FROM users WHERE age > 90 AND age < 100"},
+		{[]string{"20", "50"}, "SELECT name FROM users WHERE age > 20 AND age < 50"},
+		{[]string{"90", "100"}, "SELECT name FROM users WHERE age > 90 AND age < 100"},
+	}
+	for _, c := range cases {
+		got := runCommand(t, append([]string{"SQLEXEC", id}, c.args...)...)
+		if want := mustSQL(t, "NOCACHE "+c.query); got != want {
+			t.Errorf("SQLEXEC %s %v:\n%s\nwant the rows of %s:\n%s", id, c.args, got, c.query, want)
+		}
+	}
+	if a, b := runCommand(t, "SQLEXEC", id, "90", "100"), runCommand(t, "SQLEXEC", id, "20", "50"); a == b {
+		t.Errorf("different arguments gave the same result set:\n%s", a)
+	}
+}
+
+func TestPreparedArgumentsAreLiterals(t *testing.T) {
+	resetSQL(t)
+	prepared, err := PrepareSQL("SELECT * FROM users WHERE name = ?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, _, _, err := Execute(prepared, []string{"x' OR '1'='1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results.Rows) != 0 {
+		t.Errorf("an injection-style argument matched %d rows, want 0", len(results.Rows))
+	}
+	results, _, _, err = Execute(prepared, []string{"Alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := column(results, "id"); !reflect.DeepEqual(got, []interface{}{1}) {
+		t.Errorf("name = Alice matched ids %v, want [1]", got)
+	}
+	// The prepared statement still has its placeholder after both runs
+	if prepared.Where.Cond.Value == "Alice" {
+		t.Error("Execute bound its argument into the prepared statement")
+	}
+
+	if _, _, _, err := Execute(prepared, nil); err == nil {
+		t.Error("Execute with too few arguments succeeded")
+	}
+	if got := runCommand(t, "SQLEXEC", "999"); got[0] != '-' {
+		t.Errorf("SQLEXEC of an unknown id = %q, want an error", got)
+	}
+}
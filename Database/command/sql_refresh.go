@@ -0,0 +1,119 @@
+package command
+
+import (
+	"container/list"
+	"time"
+)
+
+// refreshCandidate is a cache entry due for a background refresh.
+type refreshCandidate struct {
+	shard *cacheShard
+	elem  *list.Element
+	key   string
+	query *QueryAST
+}
+
+// startRefresher launches the background refresh when the cache has a TTL
+// and a refresh window and interval are configured. Close stops it.
+func (sc *SemanticCache) startRefresher(interval time.Duration) {
+	if sc.TTL <= 0 || sc.refreshWindow <= 0 || interval <= 0 {
+		return
+	}
+	sc.stopRefresh = make(chan struct{})
+	go sc.refreshLoop(interval, sc.stopRefresh)
+}
+
+func (sc *SemanticCache) refreshLoop(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			sc.refreshExpiring()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// Close stops the background refresh, if one is running. It is safe to
+// call more than once.
+func (sc *SemanticCache) Close() {
+	sc.closeOnce.Do(func() {
+		if sc.stopRefresh != nil {
+			close(sc.stopRefresh)
+		}
+	})
+}
+
+// refreshExpiring re-runs every entry due for a refresh against the backing
+// store and swaps in the fresh results, restarting its TTL. It returns how
+// many entries were refreshed. The queries run without any cache lock held,
+// and without the simulated miss penalty since no client is waiting.
+func (sc *SemanticCache) refreshExpiring() int {
+	refreshed := 0
+	for _, candidate := range sc.dueForRefresh() {
+		results, err := executeOnBackingStore(candidate.query)
+		if err != nil {
+			// e.g. the table was dropped; the entry simply expires
+			queryLog.Debug("cache refresh failed", "query", candidate.key, "error", err)
+			continue
+		}
+		if sc.replaceResults(candidate, results) {
+			refreshed++
+			queryLog.Debug("refreshed cache entry", "query", candidate.key, "rows", len(results.Rows))
+		}
+	}
+	return refreshed
+}
+
+// dueForRefresh lists the live entries within the refresh window of
+// expiring that have been used since they were cached. Entries nobody has
+// asked for again are left to expire.
+func (sc *SemanticCache) dueForRefresh() []refreshCandidate {
+	now := sc.now()
+	var due []refreshCandidate
+	for _, shard := range sc.shards {
+		shard.mu.RLock()
+		for e := shard.entries.Front(); e != nil; e = e.Next() {
+			entry := e.Value.(*CacheEntry)
+			age := now.Sub(entry.CreatedAt)
+			if age > sc.TTL || age < sc.TTL-sc.refreshWindow {
+				continue
+			}
+			if entry.Timestamp.After(entry.CreatedAt) {
+				due = append(due, refreshCandidate{shard: shard, elem: e, key: entry.key, query: entry.Query})
+			}
+		}
+		shard.mu.RUnlock()
+	}
+	return due
+}
+
+// replaceResults stores refreshed results for a candidate and restarts its
+// TTL. Its position in the eviction order is kept, since a refresh isn't a
+// use. It reports false if the entry was evicted, invalidated or expired
+// while the query ran, or if a table it reads was written meanwhile; either
+// of the last two means the results may already be stale.
+func (sc *SemanticCache) replaceResults(candidate refreshCandidate, results *Table) bool {
+	dbMutex.RLock() // See AddToCache
+	defer dbMutex.RUnlock()
+	if !results.isCurrent() {
+		return false
+	}
+
+	candidate.shard.mu.Lock()
+	defer candidate.shard.mu.Unlock()
+
+	entry := candidate.elem.Value.(*CacheEntry)
+	if candidate.shard.lookup[candidate.key] != candidate.elem || sc.isExpired(entry) {
+		return false
+	}
+	candidate.shard.setResultsLocked(entry, results)
+	entry.CreatedAt = sc.now()
+	// Bigger results may push the shard over its cell budget, in which
+	// case the coldest entries (possibly this one) make room
+	candidate.shard.evictOverBudgetLocked()
+	return true
+}
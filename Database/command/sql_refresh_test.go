@@ -0,0 +1,72 @@
+package command
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRefreshKeepsHotEntriesAlive(t *testing.T) {
+	resetSQL(t)
+	cfg := DefaultSQLCacheConfig()
+	cfg.MissPenalty, cfg.RefreshInterval = 0, 0
+	cfg.TTL, cfg.RefreshWindow = time.Minute, 20*time.Second
+	InitSQLCache(cfg)
+	clock := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	SQLCache.now = func() time.Time { return clock }
+
+	const hot = "SELECT * FROM users WHERE age > 40"
+	const cold = "SELECT * FROM products"
+	cacheOutcome(t, hot)
+	cacheOutcome(t, cold)
+	clock = clock.Add(10 * time.Second)
+	cacheOutcome(t, hot)
+
+	// Outside the refresh window nothing is due yet
+	if n := SQLCache.refreshExpiring(); n != 0 {
+		t.Fatalf("refreshed %d entries 10s into a 1m TTL, want 0", n)
+	}
+	clock = clock.Add(45 * time.Second)
+	if n := SQLCache.refreshExpiring(); n != 1 {
+		t.Fatalf("refreshed %d entries, want only the hot one", n)
+	}
+
+	// Past the original TTL the hot entry lives on; the cold one expired
+	clock = clock.Add(30 * time.Second)
+	if got := cacheOutcome(t, hot); got != "direct" {
+		t.Errorf("refreshed query was a %s, want direct", got)
+	}
+	if got := cacheOutcome(t, cold); got != "miss" {
+		t.Errorf("unused query was a %s, want miss", got)
+	}
+}
+
+func TestBackgroundRefreshRunsUntilClosed(t *testing.T) {
+	resetSQL(t)
+	cfg := DefaultSQLCacheConfig()
+	cfg.MissPenalty = 0
+	cfg.TTL, cfg.RefreshWindow, cfg.RefreshInterval = 200*time.Millisecond, 150*time.Millisecond, 10*time.Millisecond
+	InitSQLCache(cfg)
+	t.Cleanup(SQLCache.Close)
+
+	const query = "SELECT * FROM users WHERE age > 40"
+	cacheOutcome(t, query)
+	cacheOutcome(t, query)
+
+	// Keep using the entry for well past its TTL; it should never miss
+	deadline := time.Now().Add(600 * time.Millisecond)
+	for time.Now().Before(deadline) {
+		if got := cacheOutcome(t, query); got != "direct" {
+			t.Fatalf("hot query was a %s %v into a 200ms TTL, want direct",
+				got, 600*time.Millisecond-time.Until(deadline))
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	// Once closed, nothing refreshes it and it expires as usual
+	SQLCache.Close()
+	SQLCache.Close() // Safe to call twice
+	time.Sleep(250 * time.Millisecond)
+	if got := cacheOutcome(t, query); got != "miss" {
+		t.Errorf("query after Close and a full TTL was a %s, want miss", got)
+	}
+}
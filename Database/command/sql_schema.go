@@ -0,0 +1,65 @@
+package command
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+)
+
+func init() {
+	Register(CommandInfo{Name: "SHOW", Usage: "SHOW TABLES", Summary: "List the SQL tables in every database"}, HandlerFunc(HandleSQL))
+	Register(CommandInfo{Name: "DESCRIBE", Usage: "DESCRIBE <table>", Summary: "List a SQL table's columns and declared types"}, HandlerFunc(HandleSQL))
+}
+
+// handleShowTables answers SHOW TABLES with the full name of every table
+// ("users", "analytics.events"), sorted.
+func handleShowTables(query string, c net.Conn) {
+	fields := strings.Fields(query)
+	if len(fields) != 2 || !strings.EqualFold(fields[1], "TABLES") {
+		c.Write([]byte("-ERR expected TABLES after SHOW\r\n"))
+		return
+	}
+
+	dbMutex.RLock()
+	var names []string
+	for _, tables := range Databases {
+		for _, table := range tables {
+			names = append(names, table.Name)
+		}
+	}
+	dbMutex.RUnlock()
+
+	sort.Strings(names)
+	c.Write([]byte(formatListAsRespArray(names)))
+}
+
+// handleDescribe answers DESCRIBE <table> with one element per column in
+// table order: "<name> <type>" for tables created with CREATE TABLE, just
+// the name for untyped ones such as the seeded tables.
+func handleDescribe(query string, c net.Conn) {
+	fields := strings.Fields(query)
+	if len(fields) != 2 {
+		c.Write([]byte("-ERR expected a table name after DESCRIBE\r\n"))
+		return
+	}
+
+	dbMutex.RLock()
+	table, ok := lookupTable(fields[1])
+	var columns []string
+	if ok {
+		for _, col := range table.Columns {
+			if colType := table.Types[col]; colType != "" {
+				col += " " + colType
+			}
+			columns = append(columns, col)
+		}
+	}
+	dbMutex.RUnlock()
+
+	if !ok {
+		c.Write([]byte(fmt.Sprintf("-ERR table '%s' not found\r\n", fields[1])))
+		return
+	}
+	c.Write([]byte(formatListAsRespArray(columns)))
+}
@@ -0,0 +1,36 @@
+package command
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestShowTablesAndDescribe(t *testing.T) {
+	resetSQL(t)
+	cases := []struct{ query, want string }{
+		{"SHOW TABLES", "*3\r\n$8\r\nproducts\r\n$11\r\nserver_logs\r\n$5\r\nusers\r\n"},
+		{"show tables", "*3\r\n$8\r\nproducts\r\n$11\r\nserver_logs\r\n$5\r\nusers\r\n"},
+		{"DESCRIBE users", "*3\r\n$2\r\nid\r\n$4\r\nname\r\n$3\r\nage\r\n"},
+		{"DESCRIBE USERS", "*3\r\n$2\r\nid\r\n$4\r\nname\r\n$3\r\nage\r\n"},
+	}
+	for _, c := range cases {
+		if got := runSQL(t, &recordConn{}, c.query); got != c.want {
+			t.Errorf("%s = %q, want %q", c.query, got, c.want)
+		}
+	}
+
+	// Created tables show up too, with their declared types
+	mustSQL(t, "CREATE TABLE notes (id INT, body TEXT)")
+	if got, want := runSQL(t, &recordConn{}, "SHOW TABLES"), "*4\r\n$5\r\nnotes\r\n$8\r\nproducts\r\n$11\r\nserver_logs\r\n$5\r\nusers\r\n"; got != want {
+		t.Errorf("SHOW TABLES after CREATE TABLE = %q, want %q", got, want)
+	}
+	if got, want := runSQL(t, &recordConn{}, "DESCRIBE notes"), "*2\r\n$6\r\nid INT\r\n$9\r\nbody TEXT\r\n"; got != want {
+		t.Errorf("DESCRIBE notes = %q, want %q", got, want)
+	}
+
+	for _, query := range []string{"DESCRIBE nope", "DESCRIBE", "SHOW COLUMNS"} {
+		if got := runSQL(t, &recordConn{}, query); !strings.HasPrefix(got, "-ERR ") {
+			t.Errorf("%s = %q, want an error", query, got)
+		}
+	}
+}
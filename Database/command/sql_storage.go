@@ -2,9 +2,14 @@ package command
 
 import (
 	"container/list"
+	"encoding/json"
 	"MiniRedisDb/storage"
 	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -20,33 +25,165 @@ type Table struct {
 	Name    string
 	Columns []string
 	Rows    []Row
+	Types   map[string]string    // Declared column types ("INT"/"TEXT"); nil for untyped tables
+	Indexes map[string]hashIndex // Hash indexes by column, built with CREATE INDEX
+
+	// generations is set on query results: the generation of every table
+	// the query read, as it ran (see tableGenerations)
+	generations map[string]uint64
 }
 
 // BackingDatabase represents the "unlimited" main database (disk)
 // We'll just use an in-memory map to simulate this.
+// It holds the tables of the default database; see Databases for the rest.
 var BackingDatabase map[string]*Table
 var dbMutex sync.RWMutex
 
+// tableGenerations counts the writes to each table, keyed by lower-cased
+// full name. A query result remembers the generations it read (see
+// executeOnBackingStore), so AddToCache can tell that a write landed while
+// the query ran: that write's invalidation may already be over, and the
+// stale result would otherwise stay cached. Guarded by dbMutex. Counts are
+// never reset, so a dropped and recreated table keeps counting up.
+var tableGenerations = make(map[string]uint64)
+
+// bumpGeneration records a write to a table.
+// NOTE: This function is not thread-safe, callers must hold dbMutex for writing!
+func bumpGeneration(table string) {
+	tableGenerations[strings.ToLower(table)]++
+}
+
+// recordGenerations notes on results the current generation of every table
+// the query reads.
+// NOTE: This function is not thread-safe, callers must hold dbMutex!
+func recordGenerations(query *QueryAST, results *Table) {
+	results.generations = make(map[string]uint64)
+	for _, table := range query.tablesRead() {
+		key := strings.ToLower(table)
+		results.generations[key] = tableGenerations[key]
+	}
+}
+
+// isCurrent reports whether no table a result was computed from has been
+// written since. Tables not built by executeOnBackingStore carry no
+// generations and count as current.
+// NOTE: This function is not thread-safe, callers must hold dbMutex!
+func (t *Table) isCurrent() bool {
+	if t == nil {
+		return true
+	}
+	for table, gen := range t.generations {
+		if tableGenerations[table] != gen {
+			return false
+		}
+	}
+	return true
+}
+
 // CacheEntry stores the result of a query in the cache.
 type CacheEntry struct {
 	Query     *QueryAST // The parsed query
 	Results   *Table    // The resulting table
 	Timestamp time.Time // Used for LRU
+	CreatedAt time.Time // When the results were fetched, used for TTL expiry
+	key       string    // The lookup map key this entry is stored under (the normalized query)
+	cells     int       // Size of Results counted against the shard's cell budget
 }
 
-// SemanticCache holds the in-memory cache state.
-type SemanticCache struct {
-	entries *list.List // Holds *CacheEntry, ordered by recency (front = newest)
-	lookup  map[string]*list.Element // Maps *query string* to list element for fast direct hits
+// cacheShard is one independently locked part of the cache. Each query
+// string belongs to exactly one shard (see shardFor), which holds its entry
+// and evicts within its own share of the cache size.
+type cacheShard struct {
+	entries *list.List // Holds *CacheEntry, ordered by the eviction policy (front = newest)
+	lookup  map[string]*list.Element // Maps the *normalized query string* to list element for fast direct hits
 	mu      sync.RWMutex
 	maxSize int
+	policy  EvictionPolicy // Picks the entry to drop when the shard is full
+
+	maxCells int // Budget for the entries' cells (rows × columns); 0 means none
+	cells    int // Cells held by the entries
+
+	// Signatures of entries that could answer a semantic hit, so
+	// FindSemanticHit can skip the shard when none can. nil when disabled.
+	bloom *bloomFilter
+}
+
+// SemanticCache holds the in-memory cache state, split into shards so
+// queries for different keys don't contend on a single lock.
+type SemanticCache struct {
+	shards  []*cacheShard
+	maxSize int              // Total across the shards
+	maxCells int             // Cell budget across the shards; 0 means none
+	TTL     time.Duration    // Entries older than this are dropped; 0 disables expiry
+	now     func() time.Time // Clock used for TTL checks, swappable for tests
+
+	mu          sync.RWMutex  // Guards missPenalty and tolerance
+	missPenalty time.Duration // Simulated backing-store I/O delay per miss; 0 disables it
+	tolerance   float64       // Slack allowed on numeric bounds for semantic hits; 0 is exact
+
+	// Background refresh of hot entries about to expire (see sql_refresh.go)
+	refreshWindow time.Duration
+	stopRefresh   chan struct{} // Closed by Close; nil if no refresh runs
+	closeOnce     sync.Once
 
 	// --- NEW: Cache Statistics ---
-	totalQueries uint64
-	directHits   uint64
-	semanticHits uint64
-	cacheMisses  uint64
+	// The counters are atomics so recording a query never waits on a lock
+	totalQueries atomic.Uint64
+	directHits   atomic.Uint64
+	semanticHits atomic.Uint64
+	cacheMisses  atomic.Uint64
 	// --- End NEW ---
+	perTableMu sync.RWMutex            // Guards the perTable map, not the counters in it
+	perTable   map[string]*tableStats // The same counters broken down by FROM table
+
+	// Cumulative query latency, by how the query was answered
+	directLatency   latencyStats
+	semanticLatency latencyStats
+	missLatency     latencyStats
+}
+
+// queryOutcome says how HandleSQL answered a SELECT, for RecordLatency.
+type queryOutcome int
+
+const (
+	outcomeDirectHit queryOutcome = iota
+	outcomeSemanticHit
+	outcomeMiss
+	outcomeNoCache // NOCACHE query, never recorded in the stats
+)
+
+// latencyStats accumulates the time spent answering one kind of query.
+type latencyStats struct {
+	total atomic.Int64 // Nanoseconds
+	count atomic.Uint64
+}
+
+// record adds one query's latency.
+func (ls *latencyStats) record(elapsed time.Duration) {
+	ls.total.Add(int64(elapsed))
+	ls.count.Add(1)
+}
+
+// reset zeroes the totals.
+func (ls *latencyStats) reset() {
+	ls.total.Store(0)
+	ls.count.Store(0)
+}
+
+// averageMs returns the mean latency in milliseconds, 0 if nothing was recorded.
+func (ls *latencyStats) averageMs() float64 {
+	count := ls.count.Load()
+	if count == 0 {
+		return 0
+	}
+	return float64(ls.total.Load()) / float64(count) / float64(time.Millisecond)
+}
+
+// tableStats holds the cache counters for queries against a single table.
+type tableStats struct {
+	directHits   atomic.Uint64
+	semanticHits atomic.Uint64
+	cacheMisses  atomic.Uint64
 }
 
 // Global cache instance
@@ -55,22 +192,136 @@ var SQLCache *SemanticCache
 // Constants for cache simulation
 const (
 	CACHE_MAX_SIZE      = 5 // A small fixed size for the cache
-	CACHE_MISS_PENALTY  = 100 * time.Millisecond // Fixed time to simulate cache miss
+	CACHE_MISS_PENALTY  = 100 * time.Millisecond // Default time to simulate cache miss
+	CACHE_DEFAULT_TTL   = 0 // Entries never expire unless a TTL is configured
+	CACHE_DEFAULT_EVICTION = "LRU" // Policy used when none (or an unknown one) is configured
+	CACHE_DEFAULT_SHARDS   = 4     // Independently locked parts of the cache
+	CACHE_MIN_SHARD_SIZE   = 8     // Fewest entries worth giving a shard of its own
+	CACHE_DEFAULT_REFRESH_WINDOW   = 5 * time.Second // Refresh hot entries this close to expiring
+	CACHE_DEFAULT_REFRESH_INTERVAL = 1 * time.Second // How often to look for them
+	CACHE_DEFAULT_MAX_CELLS        = 0               // No cell budget; only MaxSize limits the cache
 )
 
-// InitSQLCache initializes the semantic cache.
-func InitSQLCache() {
+// SQLCacheConfig holds the tunables passed to InitSQLCache.
+type SQLCacheConfig struct {
+	MaxSize  int
+	TTL      time.Duration // 0 disables expiry
+	Eviction string        // "LRU" or "FIFO"
+	// MissPenalty is slept on every miss to simulate backing-store I/O; 0 disables it
+	MissPenalty time.Duration
+	// BloomFilter lets semantic lookups skip the cache scan when no cached
+	// query is over the same table
+	BloomFilter bool
+	// Shards splits the cache into independently locked parts, each holding
+	// an equal share of MaxSize and evicting on its own, so a shard can be
+	// full while others have room. It is capped so every shard holds at
+	// least CACHE_MIN_SHARD_SIZE entries: the default 5-entry cache is a
+	// single LRU, and hot queries can't crowd each other out of a one-slot
+	// shard.
+	Shards int
+	// With a TTL, entries used since they were cached are re-fetched in the
+	// background once they are within RefreshWindow of expiring, checking
+	// every RefreshInterval. Either being 0 disables the refresh.
+	RefreshWindow   time.Duration
+	RefreshInterval time.Duration
+	// Tolerance lets a cached numeric bound answer a query whose bound lies
+	// up to this far outside it (e.g. "cpu_load > 78" from "cpu_load > 80"
+	// with 5), trading missed rows near the bound for hits. 0, the
+	// default, only serves exact supersets.
+	Tolerance float64
+	// MaxCells bounds the cache by the size of its results rather than
+	// their number: each entry costs its rows × columns, and a shard evicts
+	// until its share of MaxCells is enough. A result bigger than a whole
+	// share isn't cached. MaxSize still applies; 0 disables the budget.
+	MaxCells int
+}
+
+// DefaultSQLCacheConfig returns the settings the server starts with.
+func DefaultSQLCacheConfig() SQLCacheConfig {
+	return SQLCacheConfig{
+		MaxSize:  CACHE_MAX_SIZE,
+		TTL:      CACHE_DEFAULT_TTL,
+		Eviction: CACHE_DEFAULT_EVICTION,
+
+		MissPenalty: CACHE_MISS_PENALTY,
+		BloomFilter: true,
+		Shards:      CACHE_DEFAULT_SHARDS,
+
+		RefreshWindow:   CACHE_DEFAULT_REFRESH_WINDOW,
+		RefreshInterval: CACHE_DEFAULT_REFRESH_INTERVAL,
+
+		MaxCells: CACHE_DEFAULT_MAX_CELLS,
+	}
+}
+
+// InitSQLCache initializes the semantic cache, starting its background
+// refresh if one is configured.
+func InitSQLCache(cfg SQLCacheConfig) {
+	eviction := cfg.Eviction
+	if _, err := newEvictionPolicy(eviction, list.New()); err != nil {
+		fmt.Printf("WARNING: %s, falling back to %s\n", err, CACHE_DEFAULT_EVICTION)
+		eviction = CACHE_DEFAULT_EVICTION
+	}
+
+	n := cfg.Shards
+	if n > cfg.MaxSize/CACHE_MIN_SHARD_SIZE {
+		n = cfg.MaxSize / CACHE_MIN_SHARD_SIZE
+	}
+	if n < 1 {
+		n = 1
+	}
+
+	shards := make([]*cacheShard, n)
+	for i := range shards {
+		entries := list.New()
+		policy, _ := newEvictionPolicy(eviction, entries)
+		shards[i] = &cacheShard{
+			entries: entries,
+			lookup:  make(map[string]*list.Element),
+			maxSize: cfg.MaxSize / n,
+			policy:  policy,
+		}
+		if i < cfg.MaxSize%n {
+			shards[i].maxSize++ // Spread the remainder so the shares add up to MaxSize
+		}
+		if cfg.MaxCells > 0 {
+			shards[i].maxCells = cfg.MaxCells / n
+			if i < cfg.MaxCells%n {
+				shards[i].maxCells++
+			}
+		}
+		if cfg.BloomFilter {
+			shards[i].bloom = &bloomFilter{}
+		}
+	}
+
 	SQLCache = &SemanticCache{
-		entries: list.New(),
-		lookup:  make(map[string]*list.Element),
-		maxSize: CACHE_MAX_SIZE,
-		// --- NEW: Initialize Stats ---
-		totalQueries: 0,
-		directHits:   0,
-		semanticHits: 0,
-		cacheMisses:  0,
-		// --- End NEW ---
+		shards:   shards,
+		maxSize:  cfg.MaxSize,
+		maxCells: cfg.MaxCells,
+		TTL:     cfg.TTL,
+		now:     time.Now,
+
+		missPenalty: cfg.MissPenalty,
+		tolerance:   cfg.Tolerance,
+		perTable:    make(map[string]*tableStats),
+
+		refreshWindow: cfg.RefreshWindow,
 	}
+	SQLCache.startRefresher(cfg.RefreshInterval)
+}
+
+// shardFor returns the shard that holds (or would hold) the entry for a
+// query string.
+func (sc *SemanticCache) shardFor(queryString string) *cacheShard {
+	h := fnv.New32a()
+	h.Write([]byte(queryString))
+	return sc.shards[h.Sum32()%uint32(len(sc.shards))]
+}
+
+// policyName is the eviction policy every shard uses.
+func (sc *SemanticCache) policyName() string {
+	return sc.shards[0].policy.Name()
 }
 
 // InitBackingDB populates our simulated main database with data.
@@ -79,6 +330,7 @@ func InitBackingDB() {
 	defer dbMutex.Unlock()
 
 	BackingDatabase = make(map[string]*Table)
+	Databases = map[string]map[string]*Table{DEFAULT_DATABASE: BackingDatabase}
 
 	// Create a sample 'users' table (your original data)
 	users := &Table{
@@ -143,106 +395,370 @@ func InitBackingDB() {
 	// --- End NEW ---
 }
 
-// Get from cache (and update LRU)
+// Get from cache (and record the access with the eviction policy). Like Peek
+// and AddToCache it keys on the normalized query (see normalizeQueryKey).
+// It returns a copy of the entry, since a refresh may swap the cached
+// results once the shard lock is released.
 func (sc *SemanticCache) Get(queryString string) (*CacheEntry, bool) {
-	sc.mu.Lock()
-	defer sc.mu.Unlock()
+	queryString = normalizeQueryKey(queryString)
+	shard := sc.shardFor(queryString)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	// Lazily drop anything past its TTL before looking up
+	sc.removeExpiredLocked(shard)
 
-	if elem, hit := sc.lookup[queryString]; hit {
-		// Move to front (most recently used)
-		sc.entries.MoveToFront(elem)
+	if elem, hit := shard.lookup[queryString]; hit {
+		shard.policy.RecordAccess(elem)
 		entry := elem.Value.(*CacheEntry)
-		entry.Timestamp = time.Now()
+		entry.Timestamp = sc.now()
 		// --- NEW: Update Stat ---
-		sc.directHits++
+		sc.directHits.Add(1)
 		// --- End NEW ---
-		return entry, true
+		sc.statsFor(entry.Query.FromTable).directHits.Add(1)
+		snapshot := *entry
+		return &snapshot, true
+	}
+	return nil, false
+}
+
+// Peek is Get without side effects: it neither counts a hit nor marks the
+// entry as used, and treats an expired entry as absent. Like Get it returns
+// a copy.
+func (sc *SemanticCache) Peek(queryString string) (*CacheEntry, bool) {
+	queryString = normalizeQueryKey(queryString)
+	shard := sc.shardFor(queryString)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+
+	if elem, hit := shard.lookup[queryString]; hit {
+		entry := elem.Value.(*CacheEntry)
+		if !sc.isExpired(entry) {
+			snapshot := *entry
+			return &snapshot, true
+		}
 	}
 	return nil, false
 }
 
-// AddToCache adds a new entry, handling LRU eviction if full.
+// AddToCache adds a new entry, handling LRU eviction if its shard is full
+// or over its cell budget.
 func (sc *SemanticCache) AddToCache(queryString string, query *QueryAST, results *Table) {
-	sc.mu.Lock()
-	defer sc.mu.Unlock()
+	// Hold off writes until the entry is in place: a write that already
+	// happened makes the results stale, and one that comes after will find
+	// the entry to invalidate
+	dbMutex.RLock()
+	defer dbMutex.RUnlock()
+	if !results.isCurrent() {
+		return
+	}
 
-	// If it already exists, just update it and move to front
-	if elem, hit := sc.lookup[queryString]; hit {
-		sc.entries.MoveToFront(elem)
+	queryString = normalizeQueryKey(queryString)
+	shard := sc.shardFor(queryString)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	cells := resultCells(results)
+	tooBig := shard.maxCells > 0 && cells > shard.maxCells
+
+	// If it already exists, just update it and count it as an access
+	if elem, hit := shard.lookup[queryString]; hit {
+		if tooBig {
+			shard.removeLocked(elem) // Its old results are stale now
+			return
+		}
+		shard.policy.RecordAccess(elem)
 		entry := elem.Value.(*CacheEntry)
-		entry.Results = results
-		entry.Timestamp = time.Now()
+		shard.setResultsLocked(entry, results)
+		entry.Timestamp = sc.now()
+		entry.CreatedAt = entry.Timestamp
+		shard.evictOverBudgetLocked()
 		return
 	}
+	if tooBig {
+		return // Caching it would mean evicting everything else for nothing
+	}
+
+	sc.removeExpiredLocked(shard)
 
-	// If cache is full, evict the least recently used item
-	if sc.entries.Len() >= sc.maxSize {
-		lruElement := sc.entries.Back()
-		if lruElement != nil {
-			lruEntry := sc.entries.Remove(lruElement).(*CacheEntry)
-			// Remove from lookup map.
-			delete(sc.lookup, lruEntry.Query.OriginalString)
+	// If the shard is full, evict whichever entry the policy picks
+	if shard.entries.Len() >= shard.maxSize {
+		victim := shard.policy.Victim()
+		if victim != nil {
+			shard.removeLocked(victim)
 		}
 	}
 
 	// Add new entry
+	now := sc.now()
 	entry := &CacheEntry{
 		Query:     query,
 		Results:   results,
-		Timestamp: time.Now(),
+		Timestamp: now,
+		CreatedAt: now,
+		key:       queryString,
+		cells:     cells,
+	}
+	elem := shard.entries.PushFront(entry)
+	shard.lookup[queryString] = elem
+	shard.cells += cells
+	shard.evictOverBudgetLocked()
+	if shard.bloom != nil && canServeSemanticHits(query) {
+		shard.bloom.add(semanticSignature(query))
+	}
+}
+
+// resultCells is what a result costs against the cell budget: its rows
+// times its columns, a rough stand-in for the memory it holds.
+func resultCells(results *Table) int {
+	if results == nil {
+		return 0
+	}
+	return len(results.Rows) * len(results.Columns)
+}
+
+// removeLocked drops an entry from a shard's list and lookup map and
+// releases its cells. The caller must hold the shard's write lock.
+func (shard *cacheShard) removeLocked(elem *list.Element) *CacheEntry {
+	entry := shard.entries.Remove(elem).(*CacheEntry)
+	delete(shard.lookup, entry.key)
+	shard.cells -= entry.cells
+	return entry
+}
+
+// setResultsLocked replaces an entry's results, keeping the shard's cell
+// count in step. The caller must hold the shard's write lock.
+func (shard *cacheShard) setResultsLocked(entry *CacheEntry, results *Table) {
+	cells := resultCells(results)
+	shard.cells += cells - entry.cells
+	entry.Results = results
+	entry.cells = cells
+}
+
+// evictOverBudgetLocked evicts the entries the policy picks until the shard
+// is within its cell budget. The caller must hold the shard's write lock.
+func (shard *cacheShard) evictOverBudgetLocked() {
+	for shard.maxCells > 0 && shard.cells > shard.maxCells {
+		victim := shard.policy.Victim()
+		if victim == nil {
+			return
+		}
+		shard.removeLocked(victim)
+	}
+}
+
+// rebuildBloomLocked recomputes a shard's Bloom filter from its remaining
+// entries, since it can't forget the signatures of removed ones. Evictions
+// don't bother: a stale signature only costs a scan, it never hides an entry.
+// NOTE: callers must hold shard.mu for writing!
+func (shard *cacheShard) rebuildBloomLocked() {
+	if shard.bloom == nil {
+		return
+	}
+	shard.bloom.reset()
+	for e := shard.entries.Front(); e != nil; e = e.Next() {
+		query := e.Value.(*CacheEntry).Query
+		if canServeSemanticHits(query) {
+			shard.bloom.add(semanticSignature(query))
+		}
+	}
+}
+
+// isExpired reports whether an entry has outlived the cache TTL.
+func (sc *SemanticCache) isExpired(entry *CacheEntry) bool {
+	return sc.TTL > 0 && sc.now().Sub(entry.CreatedAt) > sc.TTL
+}
+
+// removeExpiredLocked drops every expired entry of a shard from both its
+// list and its lookup map. The caller must hold the shard's write lock.
+func (sc *SemanticCache) removeExpiredLocked(shard *cacheShard) {
+	if sc.TTL <= 0 {
+		return
 	}
-	elem := sc.entries.PushFront(entry)
-	sc.lookup[queryString] = elem
+	removed := false
+	for e := shard.entries.Front(); e != nil; {
+		next := e.Next()
+		entry := e.Value.(*CacheEntry)
+		if sc.isExpired(entry) {
+			shard.removeLocked(e)
+			removed = true
+		}
+		e = next
+	}
+	if removed {
+		shard.rebuildBloomLocked()
+	}
+}
+
+// InvalidateTable drops every cached query that reads from the given table,
+// so results computed before a data change are never served again.
+// It returns the number of entries removed.
+func (sc *SemanticCache) InvalidateTable(table string) int {
+	removed := 0
+	for _, shard := range sc.shards {
+		removed += shard.invalidateTable(table)
+	}
+	return removed
+}
+
+// invalidateTable is InvalidateTable for a single shard.
+func (shard *cacheShard) invalidateTable(table string) int {
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	removed := 0
+	for e := shard.entries.Front(); e != nil; {
+		next := e.Next() // Grab before Remove() unlinks e
+		entry := e.Value.(*CacheEntry)
+		if entry.Query.usesTable(table) {
+			shard.removeLocked(e)
+			removed++
+		}
+		e = next
+	}
+	if removed > 0 {
+		shard.rebuildBloomLocked()
+	}
+	return removed
 }
 
-// findSemanticHit iterates the cache (MRU to LRU) looking for a superset query.
+// FindSemanticHit looks for a cached superset query, preferring the most
+// recently used one.
 // --- NEW: Returns the matching cached query for logging ---
 func (sc *SemanticCache) FindSemanticHit(newQuery *QueryAST) (*Table, *QueryAST, bool) {
-	sc.mu.RLock()
-	defer sc.mu.RUnlock()
+	hitElem, filteredResults, cachedQuery := sc.matchSemanticHit(newQuery)
+	if hitElem == nil {
+		return nil, nil, false
+	}
 
-	// Iterate from MRU (front) to LRU (back)
-	for e := sc.entries.Front(); e != nil; e = e.Next() {
-		cachedEntry := e.Value.(*CacheEntry)
+	// The superset was used: refresh its timestamp and LRU position
+	sc.touch(hitElem)
 
-		if isQuerySubset(newQuery, cachedEntry.Query) {
-			// Found a superset!
-			// Now, filter the superset's results in memory.
-			filteredResults := filterResultsFromSuperset(cachedEntry.Results, newQuery.Where)
+	// We'll update stats in HandleSQL.
+	return filteredResults, cachedQuery, true
+}
 
-			// Update the superset's timestamp (as it was used)
-			cachedEntry.Timestamp = time.Now()
-			// We can't move to front here without a Write lock,
-			// but we can update the stat.
-			
-			// We'll update stats in HandleSQL as we need the RLock here.
+// PeekSemanticHit is FindSemanticHit without marking the superset as used,
+// for EXPLAIN.
+func (sc *SemanticCache) PeekSemanticHit(newQuery *QueryAST) (*Table, *QueryAST, bool) {
+	hitElem, filteredResults, cachedQuery := sc.matchSemanticHit(newQuery)
+	return filteredResults, cachedQuery, hitElem != nil
+}
 
-			return filteredResults, cachedEntry.Query, true
+// matchSemanticHit finds the most recently used live superset of newQuery
+// and answers newQuery from its results. It returns a nil element if there
+// is none.
+func (sc *SemanticCache) matchSemanticHit(newQuery *QueryAST) (*list.Element, *Table, *QueryAST) {
+	if hasColumnComparison(newQuery.Where) {
+		return nil, nil, nil
+	}
+
+	// Each shard offers its own most recently used superset; the freshest
+	// of those wins, as it would in a single list
+	tolerance := sc.Tolerance()
+	var best *list.Element
+	var bestEntry CacheEntry
+	for _, shard := range sc.shards {
+		elem, entry := sc.shardSuperset(shard, newQuery, tolerance)
+		if elem != nil && (best == nil || entry.Timestamp.After(bestEntry.Timestamp)) {
+			best, bestEntry = elem, entry
 		}
 	}
+	if best == nil {
+		return nil, nil, nil
+	}
 
-	return nil, nil, false
+	// Now, filter the superset's results in memory. Cached result tables
+	// are never modified, so this needs no lock. With a tolerance the
+	// superset may be approximate, but the filter still applies the new
+	// query's exact condition, so every row served does match it.
+	filteredResults := filterResultsFromSuperset(bestEntry.Results, newQuery.Where)
+	// The superset is in its own order, so re-apply the new query's ORDER BY,
+	// project down to the columns the new query actually asked for, then
+	// apply its DISTINCT and LIMIT.
+	rows := sortRows(filteredResults.Rows, newQuery.OrderBy)
+	columns := filteredResults.Columns // Columns are from the superset
+	if newQuery.SelectColumns[0] != "*" {
+		columns = newQuery.SelectColumns
+	}
+	rows = projectRows(computeExpressions(rows, newQuery.Expressions), newQuery.SelectColumns)
+	if newQuery.Distinct {
+		rows = distinctRows(rows, columns)
+	}
+	filteredResults = &Table{
+		Name:    filteredResults.Name,
+		Columns: columns,
+		Rows:    paginateRows(rows, newQuery),
+	}
+	return best, filteredResults, bestEntry.Query
+}
+
+// shardSuperset returns the first live superset of newQuery in a shard's
+// list (MRU to LRU), allowing tolerance on numeric bounds, with a copy of
+// its entry taken under the shard's read lock. Marking it as used is a write and is done afterwards under the
+// write lock (see touch).
+func (sc *SemanticCache) shardSuperset(shard *cacheShard, newQuery *QueryAST, tolerance float64) (*list.Element, CacheEntry) {
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+	// No cached query over these tables can be a superset: skip the scan
+	if shard.bloom != nil && !shard.bloom.mayContain(semanticSignature(newQuery)) {
+		return nil, CacheEntry{}
+	}
+	for e := shard.entries.Front(); e != nil; e = e.Next() {
+		cachedEntry := e.Value.(*CacheEntry)
+
+		// Expired entries are skipped here and removed on the next Get/AddToCache,
+		// since we only hold the read lock.
+		if sc.isExpired(cachedEntry) {
+			continue
+		}
+		if isQuerySubset(newQuery, cachedEntry.Query, tolerance) {
+			return e, *cachedEntry
+		}
+	}
+	return nil, CacheEntry{}
+}
+
+// touch records a semantic hit with the eviction policy, so under LRU a
+// superset that keeps answering queries isn't evicted.
+// The entry may have been evicted or invalidated between releasing the read
+// lock and taking the write lock, in which case there is nothing to update.
+func (sc *SemanticCache) touch(elem *list.Element) {
+	entry := elem.Value.(*CacheEntry)
+	shard := sc.shardFor(entry.key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	if shard.lookup[entry.key] != elem {
+		return
+	}
+	shard.policy.RecordAccess(elem)
+	entry.Timestamp = sc.now()
 }
 
 // --- NEW: Function to get cache statistics ---
+// The counters are read one by one, so under load they may be a query or
+// two apart.
 func (sc *SemanticCache) GetCacheStats() string {
-	sc.mu.RLock()
-	defer sc.mu.RUnlock()
+	totalQueries := sc.totalQueries.Load()
+	directHits := sc.directHits.Load()
+	semanticHits := sc.semanticHits.Load()
+	cacheMisses := sc.cacheMisses.Load()
 
 	var directHitRatio float64 = 0
 	var semanticHitRatio float64 = 0
 	var missRatio float64 = 0
 
-	if sc.totalQueries > 0 {
-		directHitRatio = (float64(sc.directHits) / float64(sc.totalQueries)) * 100
-		semanticHitRatio = (float64(sc.semanticHits) / float64(sc.totalQueries)) * 100
-		missRatio = (float64(sc.cacheMisses) / float64(sc.totalQueries)) * 100
+	if totalQueries > 0 {
+		directHitRatio = (float64(directHits) / float64(totalQueries)) * 100
+		semanticHitRatio = (float64(semanticHits) / float64(totalQueries)) * 100
+		missRatio = (float64(cacheMisses) / float64(totalQueries)) * 100
 	}
 	
-	totalHits := sc.directHits + sc.semanticHits
+	totalHits := directHits + semanticHits
 	var totalHitRatio float64 = 0
-	if sc.totalQueries > 0 {
-		totalHitRatio = (float64(totalHits) / float64(sc.totalQueries)) * 100
+	if totalQueries > 0 {
+		totalHitRatio = (float64(totalHits) / float64(totalQueries)) * 100
 	}
 
 
@@ -253,34 +769,263 @@ func (sc *SemanticCache) GetCacheStats() string {
 			"  - Direct Hits:   %d (%.2f%%)\n"+
 			"  - Semantic Hits: %d (%.2f%%)\n"+
 			"Cache Misses: %d (%.2f%%)\n"+
-			"Cache Size: %d / %d",
-		sc.totalQueries,
+			"Avg Latency: direct %.3fms | semantic %.3fms | miss %.3fms\n"+
+			"Cache Size: %d / %d (%s eviction, %d shards)",
+		totalQueries,
 		totalHits, totalHitRatio,
-		sc.directHits, directHitRatio,
-		sc.semanticHits, semanticHitRatio,
-		sc.cacheMisses, missRatio,
-		sc.entries.Len(), sc.maxSize,
+		directHits, directHitRatio,
+		semanticHits, semanticHitRatio,
+		cacheMisses, missRatio,
+		sc.directLatency.averageMs(), sc.semanticLatency.averageMs(), sc.missLatency.averageMs(),
+		sc.Len(), sc.maxSize, sc.policyName(), len(sc.shards),
 	)
+	if sc.maxCells > 0 {
+		stats += fmt.Sprintf("\nCache Cells: %d / %d", sc.Cells(), sc.maxCells)
+	}
 	return stats
 }
 
-// --- NEW: Helper functions to increment stats safely ---
-func (sc *SemanticCache) IncrementTotalQueries() {
-	sc.mu.Lock()
-	defer sc.mu.Unlock()
-	sc.totalQueries++
+// Len returns the number of cached entries across all shards.
+func (sc *SemanticCache) Len() int {
+	n := 0
+	for _, shard := range sc.shards {
+		shard.mu.RLock()
+		n += shard.entries.Len()
+		shard.mu.RUnlock()
+	}
+	return n
 }
 
-func (sc *SemanticCache) IncrementSemanticHits() {
+// Cells returns the cells (rows × columns) held across all shards.
+func (sc *SemanticCache) Cells() int {
+	n := 0
+	for _, shard := range sc.shards {
+		shard.mu.RLock()
+		n += shard.cells
+		shard.mu.RUnlock()
+	}
+	return n
+}
+
+// DumpEntries lists the cached queries from most to least recently used,
+// with each entry's row count and age. Eviction happens within each shard,
+// so under FIFO this isn't necessarily the order entries will leave in.
+func (sc *SemanticCache) DumpEntries() string {
+	var entries []CacheEntry
+	for _, shard := range sc.shards {
+		shard.mu.RLock()
+		for e := shard.entries.Front(); e != nil; e = e.Next() {
+			entries = append(entries, *e.Value.(*CacheEntry))
+		}
+		shard.mu.RUnlock()
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Timestamp.After(entries[j].Timestamp) })
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("--- SQL Cache Contents (%d / %d, %s eviction, most recently used first) ---", len(entries), sc.maxSize, sc.policyName()))
+	for i, entry := range entries {
+		rows := 0
+		if entry.Results != nil {
+			rows = len(entry.Results.Rows)
+		}
+		age := sc.now().Sub(entry.CreatedAt).Round(time.Millisecond)
+		sb.WriteString(fmt.Sprintf("\n%d. %s | %d rows | age %s", i+1, entry.key, rows, age))
+		if sc.isExpired(&entry) {
+			sb.WriteString(" (expired)")
+		}
+	}
+	return sb.String()
+}
+
+// CacheStats is a machine-readable snapshot of the cache counters.
+type CacheStats struct {
+	TotalQueries  uint64  `json:"total_queries"`
+	DirectHits    uint64  `json:"direct_hits"`
+	SemanticHits  uint64  `json:"semantic_hits"`
+	CacheMisses   uint64  `json:"cache_misses"`
+	HitRatio      float64 `json:"hit_ratio"` // (direct + semantic) / total, in [0, 1]
+	AvgDirectMs   float64 `json:"avg_direct_hit_ms"`
+	AvgSemanticMs float64 `json:"avg_semantic_hit_ms"`
+	AvgMissMs     float64 `json:"avg_miss_ms"`
+	Size          int     `json:"size"`
+	MaxSize       int     `json:"max_size"`
+	Eviction      string  `json:"eviction_policy"`
+	Shards        int     `json:"shards"`
+	Cells         int     `json:"cells"`
+	MaxCells      int     `json:"max_cells"` // 0 when there is no cell budget
+}
+
+// GetCacheStatsJSON returns the current counters as a JSON object, for
+// monitoring tools that can't parse the GetCacheStats text.
+func (sc *SemanticCache) GetCacheStatsJSON() (string, error) {
+	stats := CacheStats{
+		TotalQueries:  sc.totalQueries.Load(),
+		DirectHits:    sc.directHits.Load(),
+		SemanticHits:  sc.semanticHits.Load(),
+		CacheMisses:   sc.cacheMisses.Load(),
+		AvgDirectMs:   sc.directLatency.averageMs(),
+		AvgSemanticMs: sc.semanticLatency.averageMs(),
+		AvgMissMs:     sc.missLatency.averageMs(),
+		Size:          sc.Len(),
+		MaxSize:       sc.maxSize,
+		Eviction:      sc.policyName(),
+		Shards:        len(sc.shards),
+		Cells:         sc.Cells(),
+		MaxCells:      sc.maxCells,
+	}
+
+	if stats.TotalQueries > 0 {
+		stats.HitRatio = float64(stats.DirectHits+stats.SemanticHits) / float64(stats.TotalQueries)
+	}
+
+	data, err := json.Marshal(stats)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// ResetStats zeroes all counters so a new benchmark phase starts from scratch.
+// Cached entries are left untouched.
+func (sc *SemanticCache) ResetStats() {
+	sc.totalQueries.Store(0)
+	sc.directHits.Store(0)
+	sc.semanticHits.Store(0)
+	sc.cacheMisses.Store(0)
+	sc.directLatency.reset()
+	sc.semanticLatency.reset()
+	sc.missLatency.reset()
+
+	sc.perTableMu.Lock()
+	sc.perTable = make(map[string]*tableStats)
+	sc.perTableMu.Unlock()
+}
+
+// MissPenalty returns the delay currently simulated on every cache miss.
+func (sc *SemanticCache) MissPenalty() time.Duration {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	return sc.missPenalty
+}
+
+// SetMissPenalty changes the simulated miss delay at runtime; 0 disables it.
+func (sc *SemanticCache) SetMissPenalty(penalty time.Duration) {
 	sc.mu.Lock()
 	defer sc.mu.Unlock()
-	sc.semanticHits++
+	sc.missPenalty = penalty
 }
 
-func (sc *SemanticCache) IncrementCacheMisses() {
+// Tolerance returns the slack currently allowed on numeric bounds when
+// looking for a cached superset.
+func (sc *SemanticCache) Tolerance() float64 {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	return sc.tolerance
+}
+
+// SetTolerance changes the numeric slack at runtime; 0 only serves exact
+// supersets.
+func (sc *SemanticCache) SetTolerance(tolerance float64) {
 	sc.mu.Lock()
 	defer sc.mu.Unlock()
-	sc.cacheMisses++
+	sc.tolerance = tolerance
+}
+
+// RecordLatency adds the time HandleSQL took to answer a query to the
+// running totals for its outcome.
+func (sc *SemanticCache) RecordLatency(outcome queryOutcome, elapsed time.Duration) {
+	stats := &sc.missLatency
+	switch outcome {
+	case outcomeDirectHit:
+		stats = &sc.directLatency
+	case outcomeSemanticHit:
+		stats = &sc.semanticLatency
+	}
+	stats.record(elapsed)
+}
+
+// statsFor returns the counters for a table, creating them on first use.
+// Only the first query against a table takes perTableMu for writing.
+func (sc *SemanticCache) statsFor(table string) *tableStats {
+	sc.perTableMu.RLock()
+	ts, ok := sc.perTable[table]
+	sc.perTableMu.RUnlock()
+	if ok {
+		return ts
+	}
+
+	sc.perTableMu.Lock()
+	defer sc.perTableMu.Unlock()
+	ts, ok = sc.perTable[table]
+	if !ok {
+		ts = &tableStats{}
+		sc.perTable[table] = ts
+	}
+	return ts
+}
+
+// GetTableStats reports the hit and miss counters for queries against one
+// table. ok is false if no query has touched the table since the last reset.
+func (sc *SemanticCache) GetTableStats(table string) (string, bool) {
+	sc.perTableMu.RLock()
+	name := table
+	ts, ok := sc.perTable[table]
+	if !ok {
+		for key, stats := range sc.perTable {
+			if strings.EqualFold(key, table) {
+				name, ts, ok = key, stats, true
+				break
+			}
+		}
+	}
+	sc.perTableMu.RUnlock()
+	if !ok {
+		return "", false
+	}
+
+	directHits := ts.directHits.Load()
+	semanticHits := ts.semanticHits.Load()
+	cacheMisses := ts.cacheMisses.Load()
+	queries := directHits + semanticHits + cacheMisses
+	ratio := func(n uint64) float64 {
+		if queries == 0 {
+			return 0
+		}
+		return float64(n) / float64(queries) * 100
+	}
+	totalHits := directHits + semanticHits
+
+	return fmt.Sprintf(
+		"--- SQL Cache Statistics: %s ---\n"+
+			"Total Queries: %d\n"+
+			"Total Cache Hits: %d (%.2f%%)\n"+
+			"  - Direct Hits:   %d (%.2f%%)\n"+
+			"  - Semantic Hits: %d (%.2f%%)\n"+
+			"Cache Misses: %d (%.2f%%)",
+		name,
+		queries,
+		totalHits, ratio(totalHits),
+		directHits, ratio(directHits),
+		semanticHits, ratio(semanticHits),
+		cacheMisses, ratio(cacheMisses),
+	), true
+}
+
+// --- NEW: Helper functions to increment stats safely ---
+// They use atomics rather than the shard locks, so counting a query never
+// waits on (or holds up) a cache lookup.
+func (sc *SemanticCache) IncrementTotalQueries() {
+	sc.totalQueries.Add(1)
+}
+
+func (sc *SemanticCache) IncrementSemanticHits(table string) {
+	sc.semanticHits.Add(1)
+	sc.statsFor(table).semanticHits.Add(1)
+}
+
+func (sc *SemanticCache) IncrementCacheMisses(table string) {
+	sc.cacheMisses.Add(1)
+	sc.statsFor(table).cacheMisses.Add(1)
 }
 // --- End NEW ---
 
@@ -0,0 +1,617 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// missResult runs query the way a cache miss does, without caching it.
+func missResult(t *testing.T, query string) (*QueryAST, *Table) {
+	t.Helper()
+	ast, err := ParseSQL(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := resolveQueryNames(ast); err != nil {
+		t.Fatal(err)
+	}
+	results, err := executeOnBackingStore(ast)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ast, results
+}
+
+func TestAddToCacheDropsResultsReadBeforeAWrite(t *testing.T) {
+	resetSQL(t)
+	const query = "SELECT * FROM users WHERE age > 40"
+
+	// The miss reads the table, then an INSERT and its invalidation finish
+	// before the miss gets to cache its result
+	ast, stale := missResult(t, query)
+	mustSQL(t, "INSERT INTO users (id, name, age) VALUES (99, 'Zed', 70)")
+	SQLCache.AddToCache(query, ast, stale)
+
+	if _, hit := SQLCache.Peek(query); hit {
+		t.Fatal("a result read before the INSERT was cached after its invalidation")
+	}
+	if reply := mustSQL(t, query); !strings.Contains(reply, "Zed") {
+		t.Errorf("query after the INSERT is missing the new row:\n%s", reply)
+	}
+}
+
+func TestAddToCacheKeepsResultsAfterUnrelatedWrite(t *testing.T) {
+	resetSQL(t)
+	const query = "SELECT * FROM users WHERE age > 40"
+
+	ast, results := missResult(t, query)
+	mustSQL(t, "INSERT INTO products (id, item, stock) VALUES (99, 'pear', 3)")
+	SQLCache.AddToCache(query, ast, results)
+
+	if _, hit := SQLCache.Peek(query); !hit {
+		t.Error("a write to another table discarded the result")
+	}
+}
+
+func TestDefaultCacheIsOneLRU(t *testing.T) {
+	resetSQL(t)
+	if n := len(SQLCache.shards); n != 1 {
+		t.Fatalf("default cache has %d shards, want 1", n)
+	}
+
+	// Five different queries all fit, whatever they hash to. The narrower
+	// ones go first so none is answered from a cached superset.
+	queries := []string{
+		"SELECT name FROM users WHERE age > 40",
+		"SELECT item FROM products WHERE stock > 5",
+		"SELECT * FROM users",
+		"SELECT * FROM products",
+		"SELECT * FROM server_logs",
+	}
+	for _, q := range queries {
+		mustSQL(t, q)
+	}
+	for _, q := range queries {
+		if _, hit := SQLCache.Peek(q); !hit {
+			t.Errorf("%q was evicted from a cache of %d", q, CACHE_MAX_SIZE)
+		}
+	}
+}
+
+func TestShardsKeepAMinimumShare(t *testing.T) {
+	cases := []struct{ maxSize, shards, want int }{
+		{5, 4, 1},
+		{15, 4, 1},
+		{16, 4, 2},
+		{64, 4, 4},
+		{1000, 4, 4},
+	}
+	for _, c := range cases {
+		cfg := DefaultSQLCacheConfig()
+		cfg.MaxSize, cfg.Shards, cfg.RefreshInterval = c.maxSize, c.shards, 0
+		InitSQLCache(cfg)
+		if n := len(SQLCache.shards); n != c.want {
+			t.Errorf("MaxSize %d, Shards %d: got %d shards, want %d", c.maxSize, c.shards, n, c.want)
+		}
+		total := 0
+		for _, shard := range SQLCache.shards {
+			total += shard.maxSize
+		}
+		if total != c.maxSize {
+			t.Errorf("MaxSize %d: shard shares add up to %d", c.maxSize, total)
+		}
+	}
+}
+
+func TestInvalidateTable(t *testing.T) {
+	resetSQL(t)
+	const query = "SELECT * FROM users WHERE age > 40"
+	cacheOutcome(t, query)
+	cacheOutcome(t, "SELECT * FROM products")
+
+	c := &recordConn{}
+	HandleSQLInvalidate([]string{"SQLINVALIDATE", "users"}, c)
+	if got := c.out.String(); got != ":1\r\n" {
+		t.Errorf("SQLINVALIDATE users replied %q, want :1", got)
+	}
+	if _, hit := SQLCache.Peek(query); hit {
+		t.Error("users query still cached after invalidation")
+	}
+	if got := cacheOutcome(t, query); got != "miss" {
+		t.Errorf("users query after invalidation was a %s, want miss", got)
+	}
+	if got := cacheOutcome(t, "SELECT * FROM products"); got != "direct" {
+		t.Errorf("products query was a %s after invalidating users, want direct", got)
+	}
+}
+
+func TestEntriesExpireAfterTTL(t *testing.T) {
+	resetSQL(t)
+	cfg := DefaultSQLCacheConfig()
+	cfg.MissPenalty, cfg.RefreshInterval, cfg.TTL = 0, 0, time.Minute
+	InitSQLCache(cfg)
+	clock := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	SQLCache.now = func() time.Time { return clock }
+
+	const query = "SELECT * FROM users WHERE age > 40"
+	cacheOutcome(t, query)
+	clock = clock.Add(30 * time.Second)
+	if got := cacheOutcome(t, query); got != "direct" {
+		t.Errorf("query within the TTL was a %s, want direct", got)
+	}
+	if got := cacheOutcome(t, "SELECT * FROM users WHERE age > 50"); got != "semantic" {
+		t.Errorf("subset within the TTL was a %s, want semantic", got)
+	}
+
+	clock = clock.Add(2 * time.Minute)
+	if got := cacheOutcome(t, "SELECT * FROM users WHERE age > 60"); got != "miss" {
+		t.Errorf("subset of an expired entry was a %s, want miss", got)
+	}
+	if got := cacheOutcome(t, query); got != "miss" {
+		t.Errorf("expired query was a %s, want miss", got)
+	}
+
+	// Expired entries are gone from the lookup map too, not just skipped
+	clock = clock.Add(2 * time.Minute)
+	SQLCache.Get("SELECT * FROM users WHERE age > 1")
+	shard := SQLCache.shards[0]
+	if n := len(shard.lookup); n != 0 || shard.entries.Len() != 0 {
+		t.Errorf("%d lookup keys and %d entries left after expiry", n, shard.entries.Len())
+	}
+}
+
+func TestResetStatsStartsANewPhase(t *testing.T) {
+	resetSQL(t)
+	mustSQL(t, "SELECT * FROM users WHERE age > 40")
+	mustSQL(t, "SELECT * FROM users WHERE age > 40")
+	mustSQL(t, "SELECT * FROM users WHERE age > 50")
+
+	c := &recordConn{}
+	HandleSQLStats([]string{"SQLSTATS", "RESET"}, c)
+	if got := c.out.String(); got != "+OK\r\n" {
+		t.Fatalf("SQLSTATS RESET replied %q", got)
+	}
+
+	mustSQL(t, "SELECT * FROM users WHERE age > 60") // semantic
+	mustSQL(t, "SELECT * FROM products")             // miss
+	got := [4]uint64{SQLCache.totalQueries.Load(), SQLCache.directHits.Load(), SQLCache.semanticHits.Load(), SQLCache.cacheMisses.Load()}
+	if want := [4]uint64{2, 0, 1, 1}; got != want {
+		t.Errorf("total/direct/semantic/miss after the reset = %v, want %v", got, want)
+	}
+	if stats := SQLCache.GetCacheStats(); !strings.Contains(stats, "Total Queries: 2\n") {
+		t.Errorf("stats text doesn't reflect the reset:\n%s", stats)
+	}
+	if SQLCache.Len() != 2 {
+		t.Errorf("the reset changed the cache to %d entries, want 2", SQLCache.Len())
+	}
+}
+
+func TestStatsJSONRoundTrips(t *testing.T) {
+	resetSQL(t)
+	mustSQL(t, "SELECT * FROM users WHERE age > 40") // miss
+	mustSQL(t, "SELECT * FROM users WHERE age > 40") // direct
+	mustSQL(t, "SELECT * FROM users WHERE age > 50") // semantic
+	mustSQL(t, "SELECT * FROM products")             // miss
+
+	c := &recordConn{}
+	HandleSQLStats([]string{"SQLSTATS", "JSON"}, c)
+	reply := c.out.String()
+	body := reply[strings.Index(reply, "\r\n")+2 : len(reply)-2]
+
+	var got CacheStats
+	if err := json.Unmarshal([]byte(body), &got); err != nil {
+		t.Fatalf("SQLSTATS JSON reply %q: %v", reply, err)
+	}
+	want := CacheStats{
+		TotalQueries: 4,
+		DirectHits:   1,
+		SemanticHits: 1,
+		CacheMisses:  2,
+		HitRatio:     0.5,
+		Size:         2,
+		MaxSize:      CACHE_MAX_SIZE,
+		Eviction:     "LRU",
+		Shards:       1,
+		Cells:        len(queryRows(t, "SELECT * FROM users WHERE age > 40").Rows)*3 + 3*3,
+	}
+	// Latencies vary from run to run
+	got.AvgDirectMs, got.AvgSemanticMs, got.AvgMissMs = 0, 0, 0
+	if got != want {
+		t.Errorf("SQLSTATS JSON = %+v, want %+v", got, want)
+	}
+}
+
+// Run with -race: every goroutine is served from, and marks as used, the
+// same cached superset.
+func TestConcurrentSemanticHitsOnOneSuperset(t *testing.T) {
+	resetSQL(t)
+	mustSQL(t, "SELECT * FROM users WHERE age > 20")
+	before := SQLCache.semanticHits.Load()
+
+	const workers, perWorker = 8, 50
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			c := &recordConn{}
+			for i := 0; i < perWorker; i++ {
+				// Distinct texts, so none is a direct hit or gets cached
+				HandleSQL([]string{"SQL", fmt.Sprintf("SELECT * FROM users WHERE age > %d", 21+(w*perWorker+i)%70)}, c)
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	if hits := SQLCache.semanticHits.Load() - before; hits != workers*perWorker {
+		t.Errorf("%d semantic hits, want %d", hits, workers*perWorker)
+	}
+}
+
+func TestSemanticHitsKeepTheSupersetCached(t *testing.T) {
+	resetSQL(t)
+	const superset = "SELECT * FROM users WHERE age > 20"
+	mustSQL(t, superset)
+
+	// Each round adds a new entry, enough to cycle the whole cache, and
+	// uses the superset once
+	for i := 0; i < 3*CACHE_MAX_SIZE; i++ {
+		mustSQL(t, fmt.Sprintf("SELECT * FROM products WHERE stock = %d", i))
+		if got := cacheOutcome(t, fmt.Sprintf("SELECT name FROM users WHERE age > %d", 30+i)); got != "semantic" {
+			t.Fatalf("round %d: subset was a %s, want semantic", i, got)
+		}
+	}
+	if _, hit := SQLCache.Peek(superset); !hit {
+		t.Error("the superset answering every round was evicted")
+	}
+	if _, hit := SQLCache.Peek("SELECT * FROM products WHERE stock = 0"); hit {
+		t.Error("the oldest unused entry was kept")
+	}
+}
+
+func TestSemanticHitsProjectToTheRequestedColumns(t *testing.T) {
+	resetSQL(t)
+	mustSQL(t, "SELECT * FROM users WHERE age > 40")
+
+	cases := []struct {
+		query string
+		want  []string
+	}{
+		{"SELECT name FROM users WHERE age > 50", []string{"name"}},
+		{"SELECT age, id FROM users WHERE age > 50", []string{"age", "id"}},
+		{"SELECT * FROM users WHERE age > 50", []string{"id", "name", "age"}},
+	}
+	for _, c := range cases {
+		results, _, hit := SQLCache.FindSemanticHit(parseQuery(t, c.query))
+		if !hit {
+			t.Errorf("%s: no semantic hit", c.query)
+			continue
+		}
+		if !reflect.DeepEqual(results.Columns, c.want) {
+			t.Errorf("%s: columns %v, want %v", c.query, results.Columns, c.want)
+		}
+		for _, row := range results.Rows {
+			if len(row) != len(c.want) {
+				t.Errorf("%s: row %v holds other columns than %v", c.query, row, c.want)
+				break
+			}
+		}
+	}
+}
+
+func TestSemanticHitsProjectFromSpecificColumns(t *testing.T) {
+	resetSQL(t)
+	mustSQL(t, "SELECT id, name, age FROM users WHERE age > 40")
+
+	cases := []struct {
+		query string
+		want  []string
+	}{
+		{"SELECT name FROM users WHERE age > 50", []string{"name"}},
+		{"SELECT age, id FROM users WHERE age > 60", []string{"age", "id"}},
+		{"SELECT id FROM users WHERE age > 45 AND name LIKE '%a%'", []string{"id"}},
+	}
+	for _, c := range cases {
+		results, _, hit := SQLCache.PeekSemanticHit(parseQuery(t, c.query))
+		if !hit {
+			t.Errorf("%s: no semantic hit", c.query)
+			continue
+		}
+		if !reflect.DeepEqual(results.Columns, c.want) {
+			t.Errorf("%s: columns %v, want %v", c.query, results.Columns, c.want)
+		}
+		for _, row := range results.Rows {
+			if len(row) != len(c.want) {
+				t.Errorf("%s: row %v holds other columns than %v", c.query, row, c.want)
+				break
+			}
+		}
+		// The reply is the one direct execution gives
+		if got := cacheOutcome(t, c.query); got != "semantic" {
+			t.Errorf("%s: got a %s, want semantic", c.query, got)
+		}
+	}
+}
+
+func TestSemanticHitsNeedEveryColumnMaterialized(t *testing.T) {
+	cases := []struct {
+		cached, query, want string
+	}{
+		{"SELECT name FROM users WHERE age > 40", "SELECT age FROM users WHERE age > 50", "miss"},
+		{"SELECT name FROM users WHERE age > 40", "SELECT name, age FROM users WHERE age > 50", "miss"},
+		{"SELECT name FROM users WHERE age > 40", "SELECT * FROM users WHERE age > 50", "miss"},
+		// Filtering needs the WHERE column even when it isn't selected
+		{"SELECT name FROM users WHERE age > 40", "SELECT name FROM users WHERE age > 50", "miss"},
+		{"SELECT id, name FROM users", "SELECT name FROM users WHERE id < 5", "semantic"},
+		{"SELECT id, name FROM users", "SELECT name FROM users WHERE age < 30", "miss"},
+		{"SELECT id, name FROM users", "SELECT name FROM users WHERE id < 5 ORDER BY age", "miss"},
+	}
+	for _, c := range cases {
+		resetSQL(t)
+		mustSQL(t, c.cached)
+		if got := cacheOutcome(t, c.query); got != c.want {
+			t.Errorf("%s after caching %s: got a %s, want a %s", c.query, c.cached, got, c.want)
+		}
+	}
+}
+
+func TestPerTableStatsDiverge(t *testing.T) {
+	resetSQL(t)
+	mustSQL(t, "SELECT * FROM users WHERE age > 40") // miss
+	mustSQL(t, "SELECT * FROM users WHERE age > 40") // direct
+	mustSQL(t, "SELECT * FROM users WHERE age > 50") // semantic
+	mustSQL(t, "SELECT * FROM users WHERE age > 60") // semantic
+	mustSQL(t, "SELECT * FROM products WHERE stock > 100")
+	mustSQL(t, "SELECT * FROM products WHERE stock < 100")
+
+	cases := []struct {
+		table string
+		want  [3]uint64 // direct, semantic, miss
+	}{
+		{"users", [3]uint64{1, 2, 1}},
+		{"products", [3]uint64{0, 0, 2}},
+	}
+	for _, c := range cases {
+		ts := SQLCache.statsFor(c.table)
+		if got := [3]uint64{ts.directHits.Load(), ts.semanticHits.Load(), ts.cacheMisses.Load()}; got != c.want {
+			t.Errorf("%s: direct/semantic/miss = %v, want %v", c.table, got, c.want)
+		}
+	}
+
+	reply := runCommand(t, "SQLSTATS", "TABLE", "USERS")
+	for _, want := range []string{"--- SQL Cache Statistics: users ---", "Total Queries: 4", "Total Cache Hits: 3 (75.00%)", "Cache Misses: 1 (25.00%)"} {
+		if !strings.Contains(reply, want) {
+			t.Errorf("SQLSTATS TABLE USERS is missing %q:\n%s", want, reply)
+		}
+	}
+	if reply := runCommand(t, "SQLSTATS", "TABLE", "server_logs"); !strings.HasPrefix(reply, "-ERR") {
+		t.Errorf("SQLSTATS TABLE for an unqueried table replied %q, want an error", reply)
+	}
+}
+
+func TestLatencyAverages(t *testing.T) {
+	resetSQL(t)
+	SQLCache.RecordLatency(outcomeDirectHit, 1*time.Millisecond)
+	SQLCache.RecordLatency(outcomeDirectHit, 3*time.Millisecond)
+	SQLCache.RecordLatency(outcomeSemanticHit, 500*time.Microsecond)
+	SQLCache.RecordLatency(outcomeMiss, 100*time.Millisecond)
+	SQLCache.RecordLatency(outcomeMiss, 110*time.Millisecond)
+	SQLCache.RecordLatency(outcomeMiss, 120*time.Millisecond)
+
+	if stats := SQLCache.GetCacheStats(); !strings.Contains(stats, "Avg Latency: direct 2.000ms | semantic 0.500ms | miss 110.000ms") {
+		t.Errorf("stats text has the wrong averages:\n%s", stats)
+	}
+	data, err := SQLCache.GetCacheStatsJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stats CacheStats
+	if err := json.Unmarshal([]byte(data), &stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats.AvgDirectMs != 2 || stats.AvgSemanticMs != 0.5 || stats.AvgMissMs != 110 {
+		t.Errorf("JSON averages direct %v, semantic %v, miss %v; want 2, 0.5, 110", stats.AvgDirectMs, stats.AvgSemanticMs, stats.AvgMissMs)
+	}
+
+	SQLCache.ResetStats()
+	if stats := SQLCache.GetCacheStats(); !strings.Contains(stats, "Avg Latency: direct 0.000ms | semantic 0.000ms | miss 0.000ms") {
+		t.Errorf("averages survived a reset:\n%s", stats)
+	}
+}
+
+func TestDumpListsEntriesByRecency(t *testing.T) {
+	resetSQL(t)
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	clock := start
+	SQLCache.now = func() time.Time { return clock }
+	at := func(seconds int, query string) {
+		clock = start.Add(time.Duration(seconds) * time.Second)
+		mustSQL(t, query)
+	}
+
+	at(0, "SELECT * FROM users WHERE age > 90")
+	at(1, "SELECT * FROM products")
+	at(2, "SELECT * FROM users WHERE age < 20")
+	at(3, "SELECT * FROM users WHERE age > 90")       // direct hit
+	at(4, "SELECT * FROM products WHERE stock > 300") // semantic hit on products
+	clock = start.Add(10 * time.Second)
+
+	want := "--- SQL Cache Contents (3 / 5, LRU eviction, most recently used first) ---\n" +
+		"1. SELECT * FROM products | 3 rows | age 9s\n" +
+		"2. SELECT * FROM users WHERE age > 90 | 3 rows | age 10s\n" +
+		"3. SELECT * FROM users WHERE age < 20 | 2 rows | age 8s"
+	if got := bulkBody(t, runCommand(t, "SQLCACHE", "DUMP")); got != want {
+		t.Errorf("SQLCACHE DUMP =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestConcurrentStatIncrementsAreExact(t *testing.T) {
+	resetSQL(t)
+	const workers, perWorker = 8, 1000
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			table := []string{"users", "products"}[w%2]
+			for i := 0; i < perWorker; i++ {
+				SQLCache.IncrementTotalQueries()
+				if i%2 == 0 {
+					SQLCache.IncrementSemanticHits(table)
+				} else {
+					SQLCache.IncrementCacheMisses(table)
+				}
+				if i%100 == 0 {
+					SQLCache.GetCacheStats() // Reads race with the increments
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	if got := SQLCache.totalQueries.Load(); got != workers*perWorker {
+		t.Errorf("total queries = %d, want %d", got, workers*perWorker)
+	}
+	if got := SQLCache.semanticHits.Load(); got != workers*perWorker/2 {
+		t.Errorf("semantic hits = %d, want %d", got, workers*perWorker/2)
+	}
+	for _, table := range []string{"users", "products"} {
+		if got := SQLCache.statsFor(table).cacheMisses.Load(); got != workers*perWorker/4 {
+			t.Errorf("%s misses = %d, want %d", table, got, workers*perWorker/4)
+		}
+	}
+}
+
+// lockedStats counts the way the cache did before its counters were
+// atomics: every increment takes one shared mutex.
+type lockedStats struct {
+	mu                               sync.Mutex
+	total, semantic, misses          uint64
+	perTableSemantic, perTableMisses map[string]uint64
+}
+
+func (s *lockedStats) record(table string, semantic bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.total++
+	if semantic {
+		s.semantic++
+		s.perTableSemantic[table]++
+	} else {
+		s.misses++
+		s.perTableMisses[table]++
+	}
+}
+
+// BenchmarkConcurrentStatIncrements counts queries from many goroutines at
+// once, under one mutex and with the cache's atomic counters. Run it with
+// -race to check the atomic counters too.
+func BenchmarkConcurrentStatIncrements(b *testing.B) {
+	b.Run("mutex", func(b *testing.B) {
+		stats := &lockedStats{perTableSemantic: map[string]uint64{}, perTableMisses: map[string]uint64{}}
+		b.RunParallel(func(pb *testing.PB) {
+			for i := 0; pb.Next(); i++ {
+				stats.record("users", i%2 == 0)
+			}
+		})
+	})
+	b.Run("atomic", func(b *testing.B) {
+		resetSQL(b)
+		b.RunParallel(func(pb *testing.PB) {
+			for i := 0; pb.Next(); i++ {
+				SQLCache.IncrementTotalQueries()
+				if i%2 == 0 {
+					SQLCache.IncrementSemanticHits("users")
+				} else {
+					SQLCache.IncrementCacheMisses("users")
+				}
+			}
+		})
+	})
+}
+
+// BenchmarkConcurrentDirectHits serves direct hits on 64 cached queries from
+// many goroutines, with the whole cache behind one lock and split into 8
+// shards. Every hit updates LRU order, so it takes its shard's write lock.
+func BenchmarkConcurrentDirectHits(b *testing.B) {
+	for _, shards := range []int{1, 8} {
+		b.Run(fmt.Sprintf("shards=%d", shards), func(b *testing.B) {
+			cfg := DefaultSQLCacheConfig()
+			cfg.MaxSize, cfg.Shards, cfg.MissPenalty, cfg.RefreshInterval = 128, shards, 0, 0
+			InitSQLCache(cfg)
+			InitBackingDB()
+			queries := make([]string, 64)
+			for i := range queries {
+				queries[i] = fmt.Sprintf("SELECT * FROM users WHERE age > %d", i)
+				ast, err := ParseSQL(queries[i])
+				if err != nil {
+					b.Fatal(err)
+				}
+				results, err := executeOnBackingStore(ast)
+				if err != nil {
+					b.Fatal(err)
+				}
+				SQLCache.AddToCache(queries[i], ast, results)
+			}
+			if n := len(SQLCache.shards); n != shards {
+				b.Fatalf("got %d shards, want %d", n, shards)
+			}
+
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for i := 0; pb.Next(); i++ {
+					if _, hit := SQLCache.Get(queries[i%len(queries)]); !hit {
+						b.Error("cached query missed")
+						return
+					}
+				}
+			})
+		})
+	}
+}
+
+func TestCellBudgetEvictsTheLargeResult(t *testing.T) {
+	resetSQL(t)
+	cfg := DefaultSQLCacheConfig()
+	cfg.MaxSize, cfg.Shards, cfg.MaxCells, cfg.MissPenalty, cfg.RefreshInterval = 16, 1, 60, 0, 0
+	InitSQLCache(cfg)
+
+	const large = "SELECT * FROM users" // 15 rows × 3 columns
+	cacheOutcome(t, large)
+	var small []string // 1 row × 3 columns each
+	for id := 1; id <= 6; id++ {
+		small = append(small, fmt.Sprintf("SELECT * FROM users WHERE id = %d", id))
+	}
+	// Added directly, since a SELECT would be a semantic hit on the large one
+	for _, query := range small[:5] {
+		SQLCache.AddToCache(query, parseQuery(t, query), queryRows(t, query))
+	}
+	if _, ok := SQLCache.Peek(large); !ok || SQLCache.Len() != 6 {
+		t.Fatalf("%d entries with 60 of 60 cells used, want all 6", SQLCache.Len())
+	}
+
+	// One more small result goes over budget; the large one makes room
+	SQLCache.AddToCache(small[5], parseQuery(t, small[5]), queryRows(t, small[5]))
+	if _, ok := SQLCache.Peek(large); ok {
+		t.Errorf("large result still cached over the cell budget")
+	}
+	for _, query := range small {
+		if _, ok := SQLCache.Peek(query); !ok {
+			t.Errorf("%s was evicted, want only the large result gone", query)
+		}
+	}
+
+	// A result bigger than the whole budget isn't cached at all
+	const huge = "SELECT * FROM server_logs" // 14 rows × 4 columns
+	cfg.MaxCells = 40
+	InitSQLCache(cfg)
+	mustSQL(t, huge)
+	if _, ok := SQLCache.Peek(huge); ok || SQLCache.Len() != 0 {
+		t.Errorf("56-cell result was cached under a 40-cell budget")
+	}
+}
@@ -0,0 +1,88 @@
+package command
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Output formats for SELECT replies, chosen with a trailing FORMAT clause.
+const (
+	formatTable = "TABLE" // One bulk string holding a padded text table (the default)
+	formatArray = "ARRAY" // A RESP array with one bulk string per row, header first
+	formatJSON  = "JSON"  // One bulk string holding a JSON array of row objects
+	formatCSV   = "CSV"   // One bulk string holding RFC 4180 CSV with a header row
+)
+
+// streamChunkSize is how much of an ARRAY reply is buffered before it is
+// written to the connection.
+const streamChunkSize = 32 * 1024
+
+// formatClause matches a statement ending in a FORMAT clause.
+var formatClause = regexp.MustCompile(`(?is)^(.*?)\s+FORMAT\s+(TABLE|ARRAY|JSON|CSV)\s*$`)
+
+// splitOutputFormat strips a trailing FORMAT clause from a statement and
+// returns the bare statement and the format; without the clause the format
+// is formatTable. Stripping it before the cache lookup lets both formats
+// share cache entries.
+func splitOutputFormat(stmt string) (string, string) {
+	m := formatClause.FindStringSubmatch(stmt)
+	if m == nil {
+		return stmt, formatTable
+	}
+	return m[1], strings.ToUpper(m[2])
+}
+
+// writeResults writes a SELECT reply to w in the requested format.
+func writeResults(w io.Writer, table *Table, headers []string, format string) {
+	switch format {
+	case formatArray:
+		streamResults(w, table, headers)
+	case formatJSON:
+		w.Write([]byte(formatJSONResults(table, headers)))
+	case formatCSV:
+		w.Write([]byte(formatCSVResults(table, headers)))
+	default:
+		w.Write([]byte(formatResults(table, headers)))
+	}
+}
+
+// streamResults writes the results as a RESP array of bulk strings: the
+// column headers first, then one element per row, each with its values
+// separated by tabs. Rows are written out in chunks as they are formatted
+// rather than built into one string. The row cap applies as for tables.
+func streamResults(w io.Writer, table *Table, headers []string) {
+	if table == nil {
+		w.Write([]byte("*0\r\n"))
+		return
+	}
+	if headers == nil {
+		headers = table.Columns
+	}
+
+	rows := table.Rows
+	if limit := maxResultRows.Load(); limit > 0 && int64(len(rows)) > limit {
+		rows = rows[:limit]
+	}
+
+	bw := bufio.NewWriterSize(w, streamChunkSize)
+	fmt.Fprintf(bw, "*%d\r\n", len(rows)+1)
+	writeBulkLine(bw, headers)
+
+	values := make([]string, len(table.Columns))
+	for _, row := range rows {
+		for i, col := range table.Columns {
+			values[i] = formatValue(row[col])
+		}
+		writeBulkLine(bw, values)
+	}
+	bw.Flush()
+}
+
+// writeBulkLine writes fields joined by tabs as one RESP bulk string.
+func writeBulkLine(bw *bufio.Writer, fields []string) {
+	line := strings.Join(fields, "\t")
+	fmt.Fprintf(bw, "$%d\r\n%s\r\n", len(line), line)
+}
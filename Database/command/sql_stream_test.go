@@ -0,0 +1,74 @@
+package command
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// parseRESPArray strictly decodes a reply that must be exactly one RESP array
+// of bulk strings, checking every length prefix.
+func parseRESPArray(t *testing.T, reply string) []string {
+	t.Helper()
+	r := bufio.NewReader(strings.NewReader(reply))
+	readLine := func() string {
+		line, err := r.ReadString('\n')
+		if err != nil || !strings.HasSuffix(line, "\r\n") {
+			t.Fatalf("malformed RESP line %q in %q", line, reply)
+		}
+		return strings.TrimSuffix(line, "\r\n")
+	}
+
+	header := readLine()
+	count, err := strconv.Atoi(strings.TrimPrefix(header, "*"))
+	if !strings.HasPrefix(header, "*") || err != nil || count < 0 {
+		t.Fatalf("reply doesn't start with an array header: %q", header)
+	}
+	items := make([]string, count)
+	for i := range items {
+		prefix := readLine()
+		n, err := strconv.Atoi(strings.TrimPrefix(prefix, "$"))
+		if !strings.HasPrefix(prefix, "$") || err != nil || n < 0 {
+			t.Fatalf("element %d: expected a bulk string header, got %q", i, prefix)
+		}
+		payload := make([]byte, n+2)
+		if _, err := io.ReadFull(r, payload); err != nil || string(payload[n:]) != "\r\n" {
+			t.Fatalf("element %d: payload doesn't match its length %d", i, n)
+		}
+		items[i] = string(payload[:n])
+	}
+	if rest, _ := io.ReadAll(r); len(rest) > 0 {
+		t.Fatalf("%d bytes after the array: %q", len(rest), rest)
+	}
+	return items
+}
+
+func TestArrayFormatIsValidRESP(t *testing.T) {
+	resetSQL(t)
+	mustSQL(t, "INSERT INTO users (id, name, age) VALUES (16, 'Zoë', 33)")
+
+	items := parseRESPArray(t, mustSQL(t, "SELECT id, name FROM users WHERE age < 34 ORDER BY id FORMAT ARRAY"))
+	want := []string{"id\tname", "1\tAlice", "4\tDavid", "11\tKarl", "12\tLaura", "16\tZoë"}
+	if !reflect.DeepEqual(items, want) {
+		t.Errorf("FORMAT ARRAY items = %q, want %q", items, want)
+	}
+
+	// An empty result is just the header
+	if items := parseRESPArray(t, mustSQL(t, "SELECT name AS who FROM users WHERE age > 500 FORMAT ARRAY")); !reflect.DeepEqual(items, []string{"who"}) {
+		t.Errorf("empty FORMAT ARRAY items = %q, want only the header", items)
+	}
+
+	// A result spanning many write chunks still frames correctly
+	addEventsTable(t, 5000)
+	items = parseRESPArray(t, mustSQL(t, "SELECT * FROM events FORMAT ARRAY"))
+	if len(items) != 5001 {
+		t.Fatalf("FORMAT ARRAY of 5000 rows has %d items", len(items))
+	}
+	if got, want := items[5000], fmt.Sprintf("%d\ts%d\t%d", 4999, 4999%8, 4999%8); got != want {
+		t.Errorf("last row = %q, want %q", got, want)
+	}
+}
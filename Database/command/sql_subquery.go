@@ -0,0 +1,100 @@
+package command
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// hasSubquery reports whether a WHERE tree contains "col IN (SELECT ...)".
+func hasSubquery(node *WhereNode) bool {
+	if node == nil {
+		return false
+	}
+	if node.Cond != nil && node.Cond.Subquery != nil {
+		return true
+	}
+	return hasSubquery(node.Left) || hasSubquery(node.Right)
+}
+
+// bindSubqueries runs every subquery in a WHERE tree and returns a copy of
+// the tree in which each "col IN (SELECT ...)" tests membership in the
+// values the subquery returned (NULLs never match). The subqueries read the
+// tables directly, never the cache. A tree without subqueries is returned
+// as is; the input tree is never modified, since it may belong to a cached
+// query.
+// NOTE: This function is not thread-safe, callers must hold dbMutex!
+func bindSubqueries(node *WhereNode) (*WhereNode, error) {
+	if !hasSubquery(node) {
+		return node, nil
+	}
+	bound := &WhereNode{Op: node.Op}
+	if cond := node.Cond; cond != nil {
+		results, err := executeLocked(cond.Subquery)
+		if err != nil {
+			return nil, fmt.Errorf("subquery: %w", err)
+		}
+		col := results.Columns[0]
+		set := make(map[string]struct{}, len(results.Rows))
+		for _, row := range results.Rows {
+			if val, ok := row[col]; ok && val != nil {
+				set[literalKey(fmt.Sprintf("%v", val))] = struct{}{}
+			}
+		}
+		copied := *cond
+		copied.Subquery = nil
+		copied.valueSet = set
+		bound.Cond = &copied
+		return bound, nil
+	}
+
+	var err error
+	if bound.Left, err = bindSubqueries(node.Left); err != nil {
+		return nil, err
+	}
+	if bound.Right, err = bindSubqueries(node.Right); err != nil {
+		return nil, err
+	}
+	return bound, nil
+}
+
+// literalKey maps a value to the key it has in a subquery's value set, so
+// that values literalsEqual considers equal (42 and 42.0) share a key.
+func literalKey(value string) string {
+	if num, ok := parseNumber(value); ok {
+		return "n:" + strconv.FormatFloat(num, 'g', -1, 64)
+	}
+	return "s:" + value
+}
+
+// tablesRead lists the tables answering the query reads: FROM, JOIN and
+// the tables of its subqueries.
+func (ast *QueryAST) tablesRead() []string {
+	tables := []string{ast.FromTable}
+	if ast.Join != nil {
+		tables = append(tables, ast.Join.Table)
+	}
+	return append(tables, whereTablesRead(ast.Where)...)
+}
+
+func whereTablesRead(node *WhereNode) []string {
+	if node == nil {
+		return nil
+	}
+	var tables []string
+	if node.Cond != nil && node.Cond.Subquery != nil {
+		tables = node.Cond.Subquery.tablesRead()
+	}
+	tables = append(tables, whereTablesRead(node.Left)...)
+	return append(tables, whereTablesRead(node.Right)...)
+}
+
+// usesTable reports whether answering the query reads the named table.
+func (ast *QueryAST) usesTable(table string) bool {
+	for _, read := range ast.tablesRead() {
+		if strings.EqualFold(read, table) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package command
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestInSubqueryFiltersByMembership(t *testing.T) {
+	resetSQL(t)
+	mustSQL(t, "CREATE TABLE hosts (name TEXT, region TEXT)")
+	mustSQL(t, "INSERT INTO hosts (name, region) VALUES ('web-01', 'us')")
+	mustSQL(t, "INSERT INTO hosts (name, region) VALUES ('db-01', 'us')")
+	mustSQL(t, "INSERT INTO hosts (name, region) VALUES ('api-01', 'eu')")
+	mustSQL(t, "INSERT INTO hosts (name) VALUES ('nowhere')")
+
+	cases := []struct {
+		query string
+		want  []interface{}
+	}{
+		{"SELECT id FROM server_logs WHERE server_name IN (SELECT name FROM hosts WHERE region = 'us')",
+			[]interface{}{1001, 1003, 1005, 1008, 1011}},
+		{"SELECT id FROM server_logs WHERE server_name IN (SELECT name FROM hosts WHERE region = 'eu') OR cpu_load > 95",
+			[]interface{}{1004, 1007, 1012, 1013}},
+		{"SELECT id FROM server_logs WHERE NOT server_name IN (SELECT name FROM hosts) AND cpu_load > 90",
+			[]interface{}{1007, 1013}},
+		{"SELECT id FROM server_logs WHERE server_name IN (SELECT name FROM hosts WHERE region = 'asia')", nil},
+	}
+	for _, c := range cases {
+		got := column(queryRows(t, c.query), "id")
+		if !reflect.DeepEqual(got, c.want) && !(len(got) == 0 && len(c.want) == 0) {
+			t.Errorf("%s: got ids %v, want %v", c.query, got, c.want)
+		}
+	}
+
+	// A write to the inner table changes the outer query's rows
+	const query = "SELECT id FROM server_logs WHERE server_name IN (SELECT name FROM hosts WHERE region = 'eu')"
+	cacheOutcome(t, query)
+	mustSQL(t, "INSERT INTO hosts (name, region) VALUES ('cache-01', 'eu')")
+	if got := cacheOutcome(t, query); got != "miss" {
+		t.Errorf("subquery after a write to its table was a %s, want miss", got)
+	}
+
+	// A quoted 'SELECT' in a value list is a string, not a subquery
+	mustSQL(t, "INSERT INTO hosts (name, region) VALUES ('SELECT', 'us')")
+	results := queryRows(t, "SELECT name FROM hosts WHERE name IN ('SELECT', 'db-01') ORDER BY name")
+	if got, want := column(results, "name"), []interface{}{"SELECT", "db-01"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("IN ('SELECT', 'db-01'): got %v, want %v", got, want)
+	}
+	results = queryRows(t, "SELECT id FROM server_logs WHERE status IN ('SELECT', 'ERROR')")
+	if got, want := column(results, "id"), []interface{}{1007, 1013}; !reflect.DeepEqual(got, want) {
+		t.Errorf("IN ('SELECT', 'ERROR'): got ids %v, want %v", got, want)
+	}
+
+	reply := runSQL(t, &recordConn{}, "SELECT id FROM server_logs WHERE server_name IN (SELECT name, region FROM hosts)")
+	if !strings.HasPrefix(reply, "-ERR ") {
+		t.Errorf("two-column subquery = %q, want an error", reply)
+	}
+}
@@ -0,0 +1,185 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+)
+
+// sqlTokenKind classifies a single lexical token of a SQL string.
+type sqlTokenKind int
+
+const (
+	tokEOF      sqlTokenKind = iota
+	tokIdent                 // keywords, table and column names
+	tokNumber                // numeric literals, e.g. 40, -5 or 80.5
+	tokString                // quoted literals, stored without the quotes
+	tokOperator              // = != < > <= >=
+	tokComma
+	tokLParen
+	tokRParen
+	tokStar
+	tokParam // "?" placeholder in a prepared statement
+	tokArith // + - / in select-list expressions ("*" is tokStar)
+)
+
+// sqlToken is one token produced by tokenizeSQL.
+type sqlToken struct {
+	Kind sqlTokenKind
+	Text string
+}
+
+// splitSQLStatements splits input on semicolons that aren't inside a quoted
+// literal, e.g. "SELECT * FROM users; SELECT * FROM products". Statements are
+// trimmed and empty ones (such as after a trailing semicolon) are dropped.
+func splitSQLStatements(input string) []string {
+	var statements []string
+	var quote byte // The quote character we're inside, 0 if none
+	start := 0
+
+	add := func(stmt string) {
+		if stmt = strings.TrimSpace(stmt); stmt != "" {
+			statements = append(statements, stmt)
+		}
+	}
+	for i := 0; i < len(input); i++ {
+		ch := input[i]
+		switch {
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '\'' || ch == '"':
+			quote = ch
+		case ch == ';':
+			add(input[start:i])
+			start = i + 1
+		}
+	}
+	add(input[start:])
+	return statements
+}
+
+// tokenizeSQL splits a SQL string into tokens. The returned slice always ends
+// with a tokEOF token so the parser never has to bounds-check.
+func tokenizeSQL(input string) ([]sqlToken, error) {
+	var tokens []sqlToken
+
+	i := 0
+	for i < len(input) {
+		ch := input[i]
+
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\r' || ch == '\n':
+			i++
+
+		case ch == '\'' || ch == '"':
+			// Quoted literal: read up to the matching quote
+			end := strings.IndexByte(input[i+1:], ch)
+			if end == -1 {
+				return nil, fmt.Errorf("unterminated string literal starting at position %d", i)
+			}
+			tokens = append(tokens, sqlToken{Kind: tokString, Text: input[i+1 : i+1+end]})
+			i += end + 2
+
+		case ch == ',':
+			tokens = append(tokens, sqlToken{Kind: tokComma, Text: ","})
+			i++
+
+		case ch == '(':
+			tokens = append(tokens, sqlToken{Kind: tokLParen, Text: "("})
+			i++
+
+		case ch == ')':
+			tokens = append(tokens, sqlToken{Kind: tokRParen, Text: ")"})
+			i++
+
+		case ch == '*':
+			tokens = append(tokens, sqlToken{Kind: tokStar, Text: "*"})
+			i++
+
+		case ch == '?':
+			tokens = append(tokens, sqlToken{Kind: tokParam, Text: "?"})
+			i++
+
+		case ch == '+' || ch == '/' || (ch == '-' && (i+1 == len(input) || !isDigit(input[i+1]))):
+			// A '-' followed by a digit is a negative number instead
+			tokens = append(tokens, sqlToken{Kind: tokArith, Text: string(ch)})
+			i++
+
+		case ch == '<' || ch == '>' || ch == '=' || ch == '!':
+			// Two-character operators first so ">=" isn't read as ">" then "="
+			if i+1 < len(input) && input[i+1] == '=' && ch != '=' {
+				tokens = append(tokens, sqlToken{Kind: tokOperator, Text: input[i : i+2]})
+				i += 2
+				continue
+			}
+			if ch == '!' {
+				return nil, fmt.Errorf("unexpected '!' at position %d", i)
+			}
+			tokens = append(tokens, sqlToken{Kind: tokOperator, Text: string(ch)})
+			i++
+
+		case isDigit(ch) || (ch == '-' && i+1 < len(input) && isDigit(input[i+1])):
+			start := i
+			i++
+			for i < len(input) && isDigit(input[i]) {
+				i++
+			}
+			// Optional fractional part, e.g. 80.5
+			if i+1 < len(input) && input[i] == '.' && isDigit(input[i+1]) {
+				i++
+				for i < len(input) && isDigit(input[i]) {
+					i++
+				}
+			}
+			tokens = append(tokens, sqlToken{Kind: tokNumber, Text: input[start:i]})
+
+		case isIdentChar(ch):
+			start := i
+			for i < len(input) && isIdentChar(input[i]) {
+				i++
+			}
+			tokens = append(tokens, sqlToken{Kind: tokIdent, Text: input[start:i]})
+
+		default:
+			return nil, fmt.Errorf("unexpected character '%c' at position %d", ch, i)
+		}
+	}
+
+	tokens = append(tokens, sqlToken{Kind: tokEOF})
+	return tokens, nil
+}
+
+// describe names a token for error messages, e.g. "'WHERE'" or "end of query".
+func (t sqlToken) describe() string {
+	if t.Kind == tokEOF {
+		return "end of query"
+	}
+	return "'" + t.Text + "'"
+}
+
+func isDigit(ch byte) bool {
+	return ch >= '0' && ch <= '9'
+}
+
+// isIdentChar allows letters, digits, '_' and '.' (for table.column names).
+func isIdentChar(ch byte) bool {
+	return (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || isDigit(ch) || ch == '_' || ch == '.'
+}
+
+// joinSQLTokens turns tokens back into query text, one space apart, with
+// string literals quoted again.
+func joinSQLTokens(tokens []sqlToken) string {
+	parts := make([]string, 0, len(tokens))
+	for _, tok := range tokens {
+		switch tok.Kind {
+		case tokEOF:
+			continue
+		case tokString:
+			parts = append(parts, quoteSQLLiteral(tok.Text))
+		default:
+			parts = append(parts, tok.Text)
+		}
+	}
+	return strings.Join(parts, " ")
+}
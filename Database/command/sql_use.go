@@ -0,0 +1,110 @@
+package command
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+)
+
+func init() {
+	Register(CommandInfo{Name: "USE", Usage: "USE TABLE <table>", Summary: "Set the table this connection's SELECTs use when they have no FROM"}, HandlerFunc(HandleSQL))
+}
+
+// defaultTables holds each connection's USE TABLE choice, by full table name.
+var (
+	defaultTablesMu sync.Mutex
+	defaultTables   = make(map[net.Conn]string)
+)
+
+// defaultTable returns the table set with USE TABLE on c, or "".
+func defaultTable(c net.Conn) string {
+	defaultTablesMu.Lock()
+	defer defaultTablesMu.Unlock()
+	return defaultTables[c]
+}
+
+// ForgetConnection drops the per-connection SQL state of c. The server
+// calls it when a client disconnects.
+func ForgetConnection(c net.Conn) {
+	defaultTablesMu.Lock()
+	delete(defaultTables, c)
+	defaultTablesMu.Unlock()
+}
+
+// handleUseTable answers USE TABLE <table>: later SELECTs on this connection
+// without a FROM clause read that table.
+func handleUseTable(query string, c net.Conn) {
+	fields := strings.Fields(query)
+	if len(fields) != 3 || !strings.EqualFold(fields[1], "TABLE") {
+		c.Write([]byte("-ERR expected USE TABLE <table>\r\n"))
+		return
+	}
+
+	dbMutex.RLock()
+	table, ok := lookupTable(fields[2])
+	name := ""
+	if ok {
+		name = table.Name
+	}
+	dbMutex.RUnlock()
+
+	if !ok {
+		c.Write([]byte(fmt.Sprintf("-ERR table '%s' not found\r\n", fields[2])))
+		return
+	}
+
+	defaultTablesMu.Lock()
+	defaultTables[c] = name
+	defaultTablesMu.Unlock()
+	c.Write([]byte("+OK\r\n"))
+}
+
+// withDefaultTable adds "FROM <table>" to a SELECT that has no FROM clause,
+// e.g. "SELECT * WHERE age > 40" becomes "SELECT * FROM users WHERE age > 40".
+// Rewriting the text rather than the parsed query means the cache key names
+// the table, so connections using different default tables never share
+// entries. Queries with a FROM, or that don't tokenize, are returned as is.
+func withDefaultTable(query, table string) string {
+	tokens, err := tokenizeSQL(query)
+	if err != nil || len(tokens) < 2 || !strings.EqualFold(tokens[0].Text, "SELECT") {
+		return query
+	}
+
+	at := len(tokens) - 1 // tokEOF: no clause follows the select list
+	depth := 0
+	for i, tok := range tokens[1:] {
+		switch tok.Kind {
+		case tokLParen:
+			depth++
+			continue
+		case tokRParen:
+			depth--
+			continue
+		}
+		if tok.Kind != tokIdent || depth > 0 {
+			continue
+		}
+		if strings.EqualFold(tok.Text, "FROM") {
+			return query
+		}
+		if isClauseKeyword(tok.Text) {
+			at = i + 1
+			break
+		}
+	}
+
+	from := []sqlToken{{Kind: tokIdent, Text: "FROM"}, {Kind: tokIdent, Text: table}}
+	rewritten := append(append(append([]sqlToken(nil), tokens[:at]...), from...), tokens[at:]...)
+	return joinSQLTokens(rewritten)
+}
+
+// applyDefaultTable rewrites query with withDefaultTable if c has a default
+// table. Any FORMAT suffix must already be split off.
+func applyDefaultTable(query string, c net.Conn) string {
+	table := defaultTable(c)
+	if table == "" {
+		return query
+	}
+	return withDefaultTable(query, table)
+}
@@ -0,0 +1,42 @@
+package command
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUseTableDefaultsFrom(t *testing.T) {
+	resetSQL(t)
+	c, other := &recordConn{}, &recordConn{}
+	if reply := runSQL(t, c, "SELECT * WHERE age > 90"); !strings.HasPrefix(reply, "-ERR ") {
+		t.Errorf("SELECT without FROM before USE TABLE = %q, want an error", reply)
+	}
+	if reply := runSQL(t, c, "USE TABLE nope"); !strings.HasPrefix(reply, "-ERR ") {
+		t.Errorf("USE TABLE of a missing table = %q, want an error", reply)
+	}
+
+	if reply := runSQL(t, c, "USE TABLE Users"); reply != "+OK\r\n" {
+		t.Fatalf("USE TABLE Users = %q", reply)
+	}
+	explicit := mustSQL(t, "SELECT name, age FROM users WHERE age > 90 ORDER BY age")
+	direct := SQLCache.directHits.Load()
+	if got := runSQL(t, c, "SELECT name, age WHERE age > 90 ORDER BY age"); got != explicit {
+		t.Errorf("FROM-less SELECT =\n%s\nwant\n%s", got, explicit)
+	}
+	// The default table is written into the query, so it shares the entry
+	if SQLCache.directHits.Load() != direct+1 {
+		t.Errorf("FROM-less SELECT wasn't a direct hit on the same query with FROM")
+	}
+
+	if got, want := runSQL(t, c, "SELECT item FROM products WHERE stock < 300"), mustSQL(t, "SELECT item FROM products WHERE stock < 300"); got != want {
+		t.Errorf("explicit FROM with a default table =\n%s\nwant\n%s", got, want)
+	}
+	if reply := runSQL(t, other, "SELECT * WHERE age > 90"); !strings.HasPrefix(reply, "-ERR ") {
+		t.Errorf("another connection's FROM-less SELECT = %q, want an error", reply)
+	}
+
+	ForgetConnection(c)
+	if reply := runSQL(t, c, "SELECT * WHERE age > 90"); !strings.HasPrefix(reply, "-ERR ") {
+		t.Errorf("FROM-less SELECT after ForgetConnection = %q, want an error", reply)
+	}
+}
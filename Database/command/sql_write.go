@@ -0,0 +1,350 @@
+package command
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// sqlStatementKind returns the upper-cased leading keyword of a statement,
+// e.g. "SELECT" or "INSERT", used to route it to the right executor.
+func sqlStatementKind(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToUpper(fields[0])
+}
+
+// secondKeyword returns the upper-cased second word of a statement, e.g.
+// "INDEX" for CREATE INDEX, or "" if there is none.
+func secondKeyword(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) < 2 {
+		return ""
+	}
+	return strings.ToUpper(fields[1])
+}
+
+// handleInsert parses and runs an INSERT, then drops the table's cached
+// queries so later SELECTs see the new row.
+func handleInsert(query string, c net.Conn) {
+	stmt, err := ParseInsert(query)
+	if err != nil {
+		c.Write([]byte(fmt.Sprintf("-ERR %s\r\n", err.Error())))
+		return
+	}
+
+	if err := executeInsert(stmt); err != nil {
+		c.Write([]byte(fmt.Sprintf("-ERR %s\r\n", err.Error())))
+		return
+	}
+
+	removed := SQLCache.InvalidateTable(stmt.Table)
+	queryLog.Info("insert", "query", query, "rows", 1, "invalidated", removed)
+	c.Write([]byte("+OK\r\n"))
+}
+
+// executeInsert validates the statement against the table schema and appends the row.
+func executeInsert(stmt *InsertAST) error {
+	dbMutex.Lock()
+	defer dbMutex.Unlock()
+
+	table, exists := lookupTable(stmt.Table)
+	if !exists {
+		return fmt.Errorf("table '%s' not found", stmt.Table)
+	}
+	stmt.Table = table.Name
+	bumpGeneration(table.Name)
+
+	columns := stmt.Columns
+	if len(columns) == 0 {
+		columns = table.Columns
+	}
+	if len(columns) != len(stmt.Values) {
+		return fmt.Errorf("%d columns but %d values", len(columns), len(stmt.Values))
+	}
+
+	row := make(Row)
+	for i, name := range columns {
+		col, err := resolveColumn(table.Columns, name, table.Name)
+		if err != nil {
+			return err
+		}
+		if _, dup := row[col]; dup {
+			return fmt.Errorf("column '%s' specified more than once", col)
+		}
+		val, err := coerceValue(table, col, stmt.Values[i])
+		if err != nil {
+			return err
+		}
+		row[col] = val
+	}
+	// NULL is stored as an absent column, which is what IS NULL checks for
+	for col, val := range row {
+		if val == nil {
+			delete(row, col)
+		}
+	}
+
+	table.Rows = append(table.Rows, row)
+	table.appendToIndexes()
+	return nil
+}
+
+// coerceValue converts a literal to the column's declared type, e.g. '42'
+// into an INT column becomes 42. Tables without declared types accept
+// values as-is, and NULL (nil) is valid for every column.
+func coerceValue(table *Table, col string, val interface{}) (interface{}, error) {
+	if val == nil {
+		return nil, nil
+	}
+	switch table.Types[col] {
+	case "INT":
+		switch v := val.(type) {
+		case int:
+			return v, nil
+		case string:
+			if n, err := strconv.Atoi(v); err == nil {
+				return n, nil
+			}
+		}
+		return nil, fmt.Errorf("column '%s' expects INT, got '%v'", col, val)
+	case "TEXT":
+		switch v := val.(type) {
+		case int:
+			return strconv.Itoa(v), nil
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), nil
+		}
+	}
+	return val, nil
+}
+
+// handleUpdate parses and runs an UPDATE, replying with the affected row count.
+func handleUpdate(query string, c net.Conn) {
+	stmt, err := ParseUpdate(query)
+	if err != nil {
+		c.Write([]byte(fmt.Sprintf("-ERR %s\r\n", err.Error())))
+		return
+	}
+
+	affected, err := executeUpdate(stmt)
+	if err != nil {
+		c.Write([]byte(fmt.Sprintf("-ERR %s\r\n", err.Error())))
+		return
+	}
+
+	removed := 0
+	if affected > 0 {
+		removed = SQLCache.InvalidateTable(stmt.Table)
+	}
+	queryLog.Info("update", "query", query, "rows", affected, "invalidated", removed)
+	c.Write([]byte(fmt.Sprintf(":%d\r\n", affected)))
+}
+
+// executeUpdate applies the SET list to every row matching the WHERE clause.
+// Matching rows are replaced by updated copies rather than modified in place,
+// because cached result tables may still hold references to the old maps.
+func executeUpdate(stmt *UpdateAST) (int, error) {
+	dbMutex.Lock()
+	defer dbMutex.Unlock()
+
+	table, exists := lookupTable(stmt.Table)
+	if !exists {
+		return 0, fmt.Errorf("table '%s' not found", stmt.Table)
+	}
+	stmt.Table = table.Name
+	bumpGeneration(table.Name)
+	if err := canonicalizeWhere(stmt.Where, table.Columns, table.Name); err != nil {
+		return 0, err
+	}
+	where, err := bindSubqueries(stmt.Where)
+	if err != nil {
+		return 0, err
+	}
+
+	values := make([]interface{}, len(stmt.Assignments))
+	for i, a := range stmt.Assignments {
+		col, err := resolveColumn(table.Columns, a.Column, table.Name)
+		if err != nil {
+			return 0, err
+		}
+		stmt.Assignments[i].Column = col
+		val, err := coerceValue(table, col, a.Value)
+		if err != nil {
+			return 0, err
+		}
+		values[i] = val
+	}
+
+	affected := 0
+	for i, row := range table.Rows {
+		if !checkCondition(row, where) {
+			continue
+		}
+		updated := make(Row, len(row))
+		for col, val := range row {
+			updated[col] = val
+		}
+		for j, a := range stmt.Assignments {
+			if values[j] == nil {
+				delete(updated, a.Column) // SET col = NULL
+				continue
+			}
+			updated[a.Column] = values[j]
+		}
+		table.Rows[i] = updated
+		affected++
+	}
+	if affected > 0 {
+		table.rebuildIndexes()
+	}
+	return affected, nil
+}
+
+// handleDelete parses and runs a DELETE, replying with the deleted row count.
+func handleDelete(query string, c net.Conn) {
+	stmt, err := ParseDelete(query)
+	if err != nil {
+		c.Write([]byte(fmt.Sprintf("-ERR %s\r\n", err.Error())))
+		return
+	}
+	if stmt.Where == nil {
+		queryLog.Warn("DELETE without WHERE removes every row", "table", stmt.Table)
+	}
+
+	deleted, err := executeDelete(stmt)
+	if err != nil {
+		c.Write([]byte(fmt.Sprintf("-ERR %s\r\n", err.Error())))
+		return
+	}
+
+	removed := 0
+	if deleted > 0 {
+		removed = SQLCache.InvalidateTable(stmt.Table)
+	}
+	queryLog.Info("delete", "query", query, "rows", deleted, "invalidated", removed)
+	c.Write([]byte(fmt.Sprintf(":%d\r\n", deleted)))
+}
+
+// executeDelete rebuilds the table's rows without the ones matching the WHERE clause.
+func executeDelete(stmt *DeleteAST) (int, error) {
+	dbMutex.Lock()
+	defer dbMutex.Unlock()
+
+	table, exists := lookupTable(stmt.Table)
+	if !exists {
+		return 0, fmt.Errorf("table '%s' not found", stmt.Table)
+	}
+	stmt.Table = table.Name
+	bumpGeneration(table.Name)
+	if err := canonicalizeWhere(stmt.Where, table.Columns, table.Name); err != nil {
+		return 0, err
+	}
+	where, err := bindSubqueries(stmt.Where)
+	if err != nil {
+		return 0, err
+	}
+
+	kept := make([]Row, 0, len(table.Rows))
+	for _, row := range table.Rows {
+		if !checkCondition(row, where) {
+			kept = append(kept, row)
+		}
+	}
+
+	deleted := len(table.Rows) - len(kept)
+	table.Rows = kept
+	if deleted > 0 {
+		table.rebuildIndexes()
+	}
+	return deleted, nil
+}
+
+// handleCreateTable parses and runs a CREATE TABLE statement.
+func handleCreateTable(query string, c net.Conn) {
+	stmt, err := ParseCreateTable(query)
+	if err != nil {
+		c.Write([]byte(fmt.Sprintf("-ERR %s\r\n", err.Error())))
+		return
+	}
+
+	if err := executeCreateTable(stmt); err != nil {
+		c.Write([]byte(fmt.Sprintf("-ERR %s\r\n", err.Error())))
+		return
+	}
+
+	queryLog.Info("create table", "query", query, "table", stmt.Table, "columns", len(stmt.Columns))
+	c.Write([]byte("+OK\r\n"))
+}
+
+// executeCreateTable registers a new, empty table with the declared schema.
+func executeCreateTable(stmt *CreateTableAST) error {
+	dbMutex.Lock()
+	defer dbMutex.Unlock()
+
+	if existing, exists := lookupTable(stmt.Table); exists {
+		return fmt.Errorf("table '%s' already exists", existing.Name)
+	}
+	tables, key, fullName, err := tableSlot(stmt.Table)
+	if err != nil {
+		return err
+	}
+	stmt.Table = fullName
+
+	table := &Table{Name: fullName, Types: make(map[string]string, len(stmt.Columns))}
+	for _, def := range stmt.Columns {
+		table.Columns = append(table.Columns, def.Name)
+		table.Types[def.Name] = def.Type
+	}
+	tables[key] = table
+	return nil
+}
+
+// handleDropTable parses and runs a DROP TABLE, then drops the table's cached queries.
+func handleDropTable(query string, c net.Conn) {
+	stmt, err := ParseDropTable(query)
+	if err != nil {
+		c.Write([]byte(fmt.Sprintf("-ERR %s\r\n", err.Error())))
+		return
+	}
+
+	dropped, err := executeDropTable(stmt)
+	if err != nil {
+		c.Write([]byte(fmt.Sprintf("-ERR %s\r\n", err.Error())))
+		return
+	}
+
+	removed := 0
+	if dropped {
+		removed = SQLCache.InvalidateTable(stmt.Table)
+	}
+	queryLog.Info("drop table", "query", query, "dropped", dropped, "invalidated", removed)
+	c.Write([]byte("+OK\r\n"))
+}
+
+// executeDropTable removes the table from the backing database. It reports
+// whether a table was actually removed; a missing table is only an error
+// without IF EXISTS.
+func executeDropTable(stmt *DropTableAST) (bool, error) {
+	dbMutex.Lock()
+	defer dbMutex.Unlock()
+
+	table, exists := lookupTable(stmt.Table)
+	if !exists {
+		if stmt.IfExists {
+			return false, nil
+		}
+		return false, fmt.Errorf("table '%s' not found", stmt.Table)
+	}
+	stmt.Table = table.Name
+	tables, key, _, err := tableSlot(table.Name)
+	if err != nil {
+		return false, err
+	}
+	delete(tables, key)
+	bumpGeneration(table.Name)
+	return true, nil
+}
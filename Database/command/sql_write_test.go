@@ -0,0 +1,160 @@
+package command
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestInsert(t *testing.T) {
+	resetSQL(t)
+	const query = "SELECT * FROM users WHERE age > 30"
+	cacheOutcome(t, query)
+
+	c := &recordConn{}
+	if reply := runSQL(t, c, "INSERT INTO users (id, name, age) VALUES (16, 'Mallory', 33)"); reply != "+OK\r\n" {
+		t.Fatalf("INSERT replied %q, want +OK", reply)
+	}
+	if _, hit := SQLCache.Peek(query); hit {
+		t.Error("the INSERT left a cached users query in place")
+	}
+	if got := cacheOutcome(t, query); got != "miss" {
+		t.Errorf("query after the INSERT was a %s, want miss", got)
+	}
+	results := queryRows(t, "SELECT id, age FROM users WHERE name = 'Mallory'")
+	if len(results.Rows) != 1 || results.Rows[0]["id"] != 16 || results.Rows[0]["age"] != 33 {
+		t.Errorf("inserted row = %v, want id 16, age 33 stored as ints", results.Rows)
+	}
+}
+
+func TestInsertErrors(t *testing.T) {
+	resetSQL(t)
+
+	for _, stmt := range []string{
+		"INSERT INTO users (id, name, age) VALUES (16, 'Mallory')", // count mismatch
+		"INSERT INTO users VALUES (16, 'Mallory')",
+		"INSERT INTO users (id, nickname, age) VALUES (16, 'Mallory', 33)",
+		"INSERT INTO people (id, name, age) VALUES (16, 'Mallory', 33)",
+	} {
+		if reply := runSQL(t, &recordConn{}, stmt); !strings.HasPrefix(reply, "-ERR") {
+			t.Errorf("%s: replied %q, want an error", stmt, reply)
+		}
+	}
+	if n := len(queryRows(t, "SELECT * FROM users").Rows); n != 15 {
+		t.Errorf("users has %d rows after failed INSERTs, want 15", n)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	resetSQL(t)
+	const query = "SELECT * FROM server_logs WHERE status = 'WARNING'"
+	cacheOutcome(t, query)
+
+	c := &recordConn{}
+	if reply := runSQL(t, c, "UPDATE server_logs SET status = 'OK', cpu_load = 12 WHERE id = 1002"); reply != ":1\r\n" {
+		t.Fatalf("targeted UPDATE replied %q, want :1", reply)
+	}
+	if reply := runSQL(t, c, "UPDATE server_logs SET status = 'OK' WHERE id = 9999"); reply != ":0\r\n" {
+		t.Errorf("no-match UPDATE replied %q, want :0", reply)
+	}
+
+	if got := cacheOutcome(t, query); got != "miss" {
+		t.Errorf("query after the UPDATE was a %s, want miss", got)
+	}
+	if reply := mustSQL(t, query); strings.Contains(reply, "1002") {
+		t.Errorf("updated row still listed as WARNING:\n%s", reply)
+	}
+	// Numeric literals are stored as ints, like the seeded values
+	results := queryRows(t, "SELECT id, cpu_load FROM server_logs WHERE cpu_load < 13")
+	if len(results.Rows) != 1 || results.Rows[0]["id"] != 1002 || results.Rows[0]["cpu_load"] != 12 {
+		t.Errorf("cpu_load < 13 after the UPDATE = %v, want only id 1002 with int 12", results.Rows)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	resetSQL(t)
+	const query = "SELECT name FROM users WHERE age > 10"
+	cacheOutcome(t, query)
+
+	c := &recordConn{}
+	if reply := runSQL(t, c, "DELETE FROM users WHERE age < 20"); reply != ":2\r\n" {
+		t.Fatalf("DELETE replied %q, want :2 (Karl and Laura)", reply)
+	}
+	if reply := runSQL(t, c, "DELETE FROM users WHERE age > 200"); reply != ":0\r\n" {
+		t.Errorf("no-match DELETE replied %q, want :0", reply)
+	}
+	if got := cacheOutcome(t, query); got != "miss" {
+		t.Errorf("query after the DELETE was a %s, want miss", got)
+	}
+	if reply := mustSQL(t, query); strings.Contains(reply, "Karl") {
+		t.Errorf("deleted row still returned:\n%s", reply)
+	}
+	if n := len(queryRows(t, "SELECT * FROM users").Rows); n != 13 {
+		t.Errorf("users has %d rows, want 13", n)
+	}
+
+	if reply := runSQL(t, c, "DELETE FROM products"); reply != ":3\r\n" {
+		t.Errorf("DELETE without WHERE replied %q, want :3", reply)
+	}
+	if n := len(queryRows(t, "SELECT * FROM products").Rows); n != 0 {
+		t.Errorf("products has %d rows after DELETE without WHERE", n)
+	}
+}
+
+func TestCreateTable(t *testing.T) {
+	resetSQL(t)
+	c := &recordConn{}
+
+	if reply := runSQL(t, c, "CREATE TABLE metrics (id INT, host TEXT, value INT)"); reply != "+OK\r\n" {
+		t.Fatalf("CREATE TABLE replied %q", reply)
+	}
+	if reply := runSQL(t, c, "CREATE TABLE metrics (id INT)"); !strings.HasPrefix(reply, "-ERR") {
+		t.Errorf("duplicate CREATE TABLE replied %q, want an error", reply)
+	}
+	if reply := runSQL(t, c, "CREATE TABLE USERS (id INT)"); !strings.HasPrefix(reply, "-ERR") {
+		t.Errorf("CREATE TABLE of a seeded table in other case replied %q, want an error", reply)
+	}
+	if n := len(queryRows(t, "SELECT * FROM metrics").Rows); n != 0 {
+		t.Errorf("new table has %d rows", n)
+	}
+
+	// Declared types coerce the values
+	mustSQL(t, "INSERT INTO metrics (id, host, value) VALUES ('1', 42, 7)")
+	results := queryRows(t, "SELECT * FROM metrics")
+	if got, want := results.Columns, []string{"id", "host", "value"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("columns = %v, want %v", got, want)
+	}
+	if want := (Row{"id": 1, "host": "42", "value": 7}); !reflect.DeepEqual(results.Rows[0], want) {
+		t.Errorf("row = %#v, want %#v", results.Rows[0], want)
+	}
+	if reply := runSQL(t, c, "INSERT INTO metrics (id, host, value) VALUES (2, 'web', 'high')"); !strings.HasPrefix(reply, "-ERR") {
+		t.Errorf("text into an INT column replied %q, want an error", reply)
+	}
+}
+
+func TestDropTable(t *testing.T) {
+	resetSQL(t)
+	c := &recordConn{}
+	mustSQL(t, "CREATE TABLE metrics (id INT, host TEXT)")
+	mustSQL(t, "INSERT INTO metrics (id, host) VALUES (1, 'web')")
+	mustSQL(t, "SELECT * FROM metrics")
+	mustSQL(t, "SELECT host FROM metrics WHERE id = 1")
+	mustSQL(t, "SELECT * FROM users")
+
+	if reply := runSQL(t, c, "DROP TABLE metrics"); reply != "+OK\r\n" {
+		t.Fatalf("DROP TABLE replied %q", reply)
+	}
+	if SQLCache.Len() != 1 {
+		t.Errorf("%d entries cached after the drop, want only the users query", SQLCache.Len())
+	}
+	if reply := runSQL(t, c, "SELECT * FROM metrics"); !strings.HasPrefix(reply, "-ERR") {
+		t.Errorf("SELECT from the dropped table replied %q, want an error", reply)
+	}
+
+	if reply := runSQL(t, c, "DROP TABLE metrics"); !strings.HasPrefix(reply, "-ERR") {
+		t.Errorf("DROP TABLE of a missing table replied %q, want an error", reply)
+	}
+	if reply := runSQL(t, c, "DROP TABLE IF EXISTS metrics"); reply != "+OK\r\n" {
+		t.Errorf("DROP TABLE IF EXISTS of a missing table replied %q, want +OK", reply)
+	}
+}
@@ -0,0 +1,9 @@
+package config
+
+import "os"
+
+// Password returns the password clients must send with AUTH before running
+// other commands, taken from MRD_PASSWORD. Empty means no authentication.
+func Password() string {
+	return os.Getenv("MRD_PASSWORD")
+}
@@ -0,0 +1,26 @@
+package config
+
+import (
+	"net"
+	"os"
+)
+
+// Default listen address, overridable with the MRD_HOST and MRD_PORT
+// environment variables, e.g. to run several instances side by side.
+const (
+	DefaultHost = "0.0.0.0"
+	DefaultPort = "6379"
+)
+
+// ListenAddress returns the host:port the server should bind to.
+func ListenAddress() string {
+	host := os.Getenv("MRD_HOST")
+	if host == "" {
+		host = DefaultHost
+	}
+	port := os.Getenv("MRD_PORT")
+	if port == "" {
+		port = DefaultPort
+	}
+	return net.JoinHostPort(host, port)
+}
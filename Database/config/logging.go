@@ -0,0 +1,15 @@
+package config
+
+import "os"
+
+// DefaultLogLevel is the query log level used when MRD_LOG_LEVEL is unset.
+// Set MRD_LOG_LEVEL=OFF to silence per-query logs, e.g. for benchmarks.
+const DefaultLogLevel = "INFO"
+
+// LogLevel returns the configured query log level name.
+func LogLevel() string {
+	if level := os.Getenv("MRD_LOG_LEVEL"); level != "" {
+		return level
+	}
+	return DefaultLogLevel
+}